@@ -5,9 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/planetscale/schemadiff/pkg/base"
 	"github.com/planetscale/schemadiff/pkg/core"
+	"github.com/planetscale/schemadiff/pkg/server"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
 )
 
 func exitWithError(err error) {
@@ -21,16 +27,114 @@ func main() {
 	source := flag.String("source", "", "Input source (file name / directory / empty for stdin)")
 	target := flag.String("target", "", "Input target (file name / directory / empty for stdin)")
 	textual := flag.Bool("textual", false, "Output textual diff rather than semantic SQL diff")
+	color := flag.String("color", core.ColorAuto, "Colorize output: auto, always or never. auto colorizes output to a terminal, unless NO_COLOR is set. --color alone means always, so a value must be given as --color=never rather than --color never")
+	flag.Lookup("color").NoOptDefVal = core.ColorAlways
+	includeInternalTables := flag.Bool("include-internal-tables", false, "Read gh-ost/pt-osc artifact tables and Vitess internal tables from MySQL sources, which are skipped by default")
+	mapping := flag.StringArray("map", nil, "Map a source entity name onto a target entity name as old=new, or a source column as table.old=new. Mapped entities and columns are renamed rather than dropped and recreated. May be repeated")
+	mappingFile := flag.String("map-file", "", "File with mappings, one old=new or table.old=new per line")
+	heuristicRenames := flag.Bool("heuristic-renames", false, "Heuristically identify renamed tables and columns while diffing")
+	suggestRenames := flag.Bool("suggest-renames", false, "Output scored candidate table and column renames rather than the diff. The output is valid --map-file input")
+	targets := flag.StringArray("targets", nil, "diff: diff the source against each of these targets, grouping targets by identical diff. Supports {a,b} and {001..256} expansion, and file globs. May be repeated")
+	targetsFile := flag.String("targets-file", "", "diff: file with targets, one per line, as with --targets")
+	concurrency := flag.Int("concurrency", core.DefaultFleetConcurrency, "diff: maximum number of targets read concurrently, with --targets")
+	outputFormat := flag.String("output", "text", "Output format: text, json, markdown or html. json is supported with --targets. markdown is a diff report for pull request comments, html a self-contained diff report")
+	emit := flag.String("emit", core.EmitSQL, "diff: format of the diff statements: sql, gh-ost, pt-osc or vitess. gh-ost and pt-osc output a command line per ALTER TABLE that cannot run as INSTANT DDL. vitess outputs a vtctldclient ApplySchema script")
+	emitDatabase := flag.String("emit-database", "", "diff: database name given to gh-ost or pt-osc, or keyspace with --emit vitess. Defaults to the database of a MySQL DSN source")
+	emitTemplate := flag.String("emit-template", "", "diff: Go text/template of the gh-ost, pt-osc or vtctldclient command line, overriding the default, e.g. to add --max-load or --chunk-size")
+	emitDDLStrategy := flag.String("emit-ddl-strategy", "", "diff: ddl_strategy of diffs other than CREATE statements, with --emit vitess, e.g. \"online --postpone-completion\". Defaults to vitess")
+	emitKeyspaces := flag.StringArray("emit-keyspace", nil, "diff: apply the statements on an entity to a keyspace, as entity=keyspace, with --emit vitess. Other entities apply to the keyspace of --emit-database. May be repeated")
+	erdFormat := flag.String("erd-format", core.ERDMermaid, "erd: diagram format: dot, mermaid or plantuml")
+	erdColumns := flag.String("erd-columns", core.ERDColumnsAll, "erd: columns listed per table: all, keys (primary, unique and foreign key columns) or none")
+	planPolicies := flag.StringSlice("plan-policies", nil, "plan: comma separated expand/contract policies: nullable-first, index-before-drop, drop-fks-first and drop-columns-last, or none. Defaults to all policies")
+	planDir := flag.String("plan-dir", "", "plan: directory into which each phase is written as a migration file, rather than to standard output")
+	snapshot := flag.String("snapshot", "", "load: also write a snapshot of the loaded schema, with checksums and metadata, into this JSON file. A snapshot file is a valid input source")
+	stdinPair := flag.Bool("stdin-pair", false, "Read both the source and the target from standard input, separated by a --stdin-delimiter line, or as a {\"source\": ..., \"target\": ...} JSON object")
+	stdinDelimiter := flag.String("stdin-delimiter", base.DefaultStdinDelimiter, "Line separating the source from the target, with --stdin-pair")
+	dryRun := flag.Bool("dry-run", false, "apply-to: output the statements to apply, without executing them")
+	yes := flag.Bool("yes", false, "apply-to: execute the statements without prompting for confirmation")
+	lockWaitTimeout := flag.Duration("lock-wait-timeout", 10*time.Second, "apply-to: session lock_wait_timeout, limiting the wait for metadata locks. 0 keeps the server's setting")
+	innodbLockWaitTimeout := flag.Duration("innodb-lock-wait-timeout", 0, "apply-to: session innodb_lock_wait_timeout. 0 keeps the server's setting")
+	stopOnError := flag.Bool("stop-on-error", true, "apply-to: stop at the first failing statement, skipping the statements that follow it")
+	mergeBase := flag.String("base", "", "merge: common ancestor schema")
+	mergeOurs := flag.String("ours", "", "merge: our side of the merge")
+	mergeTheirs := flag.String("theirs", "", "merge: their side of the merge")
+	mergeDriver := flag.Bool("merge-driver", false, "merge: run as git merge driver, writing the merged schema into --ours as SQL")
+	listen := flag.String("listen", server.DefaultListen, "serve: address to listen on")
+	grpcListen := flag.String("grpc-listen", "", "serve: address to serve gRPC on, in addition to HTTP. Empty to disable")
+	allowDSNAddresses := flag.StringArray("allow-dsn-address", nil, "serve: allow requests to read schemas from this MySQL address (host:port or socket path). May be repeated")
+	maxRequestSize := flag.Int64("max-request-size", server.DefaultMaxRequestSize, "serve: maximum request body size in bytes")
+	requestTimeout := flag.Duration("request-timeout", server.DefaultRequestTimeout, "serve: maximum duration of a request")
 	flag.Parse()
 
 	args := flag.Args()
-	if len(args) != 1 {
-		exitWithError(errors.New("one argument expected. Usage: schemadiff [flags...] <load|diff|ordered-diff|diff-table|diff-view>"))
+	if len(args) < 1 {
+		exitWithError(errors.New("command expected. Usage: schemadiff [flags...] <load|diff|ordered-diff|diff-table|diff-view|merge|fingerprint|verify|explain-order|plan|erd|apply-to|git-diff-driver|serve>"))
 	}
 	command := args[0]
-	output, err := core.Exec(ctx, command, *source, *target, *textual)
+	if command == "serve" {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		srv := server.New(server.Config{
+			Listen:              *listen,
+			GRPCListen:          *grpcListen,
+			AllowedDSNAddresses: *allowDSNAddresses,
+			MaxRequestSize:      *maxRequestSize,
+			RequestTimeout:      *requestTimeout,
+		})
+		errs, ctx := errgroup.WithContext(ctx)
+		errs.Go(func() error { return srv.ListenAndServe(ctx) })
+		if *grpcListen != "" {
+			errs.Go(func() error { return srv.ListenAndServeGRPC(ctx) })
+		}
+		if err := errs.Wait(); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	colorEnabled, err := core.ColorEnabled(*color, os.Stdout)
 	if err != nil {
 		exitWithError(err)
 	}
+	opts := &core.ExecOptions{
+		Args:                  args[1:],
+		Color:                 colorEnabled,
+		Textual:               *textual,
+		IncludeInternalTables: *includeInternalTables,
+		Warnings:              os.Stderr,
+		Mapping:               *mapping,
+		MappingFile:           *mappingFile,
+		HeuristicRenames:      *heuristicRenames,
+		SuggestRenames:        *suggestRenames,
+		Base:                  *mergeBase,
+		Ours:                  *mergeOurs,
+		Theirs:                *mergeTheirs,
+		MergeDriver:           *mergeDriver,
+		Snapshot:              *snapshot,
+		Emit:                  *emit,
+		EmitDatabase:          *emitDatabase,
+		EmitTemplate:          *emitTemplate,
+		EmitDDLStrategy:       *emitDDLStrategy,
+		EmitKeyspaces:         *emitKeyspaces,
+		ERDFormat:             *erdFormat,
+		ERDColumns:            *erdColumns,
+		PlanPolicies:          *planPolicies,
+		PlanDir:               *planDir,
+		Targets:               *targets,
+		TargetsFile:           *targetsFile,
+		Concurrency:           *concurrency,
+		Output:                *outputFormat,
+		StdinPair:             *stdinPair,
+		StdinDelimiter:        *stdinDelimiter,
+		DryRun:                *dryRun,
+		Yes:                   *yes,
+		LockWaitTimeout:       *lockWaitTimeout,
+		InnoDBLockWaitTimeout: *innodbLockWaitTimeout,
+		ContinueOnError:       !*stopOnError,
+	}
+	output, err := core.Exec(ctx, command, *source, *target, opts)
+	// apply-to outputs the statements it executed even if it fails
 	fmt.Print(output)
+	if err != nil {
+		exitWithError(err)
+	}
 }
@@ -0,0 +1,318 @@
+package base
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// Default limits of ArchiveSource, which guard against archives that decompress to excessive sizes ("zip bombs").
+const (
+	DefaultArchiveMaxFileSize  = 16 * 1024 * 1024
+	DefaultArchiveMaxTotalSize = 256 * 1024 * 1024
+	DefaultArchiveMaxEntries   = 100000
+)
+
+type archiveFormat int
+
+const (
+	unknownArchiveFormat archiveFormat = iota
+	tarArchiveFormat
+	tarGzipArchiveFormat
+	zipArchiveFormat
+)
+
+// ArchiveSource reads the .sql files in a .tar, .tar.gz, .tgz or .zip archive, like DirectorySource reads the .sql
+// files in a directory. The value is the archive file name, optionally followed by "#" and the directory within
+// the archive to read, e.g. "bundle.tgz#db/schema". Without such a subpath, the archive's top directory is read,
+// or, if it has no .sql files and a single directory, as in an archive made by "tar czf schema.tgz schema/",
+// that directory. Reading fails if the directory read has no .sql files, which would otherwise read as an empty
+// schema. The archive is read in memory, never extracted to disk. Reading fails if it exceeds any of the limits.
+type ArchiveSource struct {
+	// MaxFileSize limits the size of a single .sql file. Defaults to DefaultArchiveMaxFileSize.
+	MaxFileSize int64
+	// MaxTotalSize limits the total decompressed size read: that of all .sql files read, and for a tar archive,
+	// that of the decompressed tar stream. Defaults to DefaultArchiveMaxTotalSize.
+	MaxTotalSize int64
+	// MaxEntries limits the number of entries in the archive. Defaults to DefaultArchiveMaxEntries.
+	MaxEntries int
+}
+
+func (s *ArchiveSource) Scheme() string {
+	return "archive"
+}
+
+// splitArchiveValue splits a value into the archive file name, and the cleaned subpath within the archive,
+// which is "." for the archive's top directory.
+func splitArchiveValue(inputSourceValue string) (fileName string, subpath string) {
+	fileName, subpath, _ = strings.Cut(inputSourceValue, "#")
+	if subpath = strings.TrimPrefix(path.Clean("/"+subpath), "/"); subpath == "" {
+		subpath = "."
+	}
+	return fileName, subpath
+}
+
+func detectArchiveFormat(fileName string) archiveFormat {
+	name := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return tarGzipArchiveFormat
+	case strings.HasSuffix(name, ".tar"):
+		return tarArchiveFormat
+	case strings.HasSuffix(name, ".zip"):
+		return zipArchiveFormat
+	}
+	return unknownArchiveFormat
+}
+
+// detect returns nil if the value is an existing archive file, or otherwise the reason it is not.
+func (s *ArchiveSource) detect(inputSourceValue string) error {
+	fileName, _ := splitArchiveValue(inputSourceValue)
+	if detectArchiveFormat(fileName) == unknownArchiveFormat {
+		return errors.New("not a .tar, .tar.gz, .tgz or .zip file name")
+	}
+	fileInfo, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	if fileInfo.IsDir() {
+		return errors.New("is a directory")
+	}
+	return nil
+}
+
+func (s *ArchiveSource) Detect(inputSourceValue string) bool {
+	return s.detect(inputSourceValue) == nil
+}
+
+func (s *ArchiveSource) ExplainDetect(inputSourceValue string) string {
+	if err := s.detect(inputSourceValue); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// archiveReader reads the .sql files of a single directory within an archive, while enforcing the limits.
+type archiveReader struct {
+	source    *ArchiveSource
+	value     string
+	subpath   string
+	entries   int
+	totalSize int64
+	// found is true when subpath is the top directory, or an entry lies within it
+	found bool
+	files map[string]string
+	// topDirs are the directories in the archive's top directory, and topFiles is true if it has any files
+	topDirs  map[string]bool
+	topFiles bool
+}
+
+func (r *archiveReader) limit(value int64, defaultValue int64) int64 {
+	if value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func (r *archiveReader) maxFileSize() int64 {
+	return r.limit(r.source.MaxFileSize, DefaultArchiveMaxFileSize)
+}
+
+func (r *archiveReader) maxTotalSize() int64 {
+	return r.limit(r.source.MaxTotalSize, DefaultArchiveMaxTotalSize)
+}
+
+func (r *archiveReader) maxEntries() int {
+	return int(r.limit(int64(r.source.MaxEntries), DefaultArchiveMaxEntries))
+}
+
+// visit accounts for an archive entry, and returns true if it is a .sql file that should be read.
+func (r *archiveReader) visit(name string, isRegular bool) (bool, error) {
+	r.entries++
+	if r.entries > r.maxEntries() {
+		return false, fmt.Errorf("archive %s has more than %d entries", r.value, r.maxEntries())
+	}
+	name = path.Clean("/" + name)[1:]
+	if top, _, nested := strings.Cut(name, "/"); nested || (!isRegular && name != "") {
+		r.topDirs[top] = true
+	} else if name != "" {
+		r.topFiles = true
+	}
+	if name == r.subpath || strings.HasPrefix(name, r.subpath+"/") {
+		r.found = true
+	}
+	dir := path.Dir(name)
+	return isRegular && dir == r.subpath && strings.ToLower(path.Ext(name)) == ".sql", nil
+}
+
+// readFile reads a .sql file from the archive, up to the file and total size limits.
+func (r *archiveReader) readFile(name string, reader io.Reader) error {
+	maxFileSize := r.maxFileSize()
+	b, err := io.ReadAll(io.LimitReader(reader, maxFileSize+1))
+	if err != nil {
+		return vterrors.Wrapf(err, "reading %s in archive %s", name, r.value)
+	}
+	if int64(len(b)) > maxFileSize {
+		return fmt.Errorf("%s in archive %s exceeds %d bytes", name, r.value, maxFileSize)
+	}
+	r.totalSize += int64(len(b))
+	if r.totalSize > r.maxTotalSize() {
+		return fmt.Errorf("archive %s exceeds %d bytes", r.value, r.maxTotalSize())
+	}
+	r.files[name] = string(b)
+	return nil
+}
+
+func (r *archiveReader) readTar(ctx context.Context, reader io.Reader) error {
+	// The limit applies to the whole decompressed stream, including the entries that are skipped.
+	limited := &io.LimitedReader{R: reader, N: r.maxTotalSize() + 1}
+	err := r.readTarEntries(ctx, tar.NewReader(limited))
+	if limited.N <= 0 {
+		return fmt.Errorf("archive %s exceeds %d bytes", r.value, r.maxTotalSize())
+	}
+	return err
+}
+
+func (r *archiveReader) readTarEntries(ctx context.Context, tarReader *tar.Reader) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return vterrors.Wrapf(err, "reading archive %s", r.value)
+		}
+		read, err := r.visit(header.Name, header.Typeflag == tar.TypeReg)
+		if err != nil {
+			return err
+		}
+		if read {
+			if err := r.readFile(header.Name, tarReader); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *archiveReader) readZip(ctx context.Context, file *os.File) error {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	zipReader, err := zip.NewReader(file, fileInfo.Size())
+	if err != nil {
+		return vterrors.Wrapf(err, "reading archive %s", r.value)
+	}
+	for _, f := range zipReader.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		read, err := r.visit(f.Name, f.Mode().IsRegular())
+		if err != nil {
+			return err
+		}
+		if !read {
+			continue
+		}
+		// The declared size may lie; readFile limits the size actually read.
+		if f.UncompressedSize64 > uint64(r.maxFileSize()) {
+			return fmt.Errorf("%s in archive %s exceeds %d bytes", f.Name, r.value, r.maxFileSize())
+		}
+		reader, err := f.Open()
+		if err != nil {
+			return vterrors.Wrapf(err, "reading %s in archive %s", f.Name, r.value)
+		}
+		err = r.readFile(f.Name, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read reads the .sql files of the given directory within the archive.
+func (s *ArchiveSource) read(ctx context.Context, fileName string, format archiveFormat, subpath string) (*archiveReader, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, vterrors.Wrapf(err, "reading archive %s", fileName)
+	}
+	defer file.Close()
+
+	r := &archiveReader{source: s, value: fileName, subpath: subpath, found: subpath == ".", files: map[string]string{}, topDirs: map[string]bool{}}
+	switch format {
+	case tarArchiveFormat:
+		err = r.readTar(ctx, file)
+	case tarGzipArchiveFormat:
+		gzipReader, gzipErr := gzip.NewReader(file)
+		if gzipErr != nil {
+			return nil, vterrors.Wrapf(gzipErr, "reading archive %s", fileName)
+		}
+		defer gzipReader.Close()
+		err = r.readTar(ctx, gzipReader)
+	case zipArchiveFormat:
+		err = r.readZip(ctx, file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !r.found {
+		return nil, fmt.Errorf("no directory %s in archive %s", subpath, fileName)
+	}
+	return r, nil
+}
+
+func (s *ArchiveSource) Read(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	fileName, subpath := splitArchiveValue(inputSourceValue)
+	format := detectArchiveFormat(fileName)
+	if format == unknownArchiveFormat {
+		return nil, fmt.Errorf("%s: unsupported archive, expected a .tar, .tar.gz, .tgz or .zip file", fileName)
+	}
+	r, err := s.read(ctx, fileName, format, subpath)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.files) == 0 && subpath == "." && !r.topFiles && len(r.topDirs) == 1 {
+		// The archive bundles a single directory; read it rather than the empty top directory
+		for dir := range r.topDirs {
+			subpath = dir
+		}
+		if r, err = s.read(ctx, fileName, format, subpath); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.files) == 0 {
+		if subpath == "." {
+			return nil, fmt.Errorf("no .sql files in the top directory of archive %s", fileName)
+		}
+		return nil, fmt.Errorf("no .sql files in directory %s of archive %s", subpath, fileName)
+	}
+	names := make([]string, 0, len(r.files))
+	for name := range r.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sqls := make([]string, 0, len(names))
+	for _, name := range names {
+		sqls = append(sqls, r.files[name])
+	}
+	return sqls, nil
+}
+
+func (s *ArchiveSource) Describe(inputSourceValue string) string {
+	return "archive " + inputSourceValue
+}
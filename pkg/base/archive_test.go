@@ -0,0 +1,233 @@
+package base
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type archiveEntry struct {
+	name    string
+	content string
+}
+
+// writeArchive writes the given entries into an archive, its format implied by the file name. An entry whose
+// name ends with "/" is a directory.
+func writeArchive(t *testing.T, fileName string, entries []archiveEntry) string {
+	fileName = filepath.Join(t.TempDir(), fileName)
+	var b bytes.Buffer
+	switch format := detectArchiveFormat(fileName); format {
+	case tarArchiveFormat, tarGzipArchiveFormat:
+		var w bytes.Buffer
+		tarWriter := tar.NewWriter(&w)
+		for _, entry := range entries {
+			header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+			if strings.HasSuffix(entry.name, "/") {
+				header.Mode, header.Typeflag = 0755, tar.TypeDir
+			}
+			require.NoError(t, tarWriter.WriteHeader(header))
+			_, err := tarWriter.Write([]byte(entry.content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tarWriter.Close())
+		if format == tarArchiveFormat {
+			b = w
+			break
+		}
+		gzipWriter := gzip.NewWriter(&b)
+		_, err := gzipWriter.Write(w.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, gzipWriter.Close())
+	case zipArchiveFormat:
+		zipWriter := zip.NewWriter(&b)
+		for _, entry := range entries {
+			w, err := zipWriter.Create(entry.name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(entry.content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zipWriter.Close())
+	default:
+		require.Fail(t, "unsupported archive", fileName)
+	}
+	require.NoError(t, os.WriteFile(fileName, b.Bytes(), 0644))
+	return fileName
+}
+
+func TestArchiveSource(t *testing.T) {
+	entries := []archiveEntry{
+		{"t2.sql", "create table t2 (id int primary key)"},
+		{"t1.sql", "create table t1 (id int primary key)"},
+		{"README.md", "not a schema"},
+		{"db/", ""},
+		{"db/schema/", ""},
+		{"./db/schema/v1.SQL", "create view v1 as select id from t1"},
+		{"db/schema/nested/t3.sql", "create table t3 (id int primary key)"},
+	}
+	ctx := context.Background()
+	for _, fileName := range []string{"schema.tar", "schema.tar.gz", "schema.tgz", "schema.zip"} {
+		t.Run(fileName, func(t *testing.T) {
+			archive := writeArchive(t, fileName, entries)
+			tcases := []struct {
+				value       string
+				expectSQLs  []string
+				expectError string
+			}{
+				{
+					value:      archive,
+					expectSQLs: []string{"create table t1 (id int primary key)", "create table t2 (id int primary key)"},
+				},
+				{
+					value:      archive + "#db/schema",
+					expectSQLs: []string{"create view v1 as select id from t1"},
+				},
+				{
+					value:      archive + "#/db/schema/",
+					expectSQLs: []string{"create view v1 as select id from t1"},
+				},
+				{
+					value:       archive + "#db",
+					expectError: "no .sql files in directory db of archive",
+				},
+				{
+					value:       archive + "#db/other",
+					expectError: "no directory db/other in archive",
+				},
+			}
+			for _, tcase := range tcases {
+				t.Run(tcase.value, func(t *testing.T) {
+					source, value, err := DetectSchemaSource(tcase.value)
+					require.NoError(t, err)
+					require.IsType(t, &ArchiveSource{}, source)
+
+					sqls, err := source.Read(ctx, nil, value, nil)
+					if tcase.expectError != "" {
+						assert.ErrorContains(t, err, tcase.expectError)
+						return
+					}
+					require.NoError(t, err)
+					assert.Equal(t, tcase.expectSQLs, sqls)
+				})
+			}
+		})
+	}
+}
+
+func TestArchiveSourceSingleDirectory(t *testing.T) {
+	ctx := context.Background()
+	for _, fileName := range []string{"schema.tgz", "schema.zip"} {
+		t.Run(fileName, func(t *testing.T) {
+			// As made by "tar czf schema.tgz schema/"
+			archive := writeArchive(t, fileName, []archiveEntry{
+				{"schema/", ""},
+				{"schema/t1.sql", "create table t1 (id int primary key)"},
+				{"schema/nested/t2.sql", "create table t2 (id int primary key)"},
+			})
+			sqls, err := (&ArchiveSource{}).Read(ctx, nil, archive, nil)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"create table t1 (id int primary key)"}, sqls)
+
+			// Two top directories are ambiguous
+			archive = writeArchive(t, fileName, []archiveEntry{
+				{"v1/t1.sql", "create table t1 (id int primary key)"},
+				{"v2/t1.sql", "create table t1 (id int primary key)"},
+			})
+			_, err = (&ArchiveSource{}).Read(ctx, nil, archive, nil)
+			assert.EqualError(t, err, "no .sql files in the top directory of archive "+archive)
+
+			// A single directory without .sql files
+			archive = writeArchive(t, fileName, []archiveEntry{{"docs/README.md", "not a schema"}})
+			_, err = (&ArchiveSource{}).Read(ctx, nil, archive, nil)
+			assert.EqualError(t, err, "no .sql files in directory docs of archive "+archive)
+		})
+	}
+}
+
+func TestArchiveSourceLimits(t *testing.T) {
+	ctx := context.Background()
+	entries := []archiveEntry{
+		{"t1.sql", strings.Repeat(" ", 100) + "create table t1 (id int primary key)"},
+		{"t2.sql", "create table t2 (id int primary key)"},
+		{"skipped/large.txt", strings.Repeat(" ", 1000)},
+	}
+	for _, fileName := range []string{"schema.tar.gz", "schema.zip"} {
+		t.Run(fileName, func(t *testing.T) {
+			archive := writeArchive(t, fileName, entries)
+			tcases := []struct {
+				name        string
+				source      *ArchiveSource
+				expectError string
+			}{
+				{
+					name:   "within limits",
+					source: &ArchiveSource{MaxFileSize: 200, MaxTotalSize: 10000, MaxEntries: 3},
+				},
+				{
+					name:        "file size",
+					source:      &ArchiveSource{MaxFileSize: 100},
+					expectError: "t1.sql in archive " + archive + " exceeds 100 bytes",
+				},
+				{
+					name:        "total size",
+					source:      &ArchiveSource{MaxTotalSize: 150},
+					expectError: "archive " + archive + " exceeds 150 bytes",
+				},
+				{
+					name:        "entries",
+					source:      &ArchiveSource{MaxEntries: 2},
+					expectError: "archive " + archive + " has more than 2 entries",
+				},
+			}
+			for _, tcase := range tcases {
+				t.Run(tcase.name, func(t *testing.T) {
+					sqls, err := tcase.source.Read(ctx, nil, archive, nil)
+					if tcase.expectError != "" {
+						assert.EqualError(t, err, tcase.expectError)
+						return
+					}
+					require.NoError(t, err)
+					assert.Len(t, sqls, 2)
+				})
+			}
+		})
+	}
+	t.Run("decompressed tar stream", func(t *testing.T) {
+		// A small compressed archive, whose skipped entry decompresses beyond the limit
+		archive := writeArchive(t, "bomb.tgz", []archiveEntry{{"zeros.bin", strings.Repeat("\x00", 1024*1024)}})
+		fileInfo, err := os.Stat(archive)
+		require.NoError(t, err)
+		assert.Less(t, fileInfo.Size(), int64(16*1024))
+
+		_, err = (&ArchiveSource{MaxTotalSize: 64 * 1024}).Read(ctx, nil, archive, nil)
+		assert.EqualError(t, err, "archive "+archive+" exceeds 65536 bytes")
+	})
+}
+
+func TestArchiveSourceDetect(t *testing.T) {
+	dir := t.TempDir()
+	notArchive := filepath.Join(dir, "schema.sql")
+	require.NoError(t, os.WriteFile(notArchive, []byte("create table t (id int primary key)"), 0644))
+	dirArchive := filepath.Join(dir, "schema.zip")
+	require.NoError(t, os.Mkdir(dirArchive, 0755))
+
+	source := &ArchiveSource{}
+	assert.False(t, source.Detect(notArchive))
+	assert.Equal(t, "not a .tar, .tar.gz, .tgz or .zip file name", source.ExplainDetect(notArchive))
+	assert.False(t, source.Detect(dirArchive))
+	assert.Equal(t, "is a directory", source.ExplainDetect(dirArchive))
+	assert.False(t, source.Detect(filepath.Join(dir, "missing.tgz#db")))
+	assert.Contains(t, source.ExplainDetect(filepath.Join(dir, "missing.tgz#db")), "no such file or directory")
+
+	inputSource, err := DetectInputSource(writeArchive(t, "schema.TGZ", nil) + "#db")
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveInputSource, inputSource)
+}
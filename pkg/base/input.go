@@ -2,9 +2,7 @@ package base
 
 import (
 	"fmt"
-	"os"
-
-	mysql "github.com/go-sql-driver/mysql"
+	"strings"
 )
 
 type InputSource int
@@ -15,31 +13,51 @@ const (
 	FileInputSource
 	DirectoryInputSource
 	UriInputSource
+	RegisteredInputSource
+	ArchiveInputSource
+	SnapshotInputSource
 )
 
 type ErrUnknownInputSource struct {
 	sourceVal string
+	// attempts lists, per attempted interpretation of the value, why it failed
+	attempts []string
 }
 
 func (e *ErrUnknownInputSource) Error() string {
-	return fmt.Sprintf("unknown input source: %s", e.sourceVal)
+	var b strings.Builder
+	fmt.Fprintf(&b, "unknown input source: %s", e.sourceVal)
+	if len(e.attempts) > 0 {
+		b.WriteString("; attempted:")
+		for _, attempt := range e.attempts {
+			fmt.Fprintf(&b, "\n- %s", attempt)
+		}
+		b.WriteString("\nuse a stdin:, file://, dir://, archive://, snapshot:// or mysql:// prefix to choose the input source explicitly")
+	}
+	return b.String()
 }
 
 // DetectInputSource auto detects the type of source by the given value; it checks if the value
-// is a file, directory, etc.
+// is a file, directory, etc. See DetectSchemaSource. Sources registered outside this package are
+// reported as RegisteredInputSource.
 func DetectInputSource(inputSourceValue string) (InputSource, error) {
-	if inputSourceValue == "" {
-		return StdInputSource, nil
+	source, _, err := DetectSchemaSource(inputSourceValue)
+	if err != nil {
+		return UnknownInputSource, err
 	}
-	if fileInfo, err := os.Stat(inputSourceValue); err == nil {
-		if fileInfo.IsDir() {
-			return DirectoryInputSource, nil
-		}
+	switch source.(type) {
+	case *StdinSource:
+		return StdInputSource, nil
+	case *FileSource:
 		return FileInputSource, nil
-	}
-	if _, err := mysql.ParseDSN(inputSourceValue); err == nil {
+	case *DirectorySource:
+		return DirectoryInputSource, nil
+	case *MySQLSource:
 		return UriInputSource, nil
+	case *ArchiveSource:
+		return ArchiveInputSource, nil
+	case *SnapshotSource:
+		return SnapshotInputSource, nil
 	}
-
-	return UnknownInputSource, &ErrUnknownInputSource{sourceVal: inputSourceValue}
+	return RegisteredInputSource, nil
 }
@@ -0,0 +1,58 @@
+package base
+
+import (
+	"regexp"
+)
+
+// onlineSchemaChangeTableNameRegexp matches names of tables that are artifacts of online schema change tools, which
+// are named after the table they migrate, given by the second submatch:
+//   - gh-ost ghost, changelog and old/deleted tables, e.g. _orders_gho, _orders_ghc, _orders_del, _orders_20240101120000_del
+//   - pt-online-schema-change new and old tables, e.g. _orders_new, _orders_old, or __orders_new if _orders_new is taken
+var onlineSchemaChangeTableNameRegexp = regexp.MustCompile(`^(_+)(.+?)(_[0-9]{14})?_(gho|ghc|del|new|old)$`)
+
+// internalTableNameRegexps match names of tables which Vitess creates for its own internal purposes. These are not
+// part of the "real" schema.
+var internalTableNameRegexps = []*regexp.Regexp{
+	// Vitess table lifecycle tables, legacy format, e.g. _vt_HOLD_6ace8bcef73211ea87e9f875a4d24e90_20200915120410
+	regexp.MustCompile(`^_vt_(HOLD|PURGE|EVAC|DROP)_[0-9a-f]{32}_[0-9]{14}$`),
+	// Vitess internal tables, e.g. _vt_hld_6ace8bcef73211ea87e9f875a4d24e90_20200915120410_
+	regexp.MustCompile(`^_vt_[a-z]{3}_[0-9a-f]{32}_[0-9]{14}_$`),
+	// Vitess Online DDL vreplication tables, legacy format, e.g. _1bb2a6b0_f0d7_11ea_a2b3_f875a4d24e90_20200915120410_vrepl
+	regexp.MustCompile(`^_[0-9a-f]{8}_[0-9a-f]{4}_[0-9a-f]{4}_[0-9a-f]{4}_[0-9a-f]{12}_[0-9]{14}_vrepl$`),
+}
+
+// isOnlineSchemaChangeTableName returns true when the given table name is that of a gh-ost or
+// pt-online-schema-change artifact table, and the table it is named after is one of the given table names.
+func isOnlineSchemaChangeTableName(name string, tableNames map[string]bool) bool {
+	match := onlineSchemaChangeTableNameRegexp.FindStringSubmatch(name)
+	if match == nil {
+		return false
+	}
+	underscores, table, timestamp := match[1], match[2], match[3]
+	// The migrated table may itself start with underscores, or end with what looks like a timestamp
+	for i := range underscores {
+		for _, candidate := range []string{underscores[i+1:] + table, underscores[i+1:] + table + timestamp} {
+			if tableNames[candidate] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsInternalTableName returns true when the given table name matches a well known naming pattern of
+// gh-ost or pt-online-schema-change artifact tables, or of Vitess internal tables (table lifecycle, Online DDL).
+// As gh-ost and pt-online-schema-change name their tables after the migrated table, e.g. _orders_old, such a
+// name only matches if the migrated table is one of the given names of the schema's tables. A table such as
+// _audit_old is otherwise a real table.
+func IsInternalTableName(name string, tableNames map[string]bool) bool {
+	if isOnlineSchemaChangeTableName(name, tableNames) {
+		return true
+	}
+	for _, re := range internalTableNameRegexps {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
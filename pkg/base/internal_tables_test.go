@@ -0,0 +1,48 @@
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInternalTableName(t *testing.T) {
+	// gh-ost and pt-online-schema-change tables are named after existing tables, and audit is none
+	tableNames := map[string]bool{"orders": true, "_queue": true}
+	tcases := []struct {
+		name   string
+		expect bool
+	}{
+		{"orders", false},
+		{"_orders", false},
+		{"orders_gho", false},
+		{"orders_old", false},
+		{"_orders_gho", true},
+		{"_orders_ghc", true},
+		{"_orders_del", true},
+		{"_orders_20240101120000_del", true},
+		{"_orders_new", true},
+		{"_orders_old", true},
+		{"__orders_new", true},
+		{"_audit_old", false},
+		{"_audit_gho", false},
+		{"__queue_old", true},
+		{"__queue_20240101120000_del", true},
+		{"_queue_old", false},
+		{"_vt_HOLD_6ace8bcef73211ea87e9f875a4d24e90_20200915120410", true},
+		{"_vt_EVAC_6ace8bcef73211ea87e9f875a4d24e90_20200915120410", true},
+		{"_vt_DROP_6ace8bcef73211ea87e9f875a4d24e90_20200915120410", true},
+		{"_vt_PURGE_6ace8bcef73211ea87e9f875a4d24e90_20200915120410", true},
+		{"_vt_HOLD_6ace8bcef73211ea87e9f875a4d24e90", false},
+		{"_vt_hld_6ace8bcef73211ea87e9f875a4d24e90_20200915120410_", true},
+		{"_vt_vrp_6ace8bcef73211ea87e9f875a4d24e90_20200915120410_", true},
+		{"_vt_hld_6ace8bcef73211ea87e9f875a4d24e90_20200915120410", false},
+		{"_1bb2a6b0_f0d7_11ea_a2b3_f875a4d24e90_20200915120410_vrepl", true},
+		{"_vt_settings", false},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			assert.Equal(t, tcase.expect, IsInternalTableName(tcase.name, tableNames))
+		})
+	}
+}
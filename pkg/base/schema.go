@@ -1,11 +1,11 @@
 package base
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -17,59 +17,34 @@ import (
 	"vitess.io/vitess/go/vt/vterrors"
 )
 
-// ReadSQLsFromSource returns a list of CREATE TABLE|VIEW statements as read from given input.
-func ReadSQLsFromSource(env *schemadiff.Environment, inputSourceValue string) (sqls []string, err error) {
-	inputSourceType, err := DetectInputSource(inputSourceValue)
+// ReadOptions customize the reading of a schema from an input source. A nil value is valid and implies defaults.
+type ReadOptions struct {
+	// IncludeInternalTables, when true, reads online schema change artifact tables and Vitess internal
+	// tables from a MySQL server. By default these are skipped. See IsInternalTableName().
+	IncludeInternalTables bool
+	// Warnings, if non nil, receives a line per skipped entity.
+	Warnings io.Writer
+	// StdinPair, if non nil, is read by the "stdin:source" and "stdin:target" inputs. See ReadStdinPair.
+	StdinPair *StdinPair
+}
+
+// ReadSQLsFromSource returns a list of CREATE TABLE|VIEW statements as read from given input, by the input's
+// registered SchemaSource. The context bounds the read, e.g. of a MySQL server.
+func ReadSQLsFromSource(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) (sqls []string, err error) {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+	source, value, err := DetectSchemaSource(inputSourceValue)
 	if err != nil {
 		return nil, vterrors.Wrapf(err, "cannot read schema")
 	}
-	switch inputSourceType {
-	case StdInputSource:
-		// Read standard input. It may contain any number (zero included) number of CREATE TABLE|VIEW statements,
-		// delimtied by ';'
-		b, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			return nil, vterrors.Wrapf(err, "reading standard output")
-		}
-		return env.Parser().SplitStatementToPieces(strings.TrimSpace(string(b)))
-	case FileInputSource:
-		// Read given file. It may contain any number (zero included) number of CREATE TABLE|VIEW statements,
-		// delimtied by ';'
-		b, err := os.ReadFile(inputSourceValue)
-		if err != nil {
-			return nil, vterrors.Wrapf(err, "reading file %s", inputSourceValue)
-		}
-		return env.Parser().SplitStatementToPieces(strings.TrimSpace(string(b)))
-	case DirectoryInputSource:
-		// Read all .sql files in this directory. Each file assumed to contain a single CREATE TABLE}VIEW statememt.
-		var sqls []string
-		files, err := os.ReadDir(inputSourceValue)
-		if err != nil {
-			return nil, vterrors.Wrapf(err, "reading directory %s", inputSourceValue)
-		}
-		for _, f := range files {
-			if f.Type().IsRegular() && strings.ToLower(filepath.Ext(f.Name())) == ".sql" {
-				fullPath := filepath.Join(inputSourceValue, f.Name())
-				b, err := os.ReadFile(fullPath)
-				if err != nil {
-					return nil, vterrors.Wrapf(err, "reading file %s", fullPath)
-				}
-				sqls = append(sqls, string(b))
-			}
-		}
-		return sqls, nil
-	case UriInputSource:
-		// Read schema from database:
-		return readDatabaseSchema(inputSourceValue)
-	default:
-		return nil, vterrors.Errorf(vtrpc.Code_UNIMPLEMENTED, "input source %v unimplemented", inputSourceValue)
-	}
+	return source.Read(ctx, env, value, opts)
 }
 
 // ReadSchemaFromSource returns a loaded, validated, normalized formal Schema from the given source,
 // or an error if either the source or the schema are invalid.
-func ReadSchemaFromSource(env *schemadiff.Environment, inputSourceValue string) (*schemadiff.Schema, error) {
-	sqls, err := ReadSQLsFromSource(env, inputSourceValue)
+func ReadSchemaFromSource(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) (*schemadiff.Schema, error) {
+	sqls, err := ReadSQLsFromSource(ctx, env, inputSourceValue, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -91,31 +66,94 @@ func writeEscapedString(original string) string {
 	return b.String()
 }
 
-// Given a MySQL connection config (which includes a database name), read CREATE statements for all tables and views
+// ReadDatabaseSchema, given a MySQL connection config (which includes a database name), reads CREATE statements for all tables and views
 // from given database.
 // The given DSN must incidcate a database name, e.g.:
 // - "myuser:mypass@unix(/var/lib/mysql/sandbox8032.sock)/mydb"
 // It may optionally include a specific table name, in the following way:
 // - "myuser:mypass@unix(/var/lib/mysql/sandbox8032.sock)/mydb?#mytable"
-func readDatabaseSchema(inputSourceValue string) ([]string, error) {
+// Unless opts.IncludeInternalTables is set, online schema change artifacts and Vitess internal tables are skipped,
+// with the exception of an explicitly requested table.
+func ReadDatabaseSchema(ctx context.Context, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	return readDatabaseSchema(ctx, inputSourceValue, opts)
+}
+
+// redactedDSN formats a MySQL connection config as a DSN without password and parameters.
+func redactedDSN(cfg *mysql.Config) string {
+	return fmt.Sprintf("%s@%s(%s)/%s", cfg.User, cfg.Net, cfg.Addr, cfg.DBName)
+}
+
+// openDatabase opens the database of the given DSN, which must contain a database name.
+func openDatabase(inputSourceValue string) (*sql.DB, *mysql.Config, error) {
 	cfg, err := mysql.ParseDSN(inputSourceValue)
 	if err != nil {
-		return nil, vterrors.Wrapf(err, "parsing DSN %s", inputSourceValue)
+		return nil, nil, vterrors.Wrapf(err, "parsing DSN %s", inputSourceValue)
 	}
 	if cfg.DBName == "" {
-		return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "DNS must contain schema name")
+		return nil, nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "DSN must contain a database name")
 	}
 	cfg.InterpolateParams = true
 
 	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, cfg, nil
+}
+
+// OpenDatabase opens the database of the given MySQL DSN, which must contain a database name. The caller is
+// responsible for closing it.
+func OpenDatabase(inputSourceValue string) (*sql.DB, error) {
+	db, _, err := openDatabase(inputSourceValue)
+	return db, err
+}
+
+// DatabaseName returns the database name of the given input if it is a MySQL DSN, and an empty string otherwise.
+func DatabaseName(inputSourceValue string) string {
+	source, value, err := DetectSchemaSource(inputSourceValue)
+	if err != nil {
+		return ""
+	}
+	if _, ok := source.(*MySQLSource); !ok {
+		return ""
+	}
+	cfg, err := mysql.ParseDSN(value)
+	if err != nil {
+		return ""
+	}
+	return cfg.DBName
+}
+
+// readDatabaseVersion returns the version of the MySQL server of the given DSN.
+func readDatabaseVersion(ctx context.Context, inputSourceValue string) (string, error) {
+	db, _, err := openDatabase(inputSourceValue)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT @@version").Scan(&version); err != nil {
+		return "", vterrors.Wrapf(err, "reading server version")
+	}
+	return version, nil
+}
+
+func readDatabaseSchema(ctx context.Context, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+	db, cfg, err := openDatabase(inputSourceValue)
 	if err != nil {
 		return nil, err
 	}
+	defer db.Close()
 	var explicitEntity string
 	if idx := strings.Index(inputSourceValue, "#"); idx >= 0 {
 		explicitEntity = inputSourceValue[idx+1:]
 	}
 	names := map[string]bool{} // key for table/view name, 'true' for table, 'false' for view
+	var skipped []string
 
 	// readNames reads names of all tables and views in the given database
 	readNames := func() error {
@@ -125,7 +163,7 @@ func readDatabaseSchema(inputSourceValue string) ([]string, error) {
 			query = query + " AND TABLE_NAME = ?"
 			args = append(args, explicitEntity)
 		}
-		rows, err := db.Query(query, args...)
+		rows, err := db.QueryContext(ctx, query, args...)
 
 		if err != nil {
 			return err
@@ -141,11 +179,36 @@ func readDatabaseSchema(inputSourceValue string) ([]string, error) {
 			isTable := (entityType == "BASE TABLE")
 			names[entityName] = isTable
 		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if explicitEntity != "" || opts.IncludeInternalTables {
+			return nil
+		}
+		// Artifact tables are recognized by the tables they are named after, so all names are read first
+		tableNames := map[string]bool{}
+		for name, isTable := range names {
+			if isTable {
+				tableNames[name] = true
+			}
+		}
+		for name, isTable := range names {
+			if isTable && IsInternalTableName(name, tableNames) {
+				skipped = append(skipped, name)
+				delete(names, name)
+			}
+		}
 		return nil
 	}
 	if err := readNames(); err != nil {
 		return nil, vterrors.Wrapf(err, "reading %s table and view names", writeEscapedString(cfg.DBName))
 	}
+	if opts.Warnings != nil {
+		sort.Strings(skipped)
+		for _, name := range skipped {
+			fmt.Fprintf(opts.Warnings, "skipped internal table %s; use --include-internal-tables to include it\n", writeEscapedString(name))
+		}
+	}
 	var sqls = make([]string, 0, len(names))
 	var mu sync.Mutex
 
@@ -161,7 +224,7 @@ func readDatabaseSchema(inputSourceValue string) ([]string, error) {
 			} else {
 				query = fmt.Sprintf("SHOW CREATE VIEW %s", writeEscapedString(name))
 			}
-			rows, err := db.Query(query)
+			rows, err := db.QueryContext(ctx, query)
 			if err != nil {
 				return vterrors.Wrapf(err, "showing CREATE statement for %s", name)
 			}
@@ -19,3 +19,18 @@ func TestWriteEscapedString(t *testing.T) {
 		})
 	}
 }
+
+func TestDatabaseName(t *testing.T) {
+	expectedNames := map[string]string{
+		"myuser:mypass@tcp(127.0.0.1:3306)/test":          "test",
+		"mysql://myuser@tcp(127.0.0.1:3306)/my_db?#t1":    "my_db",
+		"myuser@unix(/var/run/mysqld/mysqld.sock)/sakila": "sakila",
+		"/tmp/schema.sql": "",
+		"":                "",
+	}
+	for k, v := range expectedNames {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, v, DatabaseName(k))
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package base
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+const (
+	// SnapshotFormat identifies a schemadiff snapshot file
+	SnapshotFormat = "schemadiff-snapshot"
+	// SnapshotVersion is the version of the snapshot file format written by this package. Readers accept
+	// this version and earlier ones.
+	SnapshotVersion = 1
+)
+
+// Snapshot is a schema captured along with its context: where and when it was read, and how it was processed.
+// It is written as JSON, and read back by SnapshotSource.
+type Snapshot struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// CapturedAt is the time the schema was read
+	CapturedAt time.Time `json:"captured_at"`
+	// Source describes the input the schema was read from. It does not contain secrets.
+	Source string `json:"source"`
+	// ServerVersion is the version of the MySQL server the schema was read from, if any
+	ServerVersion string `json:"server_version,omitempty"`
+	// MySQLVersion is the MySQL version schemadiff emulated to normalize the schema
+	MySQLVersion string `json:"mysql_version,omitempty"`
+	// Hints are the diff hints in effect when the schema was captured
+	Hints *schemadiff.DiffHints `json:"hints,omitempty"`
+	// Entities are the normalized CREATE statements of the schema
+	Entities []*SnapshotEntity `json:"entities"`
+}
+
+// SnapshotEntity is the normalized CREATE statement of an entity, and its checksum.
+type SnapshotEntity struct {
+	Name      string `json:"name"`
+	Statement string `json:"statement"`
+	// SHA256 is the hex encoded SHA-256 checksum of Statement
+	SHA256 string `json:"sha256"`
+}
+
+// SnapshotChecksum returns the hex encoded SHA-256 checksum of a statement.
+func SnapshotChecksum(statement string) string {
+	checksum := sha256.Sum256([]byte(statement))
+	return hex.EncodeToString(checksum[:])
+}
+
+// NewSnapshot returns a snapshot of the given entities, captured now. The caller may set the other metadata fields.
+func NewSnapshot(entities []schemadiff.Entity) *Snapshot {
+	snapshot := &Snapshot{
+		Format:     SnapshotFormat,
+		Version:    SnapshotVersion,
+		CapturedAt: time.Now().UTC(),
+		Entities:   make([]*SnapshotEntity, 0, len(entities)),
+	}
+	for _, e := range entities {
+		statement := e.Create().CanonicalStatementString()
+		snapshot.Entities = append(snapshot.Entities, &SnapshotEntity{
+			Name:      e.Name(),
+			Statement: statement,
+			SHA256:    SnapshotChecksum(statement),
+		})
+	}
+	return snapshot
+}
+
+// ErrSnapshotChecksumMismatch is returned when the statements of a snapshot do not match their checksums,
+// e.g. because the snapshot was edited or corrupted.
+type ErrSnapshotChecksumMismatch struct {
+	// Entities are the names of the entities whose statement does not match its checksum
+	Entities []string
+}
+
+func (e *ErrSnapshotChecksumMismatch) Error() string {
+	return fmt.Sprintf("snapshot checksum mismatch for %s", strings.Join(e.Entities, ", "))
+}
+
+// Verify validates the format and version of the snapshot, and the checksums of its statements.
+func (s *Snapshot) Verify() error {
+	if s.Format != SnapshotFormat {
+		return fmt.Errorf("not a schemadiff snapshot: format is %q, expected %q", s.Format, SnapshotFormat)
+	}
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, expected up to %d", s.Version, SnapshotVersion)
+	}
+	mismatch := &ErrSnapshotChecksumMismatch{}
+	for _, e := range s.Entities {
+		if SnapshotChecksum(e.Statement) != e.SHA256 {
+			mismatch.Entities = append(mismatch.Entities, e.Name)
+		}
+	}
+	if len(mismatch.Entities) > 0 {
+		return mismatch
+	}
+	return nil
+}
+
+// Write writes the snapshot as indented JSON.
+func (s *Snapshot) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteFile writes the snapshot into the given file.
+func (s *Snapshot) WriteFile(fileName string) error {
+	var b strings.Builder
+	if err := s.Write(&b); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, []byte(b.String()), 0644)
+}
+
+// ReadSnapshot reads and verifies a snapshot.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, vterrors.Wrapf(err, "parsing snapshot")
+	}
+	if err := snapshot.Verify(); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ReadSnapshotFile reads and verifies a snapshot file.
+func ReadSnapshotFile(fileName string) (*Snapshot, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, vterrors.Wrapf(err, "reading snapshot %s", fileName)
+	}
+	defer f.Close()
+	snapshot, err := ReadSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return snapshot, nil
+}
+
+// SnapshotSource reads a snapshot file, written by Snapshot.WriteFile. It is detected by a file that starts with
+// a JSON object, which a file of CREATE statements never does. Reading fails if the snapshot's checksums do not
+// match its statements.
+type SnapshotSource struct{}
+
+func (s *SnapshotSource) Scheme() string {
+	return "snapshot"
+}
+
+// detect returns nil if the value is a file that starts with a JSON object, or otherwise the reason it is not.
+func (s *SnapshotSource) detect(inputSourceValue string) error {
+	f, err := os.Open(inputSourceValue)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fileInfo.IsDir() {
+		return errors.New("is a directory")
+	}
+	reader := bufio.NewReader(f)
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return errors.New("not a JSON object")
+		}
+		switch r {
+		case ' ', '\t', '\r', '\n', '\uFEFF':
+			continue
+		case '{':
+			return nil
+		}
+		return errors.New("not a JSON object")
+	}
+}
+
+func (s *SnapshotSource) Detect(inputSourceValue string) bool {
+	return s.detect(inputSourceValue) == nil
+}
+
+func (s *SnapshotSource) ExplainDetect(inputSourceValue string) string {
+	if err := s.detect(inputSourceValue); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (s *SnapshotSource) Read(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	snapshot, err := ReadSnapshotFile(inputSourceValue)
+	if err != nil {
+		return nil, err
+	}
+	sqls := make([]string, 0, len(snapshot.Entities))
+	for _, e := range snapshot.Entities {
+		sqls = append(sqls, e.Statement)
+	}
+	return sqls, nil
+}
+
+// ServerVersion returns the server version recorded in the snapshot.
+func (s *SnapshotSource) ServerVersion(ctx context.Context, inputSourceValue string) (string, error) {
+	snapshot, err := ReadSnapshotFile(inputSourceValue)
+	if err != nil {
+		return "", err
+	}
+	return snapshot.ServerVersion, nil
+}
+
+func (s *SnapshotSource) Describe(inputSourceValue string) string {
+	return "snapshot " + inputSourceValue
+}
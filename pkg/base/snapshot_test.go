@@ -0,0 +1,130 @@
+package base
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+func newTestSnapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Format:        SnapshotFormat,
+		Version:       SnapshotVersion,
+		Source:        "file schema.sql",
+		ServerVersion: "8.0.36",
+	}
+	for _, statement := range []string{
+		"CREATE TABLE `t1` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)",
+		"CREATE VIEW `v1` AS SELECT `id` FROM `t1`",
+	} {
+		name := strings.Trim(strings.Fields(statement)[2], "`")
+		snapshot.Entities = append(snapshot.Entities, &SnapshotEntity{Name: name, Statement: statement, SHA256: SnapshotChecksum(statement)})
+	}
+	return snapshot
+}
+
+func TestSnapshotVerify(t *testing.T) {
+	tcases := []struct {
+		name        string
+		modify      func(*Snapshot)
+		expectError string
+	}{
+		{
+			name:   "valid",
+			modify: func(s *Snapshot) {},
+		},
+		{
+			name:        "format",
+			modify:      func(s *Snapshot) { s.Format = "" },
+			expectError: `not a schemadiff snapshot: format is "", expected "schemadiff-snapshot"`,
+		},
+		{
+			name:        "future version",
+			modify:      func(s *Snapshot) { s.Version = SnapshotVersion + 1 },
+			expectError: "unsupported snapshot version 2, expected up to 1",
+		},
+		{
+			name:        "edited statement",
+			modify:      func(s *Snapshot) { s.Entities[1].Statement = "CREATE VIEW `v1` AS SELECT 1" },
+			expectError: "snapshot checksum mismatch for v1",
+		},
+		{
+			name: "edited checksums",
+			modify: func(s *Snapshot) {
+				s.Entities[0].SHA256 = ""
+				s.Entities[1].SHA256 = strings.ToUpper(s.Entities[1].SHA256)
+			},
+			expectError: "snapshot checksum mismatch for t1, v1",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			snapshot := newTestSnapshot()
+			tcase.modify(snapshot)
+			err := snapshot.Verify()
+			if tcase.expectError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tcase.expectError)
+		})
+	}
+}
+
+func TestSnapshotSource(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshot := newTestSnapshot()
+	fileName := filepath.Join(dir, "snapshot.json")
+	require.NoError(t, snapshot.WriteFile(fileName))
+
+	t.Run("round trip", func(t *testing.T) {
+		read, err := ReadSnapshotFile(fileName)
+		require.NoError(t, err)
+		assert.Equal(t, snapshot, read)
+	})
+	t.Run("detect", func(t *testing.T) {
+		source, value, err := DetectSchemaSource(fileName)
+		require.NoError(t, err)
+		assert.IsType(t, &SnapshotSource{}, source)
+		assert.Equal(t, fileName, value)
+		assert.Equal(t, "snapshot "+fileName, DescribeInputSource(fileName))
+
+		sqlFile := filepath.Join(dir, "schema.sql")
+		require.NoError(t, os.WriteFile(sqlFile, []byte("\n create table t (id int primary key)"), 0644))
+		assert.Equal(t, "not a JSON object", (&SnapshotSource{}).ExplainDetect(sqlFile))
+	})
+	t.Run("read", func(t *testing.T) {
+		sqls, err := (&SnapshotSource{}).Read(ctx, nil, fileName, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{snapshot.Entities[0].Statement, snapshot.Entities[1].Statement}, sqls)
+
+		version, err := ReadServerVersion(ctx, fileName)
+		require.NoError(t, err)
+		assert.Equal(t, "8.0.36", version)
+	})
+	t.Run("checksum mismatch", func(t *testing.T) {
+		b, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		editedFileName := filepath.Join(dir, "edited.json")
+		require.NoError(t, os.WriteFile(editedFileName, []byte(strings.Replace(string(b), "SELECT `id`", "SELECT `id` + 1", 1)), 0644))
+
+		_, err = ReadSQLsFromSource(context.Background(), schemadiff.NewTestEnv(), editedFileName, nil)
+		var mismatch *ErrSnapshotChecksumMismatch
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, []string{"v1"}, mismatch.Entities)
+		assert.EqualError(t, err, editedFileName+": snapshot checksum mismatch for v1")
+	})
+	t.Run("not a snapshot", func(t *testing.T) {
+		jsonFile := filepath.Join(dir, "other.json")
+		require.NoError(t, os.WriteFile(jsonFile, []byte(`{"source": "", "target": ""}`), 0644))
+		_, err := ReadSQLsFromSource(context.Background(), schemadiff.NewTestEnv(), jsonFile, nil)
+		assert.ErrorContains(t, err, "not a schemadiff snapshot")
+	})
+}
@@ -0,0 +1,163 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+// SchemaSource is a kind of input a schema can be read from, such as a file, a directory or a MySQL server.
+// Sources are registered with RegisterSchemaSource, and consulted in priority order to detect the source of
+// an input value. A value may also name its source explicitly with a scheme prefix, e.g. "file://schema.sql".
+type SchemaSource interface {
+	// Scheme is the unique name of the source, used as its explicit prefix, e.g. "file" for "file://schema.sql".
+	Scheme() string
+	// Detect returns true when the given value, which has no explicit scheme, is an input of this source.
+	Detect(inputSourceValue string) bool
+	// Read returns the CREATE TABLE|VIEW statements read from the given value.
+	Read(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) ([]string, error)
+	// Describe returns a human readable description of the given value, for messages. It must not reveal secrets.
+	Describe(inputSourceValue string) string
+}
+
+// SchemaSourceExplainer is optionally implemented by a SchemaSource, to explain why it does not detect a value.
+// The explanations of all sources are reported when no source detects a value.
+type SchemaSourceExplainer interface {
+	ExplainDetect(inputSourceValue string) string
+}
+
+// SchemaSourceVersioner is optionally implemented by a SchemaSource, to report the version of the server a value
+// is read from. See ReadServerVersion.
+type SchemaSourceVersioner interface {
+	ServerVersion(ctx context.Context, inputSourceValue string) (string, error)
+}
+
+// Priorities of the built in sources. Sources with a lower priority are consulted first. A registered source
+// that must take precedence over a built in source should use a lower priority than that source.
+const (
+	StdinSourcePriority     = 0
+	ArchiveSourcePriority   = 50
+	SnapshotSourcePriority  = 50
+	FileSourcePriority      = 100
+	DirectorySourcePriority = 100
+	MySQLSourcePriority     = 200
+)
+
+type schemaSourceRegistration struct {
+	source   SchemaSource
+	priority int
+}
+
+var schemaSources struct {
+	mu            sync.RWMutex
+	registrations []*schemaSourceRegistration
+}
+
+// RegisterSchemaSource registers a source with the given priority. Sources of equal priority are consulted
+// in registration order. It returns an error if a source with the same scheme is already registered.
+func RegisterSchemaSource(source SchemaSource, priority int) error {
+	schemaSources.mu.Lock()
+	defer schemaSources.mu.Unlock()
+
+	for _, registration := range schemaSources.registrations {
+		if registration.source.Scheme() == source.Scheme() {
+			return fmt.Errorf("schema source %q is already registered", source.Scheme())
+		}
+	}
+	schemaSources.registrations = append(schemaSources.registrations, &schemaSourceRegistration{source: source, priority: priority})
+	sort.SliceStable(schemaSources.registrations, func(i, j int) bool {
+		return schemaSources.registrations[i].priority < schemaSources.registrations[j].priority
+	})
+	return nil
+}
+
+// SchemaSources returns the registered sources, in priority order.
+func SchemaSources() []SchemaSource {
+	schemaSources.mu.RLock()
+	defer schemaSources.mu.RUnlock()
+
+	sources := make([]SchemaSource, 0, len(schemaSources.registrations))
+	for _, registration := range schemaSources.registrations {
+		sources = append(sources, registration.source)
+	}
+	return sources
+}
+
+// LookupSchemaSource returns the registered source of the given scheme, or nil if there is none.
+func LookupSchemaSource(scheme string) SchemaSource {
+	for _, source := range SchemaSources() {
+		if source.Scheme() == scheme {
+			return source
+		}
+	}
+	return nil
+}
+
+// bareSchemes are the schemes which may prefix a value as "scheme:value", as well as "scheme://value". Other
+// schemes may not, since a DSN such as "mysql:pw@tcp(db:3306)/app" starts with a user name.
+var bareSchemes = []string{"file", "dir"}
+
+// DetectSchemaSource returns the source of the given value, along with the value to pass to that source.
+// A value prefixed with a registered scheme, as in "scheme://value", is read by that scheme's source, with the
+// prefix removed. The bare "scheme:value" form is supported for the file and dir schemes, unless the value is a
+// DSN, and for "stdin:", "stdin:source" and "stdin:target". Otherwise, registered sources are consulted in
+// priority order. If none detects the value, the returned *ErrUnknownInputSource lists why each source did not.
+func DetectSchemaSource(inputSourceValue string) (SchemaSource, string, error) {
+	sourceErr := &ErrUnknownInputSource{sourceVal: inputSourceValue}
+	switch inputSourceValue {
+	case "stdin:", "stdin:" + StdinPairSource, "stdin:" + StdinPairTarget:
+		if source := LookupSchemaSource("stdin"); source != nil {
+			return source, strings.TrimPrefix(inputSourceValue, "stdin:"), nil
+		}
+	}
+	if scheme, value, ok := strings.Cut(inputSourceValue, "://"); ok {
+		if source := LookupSchemaSource(scheme); source != nil {
+			return source, value, nil
+		}
+		sourceErr.attempts = append(sourceErr.attempts, fmt.Sprintf("%s://: no such scheme", scheme))
+	} else if scheme, value, ok := strings.Cut(inputSourceValue, ":"); ok && value != "" && slices.Contains(bareSchemes, scheme) {
+		mysqlSource := LookupSchemaSource("mysql")
+		if source := LookupSchemaSource(scheme); source != nil && (mysqlSource == nil || !mysqlSource.Detect(inputSourceValue)) {
+			return source, value, nil
+		}
+	}
+	for _, source := range SchemaSources() {
+		if source.Detect(inputSourceValue) {
+			return source, inputSourceValue, nil
+		}
+		reason := "not detected"
+		if explainer, ok := source.(SchemaSourceExplainer); ok {
+			reason = explainer.ExplainDetect(inputSourceValue)
+		}
+		sourceErr.attempts = append(sourceErr.attempts, fmt.Sprintf("%s: %s", source.Scheme(), reason))
+	}
+	return nil, "", sourceErr
+}
+
+// ReadServerVersion returns the version of the server the given value is read from, or an empty string if its
+// source has no such version, as is the case for files.
+func ReadServerVersion(ctx context.Context, inputSourceValue string) (string, error) {
+	source, value, err := DetectSchemaSource(inputSourceValue)
+	if err != nil {
+		return "", err
+	}
+	if versioner, ok := source.(SchemaSourceVersioner); ok {
+		return versioner.ServerVersion(ctx, value)
+	}
+	return "", nil
+}
+
+// DescribeInputSource returns a human readable description of the given value, which does not reveal secrets
+// such as passwords.
+func DescribeInputSource(inputSourceValue string) string {
+	source, value, err := DetectSchemaSource(inputSourceValue)
+	if err != nil {
+		return "unknown input"
+	}
+	return source.Describe(value)
+}
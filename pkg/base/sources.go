@@ -0,0 +1,209 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+func init() {
+	for _, registration := range []*schemaSourceRegistration{
+		{source: &StdinSource{}, priority: StdinSourcePriority},
+		{source: &ArchiveSource{}, priority: ArchiveSourcePriority},
+		{source: &SnapshotSource{}, priority: SnapshotSourcePriority},
+		{source: &FileSource{}, priority: FileSourcePriority},
+		{source: &DirectorySource{}, priority: DirectorySourcePriority},
+		{source: &MySQLSource{}, priority: MySQLSourcePriority},
+	} {
+		if err := RegisterSchemaSource(registration.source, registration.priority); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// StdinSource reads standard input. It may contain any number (zero included) number of CREATE TABLE|VIEW statements,
+// delimtied by ';'. It is detected by an empty value, or by "-". The explicit "stdin:source" and "stdin:target"
+// inputs read the two sides of ReadOptions.StdinPair, when the source and target are read from standard input together.
+type StdinSource struct{}
+
+func (s *StdinSource) Scheme() string {
+	return "stdin"
+}
+
+func (s *StdinSource) Detect(inputSourceValue string) bool {
+	return inputSourceValue == "" || inputSourceValue == "-"
+}
+
+func (s *StdinSource) ExplainDetect(inputSourceValue string) string {
+	return `not empty, nor "-"`
+}
+
+func (s *StdinSource) Read(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	var text string
+	switch inputSourceValue {
+	case "", "-":
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, vterrors.Wrapf(err, "reading standard output")
+		}
+		text = string(b)
+	case StdinPairSource, StdinPairTarget:
+		if opts == nil || opts.StdinPair == nil {
+			return nil, fmt.Errorf("stdin:%s is only valid when reading the source and target from standard input together", inputSourceValue)
+		}
+		text = opts.StdinPair.Source
+		if inputSourceValue == StdinPairTarget {
+			text = opts.StdinPair.Target
+		}
+	default:
+		return nil, fmt.Errorf("unknown standard input %q, expected stdin:, stdin:%s or stdin:%s", inputSourceValue, StdinPairSource, StdinPairTarget)
+	}
+	return env.Parser().SplitStatementToPieces(strings.TrimSpace(text))
+}
+
+func (s *StdinSource) Describe(inputSourceValue string) string {
+	switch inputSourceValue {
+	case StdinPairSource, StdinPairTarget:
+		return inputSourceValue + " schema on standard input"
+	}
+	return "standard input"
+}
+
+// FileSource reads a file. It may contain any number (zero included) number of CREATE TABLE|VIEW statements,
+// delimtied by ';'.
+type FileSource struct{}
+
+func (s *FileSource) Scheme() string {
+	return "file"
+}
+
+func (s *FileSource) Detect(inputSourceValue string) bool {
+	fileInfo, err := os.Stat(inputSourceValue)
+	return err == nil && !fileInfo.IsDir()
+}
+
+func (s *FileSource) ExplainDetect(inputSourceValue string) string {
+	if _, err := os.Stat(inputSourceValue); err != nil {
+		return err.Error()
+	}
+	return "is a directory"
+}
+
+func (s *FileSource) Read(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	b, err := os.ReadFile(inputSourceValue)
+	if err != nil {
+		return nil, vterrors.Wrapf(err, "reading file %s", inputSourceValue)
+	}
+	return env.Parser().SplitStatementToPieces(strings.TrimSpace(string(b)))
+}
+
+func (s *FileSource) Describe(inputSourceValue string) string {
+	return "file " + inputSourceValue
+}
+
+// DirectorySource reads all .sql files in a directory. Each file assumed to contain a single CREATE TABLE|VIEW statememt.
+type DirectorySource struct{}
+
+func (s *DirectorySource) Scheme() string {
+	return "dir"
+}
+
+func (s *DirectorySource) Detect(inputSourceValue string) bool {
+	fileInfo, err := os.Stat(inputSourceValue)
+	return err == nil && fileInfo.IsDir()
+}
+
+func (s *DirectorySource) ExplainDetect(inputSourceValue string) string {
+	if _, err := os.Stat(inputSourceValue); err != nil {
+		return err.Error()
+	}
+	return "not a directory"
+}
+
+func (s *DirectorySource) Read(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	var sqls []string
+	files, err := os.ReadDir(inputSourceValue)
+	if err != nil {
+		return nil, vterrors.Wrapf(err, "reading directory %s", inputSourceValue)
+	}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if f.Type().IsRegular() && strings.ToLower(filepath.Ext(f.Name())) == ".sql" {
+			fullPath := filepath.Join(inputSourceValue, f.Name())
+			b, err := os.ReadFile(fullPath)
+			if err != nil {
+				return nil, vterrors.Wrapf(err, "reading file %s", fullPath)
+			}
+			sqls = append(sqls, string(b))
+		}
+	}
+	return sqls, nil
+}
+
+func (s *DirectorySource) Describe(inputSourceValue string) string {
+	return "directory " + inputSourceValue
+}
+
+// MySQLSource reads a schema from a MySQL server, given a DSN. See ReadDatabaseSchema.
+// Without an explicit "mysql://" prefix, a value is only detected as a DSN when it names a database, and
+// has a user ("user@") or a protocol and address ("tcp(host:port)") before the database name. This avoids
+// mistaking a mistyped path, such as "schemas/v2.sql", for a DSN.
+type MySQLSource struct{}
+
+func (s *MySQLSource) Scheme() string {
+	return "mysql"
+}
+
+// detect returns nil if the value is a DSN, or otherwise the reason it is not.
+func (s *MySQLSource) detect(inputSourceValue string) error {
+	cfg, err := mysql.ParseDSN(inputSourceValue)
+	if err != nil {
+		return err
+	}
+	if cfg.DBName == "" {
+		return errors.New("no database name")
+	}
+	prefix := inputSourceValue[:strings.LastIndex(inputSourceValue, "/")]
+	if !strings.Contains(prefix, "@") && !strings.Contains(prefix, "(") {
+		return errors.New(`no "user@" nor "protocol(address)" before the database name`)
+	}
+	return nil
+}
+
+func (s *MySQLSource) Detect(inputSourceValue string) bool {
+	return s.detect(inputSourceValue) == nil
+}
+
+func (s *MySQLSource) ExplainDetect(inputSourceValue string) string {
+	if err := s.detect(inputSourceValue); err != nil {
+		return fmt.Sprintf("not a DSN: %v", err)
+	}
+	return ""
+}
+
+func (s *MySQLSource) Read(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, opts *ReadOptions) ([]string, error) {
+	return readDatabaseSchema(ctx, inputSourceValue, opts)
+}
+
+func (s *MySQLSource) ServerVersion(ctx context.Context, inputSourceValue string) (string, error) {
+	return readDatabaseVersion(ctx, inputSourceValue)
+}
+
+// Describe returns the DSN without its password.
+func (s *MySQLSource) Describe(inputSourceValue string) string {
+	cfg, err := mysql.ParseDSN(inputSourceValue)
+	if err != nil {
+		return "MySQL server"
+	}
+	return "MySQL server " + redactedDSN(cfg)
+}
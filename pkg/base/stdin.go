@@ -0,0 +1,89 @@
+package base
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// DefaultStdinDelimiter is the line that separates the source schema from the target schema, when both are read
+// from a single standard input stream. Being a SQL comment, it keeps the stream valid SQL.
+const DefaultStdinDelimiter = "-- schemadiff:target"
+
+// Values of the stdin source that read one side of a StdinPair, as in "stdin:source" and "stdin:target".
+const (
+	StdinPairSource = "source"
+	StdinPairTarget = "target"
+)
+
+// StdinPair is a source schema and a target schema, read together from a single stream. See ReadStdinPair.
+type StdinPair struct {
+	Source string
+	Target string
+}
+
+// ReadStdinPair reads a source and a target schema from a single stream. The stream is either a JSON object,
+// {"source": "...", "target": "..."}, or the source schema, followed by a line consisting of the delimiter,
+// followed by the target schema. An empty delimiter implies DefaultStdinDelimiter.
+func ReadStdinPair(r io.Reader, delimiter string) (*StdinPair, error) {
+	if delimiter == "" {
+		delimiter = DefaultStdinDelimiter
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, vterrors.Wrapf(err, "reading standard input")
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		return parseStdinPairJSON(b)
+	}
+	return splitStdinPair(string(b), delimiter)
+}
+
+func parseStdinPairJSON(b []byte) (*StdinPair, error) {
+	var value struct {
+		Source *string `json:"source"`
+		Target *string `json:"target"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf(`parsing {"source": ..., "target": ...} JSON object from standard input: %w`, err)
+	}
+	if decoder.More() {
+		return nil, errors.New(`unexpected content after the {"source": ..., "target": ...} JSON object on standard input`)
+	}
+	switch {
+	case value.Source == nil:
+		return nil, errors.New(`JSON object on standard input has no "source" key`)
+	case value.Target == nil:
+		return nil, errors.New(`JSON object on standard input has no "target" key`)
+	}
+	return &StdinPair{Source: *value.Source, Target: *value.Target}, nil
+}
+
+func splitStdinPair(text string, delimiter string) (*StdinPair, error) {
+	var delimiterLines []int
+	var source, target strings.Builder
+	for i, line := range strings.SplitAfter(text, "\n") {
+		switch {
+		case strings.TrimSpace(line) == delimiter:
+			delimiterLines = append(delimiterLines, i+1)
+		case len(delimiterLines) == 0:
+			source.WriteString(line)
+		default:
+			target.WriteString(line)
+		}
+	}
+	switch len(delimiterLines) {
+	case 0:
+		return nil, fmt.Errorf(`no %q line separates the source schema from the target schema on standard input. Send the source, a %q line and the target, or a {"source": ..., "target": ...} JSON object`, delimiter, delimiter)
+	case 1:
+		return &StdinPair{Source: source.String(), Target: target.String()}, nil
+	}
+	return nil, fmt.Errorf("expected a single %q line on standard input, found %d, on lines %v", delimiter, len(delimiterLines), delimiterLines)
+}
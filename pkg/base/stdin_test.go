@@ -0,0 +1,138 @@
+package base
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+func TestReadStdinPair(t *testing.T) {
+	tcases := []struct {
+		name         string
+		input        string
+		delimiter    string
+		expectSource string
+		expectTarget string
+		expectError  string
+	}{
+		{
+			name:         "delimiter",
+			input:        "create table t (id int);\n-- schemadiff:target\ncreate table t (id bigint);\n",
+			expectSource: "create table t (id int);\n",
+			expectTarget: "create table t (id bigint);\n",
+		},
+		{
+			name:         "delimiter with surrounding spaces and CRLF",
+			input:        "create table t (id int);\r\n  -- schemadiff:target \r\ncreate table t (id bigint);",
+			expectSource: "create table t (id int);\r\n",
+			expectTarget: "create table t (id bigint);",
+		},
+		{
+			name:         "empty sides",
+			input:        "-- schemadiff:target",
+			expectSource: "",
+			expectTarget: "",
+		},
+		{
+			name:         "custom delimiter",
+			input:        "create table t (id int);\n====\ncreate table t (id bigint);\n",
+			delimiter:    "====",
+			expectSource: "create table t (id int);\n",
+			expectTarget: "create table t (id bigint);\n",
+		},
+		{
+			name:        "delimiter within a line",
+			input:       "create table t (id int); -- schemadiff:target\ncreate table t (id bigint);\n",
+			expectError: `no "-- schemadiff:target" line separates the source schema from the target schema`,
+		},
+		{
+			name:        "missing delimiter",
+			input:       "create table t (id int);\ncreate table t (id bigint);\n",
+			expectError: `no "-- schemadiff:target" line separates the source schema from the target schema`,
+		},
+		{
+			name:        "empty",
+			input:       "",
+			expectError: `no "-- schemadiff:target" line separates the source schema from the target schema`,
+		},
+		{
+			name:        "repeated delimiter",
+			input:       "create table t (id int);\n-- schemadiff:target\ncreate table t (id bigint);\n-- schemadiff:target\n",
+			expectError: `expected a single "-- schemadiff:target" line on standard input, found 2, on lines [2 4]`,
+		},
+		{
+			name:         "json",
+			input:        ` {"source": "create table t (id int)", "target": "create table t (id bigint)"}`,
+			expectSource: "create table t (id int)",
+			expectTarget: "create table t (id bigint)",
+		},
+		{
+			name:         "json empty source",
+			input:        `{"source": "", "target": "create table t (id bigint)"}`,
+			expectSource: "",
+			expectTarget: "create table t (id bigint)",
+		},
+		{
+			name:        "json missing target",
+			input:       `{"source": "create table t (id int)"}`,
+			expectError: `JSON object on standard input has no "target" key`,
+		},
+		{
+			name:        "json missing source",
+			input:       `{"target": "create table t (id int)"}`,
+			expectError: `JSON object on standard input has no "source" key`,
+		},
+		{
+			name:        "json unknown key",
+			input:       `{"source": "", "target": "", "base": ""}`,
+			expectError: `unknown field "base"`,
+		},
+		{
+			name:        "json invalid",
+			input:       `{"source": "create table t (id int)",`,
+			expectError: "parsing {",
+		},
+		{
+			name:        "json trailing content",
+			input:       `{"source": "", "target": ""} {}`,
+			expectError: "unexpected content after the",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			pair, err := ReadStdinPair(strings.NewReader(tcase.input), tcase.delimiter)
+			if tcase.expectError != "" {
+				assert.ErrorContains(t, err, tcase.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tcase.expectSource, pair.Source)
+			assert.Equal(t, tcase.expectTarget, pair.Target)
+		})
+	}
+}
+
+func TestReadStdinPairSides(t *testing.T) {
+	env := schemadiff.NewTestEnv()
+	opts := &ReadOptions{StdinPair: &StdinPair{
+		Source: "create table t1 (id int primary key)",
+		Target: "create table t1 (id int primary key); create table t2 (id int primary key)",
+	}}
+
+	sqls, err := ReadSQLsFromSource(context.Background(), env, "stdin:source", opts)
+	require.NoError(t, err)
+	assert.Len(t, sqls, 1)
+	sqls, err = ReadSQLsFromSource(context.Background(), env, "stdin:target", opts)
+	require.NoError(t, err)
+	assert.Len(t, sqls, 2)
+	assert.Equal(t, "target schema on standard input", DescribeInputSource("stdin:target"))
+
+	_, err = ReadSQLsFromSource(context.Background(), env, "stdin:source", nil)
+	assert.ErrorContains(t, err, "stdin:source is only valid when reading the source and target from standard input together")
+	_, err = ReadSQLsFromSource(context.Background(), env, "stdin:other", opts)
+	assert.ErrorContains(t, err, `unknown standard input "other"`)
+}
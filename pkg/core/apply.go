@@ -0,0 +1,234 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+// ApplyOptions modify the behavior of Runner.ApplyTo. A nil value is valid and implies defaults.
+type ApplyOptions struct {
+	// DryRun, when true, computes the statements to apply without executing them
+	DryRun bool
+	// Confirm, if non nil, is called with the description of the target and the statements to apply, before
+	// executing them. Returning false cancels the apply, with ErrApplyCancelled.
+	Confirm func(target string, statements []string) (bool, error)
+	// LockWaitTimeout, if positive, sets the session's lock_wait_timeout, rounded up to whole seconds. It limits
+	// the time a statement waits for a metadata lock, e.g. while a long running transaction uses the table.
+	LockWaitTimeout time.Duration
+	// InnoDBLockWaitTimeout, if positive, sets the session's innodb_lock_wait_timeout, rounded up to whole seconds
+	InnoDBLockWaitTimeout time.Duration
+	// ContinueOnError, when true, executes all statements even if some fail. By default, the apply stops at the
+	// first failing statement, and the statements that follow it are skipped.
+	ContinueOnError bool
+}
+
+// ApplyStatus is the status of a statement in an ApplyResult.
+type ApplyStatus string
+
+const (
+	// ApplyStatusPending is the status of statements not executed yet, as in a dry run
+	ApplyStatusPending ApplyStatus = "pending"
+	ApplyStatusApplied ApplyStatus = "applied"
+	ApplyStatusFailed  ApplyStatus = "failed"
+	// ApplyStatusSkipped is the status of statements that follow a failed statement
+	ApplyStatusSkipped ApplyStatus = "skipped"
+)
+
+// AppliedStatement is a statement of an ApplyResult, with the outcome of its execution.
+type AppliedStatement struct {
+	Statement string
+	Status    ApplyStatus
+	// Error is the error executing the statement, if it failed
+	Error string
+}
+
+// ApplyResult is the result of applying a schema onto a MySQL server.
+type ApplyResult struct {
+	// Target is the description of the target, which does not reveal secrets
+	Target string
+	DryRun bool
+	// Statements are the statements that turn the target schema into the source schema, in order
+	Statements []*AppliedStatement
+	// Verified is true when the target schema, read again after the statements were applied, equals the source schema
+	Verified bool
+	// Remaining are the diffs found between the target schema, read again after the statements were applied, and
+	// the source schema. It is empty when Verified.
+	Remaining []string
+}
+
+func pluralStatements(count int) string {
+	if count == 1 {
+		return "1 statement"
+	}
+	return fmt.Sprintf("%d statements", count)
+}
+
+// Write writes the result as SQL: each statement, followed by a comment with its status. Statements of a dry run
+// have no status.
+func (r *ApplyResult) Write(w io.Writer) error {
+	var b strings.Builder
+	switch {
+	case len(r.Statements) == 0:
+		fmt.Fprintf(&b, "-- %s already matches the source schema\n", r.Target)
+	case r.DryRun:
+		fmt.Fprintf(&b, "-- dry run: %s to apply to %s\n", pluralStatements(len(r.Statements)), r.Target)
+	default:
+		fmt.Fprintf(&b, "-- applying %s to %s\n", pluralStatements(len(r.Statements)), r.Target)
+	}
+	for _, s := range r.Statements {
+		fmt.Fprintf(&b, "%s;\n", s.Statement)
+		switch s.Status {
+		case ApplyStatusPending:
+		case ApplyStatusFailed:
+			fmt.Fprintf(&b, "-- failed: %s\n", s.Error)
+		default:
+			fmt.Fprintf(&b, "-- %s\n", s.Status)
+		}
+	}
+	if r.Verified && len(r.Statements) > 0 {
+		fmt.Fprintf(&b, "-- verified: %s matches the source schema\n", r.Target)
+	}
+	if len(r.Remaining) > 0 {
+		fmt.Fprintf(&b, "-- verification failed: %s differs from the source schema by:\n", r.Target)
+		for _, d := range r.Remaining {
+			fmt.Fprintf(&b, "%s;\n", d)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ApplyTo turns the schema of the target MySQL server into the source schema: it diffs the target against the
+// source, and executes the ordered diff statements on the target, one at a time, on a single connection. It
+// then reads the target schema again, and verifies it equals the source schema.
+//
+// The returned result is non nil when statements were computed, even if applying or verifying them failed, in
+// which case the error wraps ErrApplyFailed or ErrApplyVerification.
+func (r *Runner) ApplyTo(ctx context.Context, source string, target string, opts *ApplyOptions) (*ApplyResult, error) {
+	if opts == nil {
+		opts = &ApplyOptions{}
+	}
+	targetSource, targetValue, err := base.DetectSchemaSource(target)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := targetSource.(*base.MySQLSource); !ok {
+		return nil, ErrApplyTarget
+	}
+	if source == target {
+		return nil, ErrIdenticalSourceTarget
+	}
+	sourceSchema, err := LoadSchema(ctx, r.env, source, r.readOpts)
+	if err != nil {
+		return nil, err
+	}
+	targetSchema, err := LoadSchema(ctx, r.env, target, r.readOpts)
+	if err != nil {
+		return nil, err
+	}
+	preamble, diff, err := diffLoadedSchemasWithMapping(r.env, targetSchema, sourceSchema, r.opts.Mapping, r.opts.Hints)
+	if err != nil {
+		return nil, err
+	}
+	diffs, err := diff.OrderedDiffs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statements := preamble
+	for _, d := range diffs {
+		statements = append(statements, d.CanonicalStatementString())
+	}
+	result := &ApplyResult{Target: base.DescribeInputSource(target), DryRun: opts.DryRun}
+	for _, statement := range statements {
+		result.Statements = append(result.Statements, &AppliedStatement{Statement: statement, Status: ApplyStatusPending})
+	}
+	if len(statements) == 0 || opts.DryRun {
+		return result, nil
+	}
+	if opts.Confirm != nil {
+		confirmed, err := opts.Confirm(result.Target, statements)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return nil, ErrApplyCancelled
+		}
+	}
+
+	db, err := base.OpenDatabase(targetValue)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := applyStatements(ctx, db, result.Statements, opts); err != nil {
+		return result, err
+	}
+
+	appliedSchema, err := LoadSchema(ctx, r.env, target, r.readOpts)
+	if err != nil {
+		return result, fmt.Errorf("reading %s after apply: %w", result.Target, err)
+	}
+	verifyDiff, err := appliedSchema.SchemaDiff(sourceSchema, r.opts.Hints)
+	if err != nil {
+		return result, err
+	}
+	for _, d := range verifyDiff.UnorderedDiffs() {
+		result.Remaining = append(result.Remaining, d.CanonicalStatementString())
+	}
+	if len(result.Remaining) > 0 {
+		return result, fmt.Errorf("%w: %s differs from the source schema by %s", ErrApplyVerification, result.Target, pluralStatements(len(result.Remaining)))
+	}
+	result.Verified = true
+	return result, nil
+}
+
+// applyStatements executes the given statements in order, on a single connection, after setting the session's
+// lock wait timeouts. It sets the status of each statement, and returns an error wrapping ErrApplyFailed if any
+// statement failed.
+func applyStatements(ctx context.Context, db *sql.DB, statements []*AppliedStatement, opts *ApplyOptions) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, setting := range []struct {
+		variable string
+		timeout  time.Duration
+	}{
+		{"lock_wait_timeout", opts.LockWaitTimeout},
+		{"innodb_lock_wait_timeout", opts.InnoDBLockWaitTimeout},
+	} {
+		if setting.timeout <= 0 {
+			continue
+		}
+		seconds := (setting.timeout + time.Second - 1) / time.Second
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION %s = %d", setting.variable, seconds)); err != nil {
+			return fmt.Errorf("setting %s: %w", setting.variable, err)
+		}
+	}
+	failed := 0
+	for _, s := range statements {
+		if failed > 0 && !opts.ContinueOnError {
+			s.Status = ApplyStatusSkipped
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, s.Statement); err != nil {
+			s.Status = ApplyStatusFailed
+			s.Error = err.Error()
+			failed++
+			continue
+		}
+		s.Status = ApplyStatusApplied
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %s failed", ErrApplyFailed, failed, pluralStatements(len(statements)))
+	}
+	return nil
+}
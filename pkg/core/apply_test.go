@@ -0,0 +1,325 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+func TestApplyResultWrite(t *testing.T) {
+	tcases := []struct {
+		name   string
+		result *ApplyResult
+		expect string
+	}{
+		{
+			name:   "up to date",
+			result: &ApplyResult{Target: "MySQL server root@tcp(db:3306)/test", Verified: true},
+			expect: "-- MySQL server root@tcp(db:3306)/test already matches the source schema\n",
+		},
+		{
+			name: "dry run",
+			result: &ApplyResult{
+				Target: "MySQL server root@tcp(db:3306)/test",
+				DryRun: true,
+				Statements: []*AppliedStatement{
+					{Statement: "ALTER TABLE `t1` ADD COLUMN `c` int", Status: ApplyStatusPending},
+				},
+			},
+			expect: "-- dry run: 1 statement to apply to MySQL server root@tcp(db:3306)/test\nALTER TABLE `t1` ADD COLUMN `c` int;\n",
+		},
+		{
+			name: "verified",
+			result: &ApplyResult{
+				Target: "MySQL server root@tcp(db:3306)/test",
+				Statements: []*AppliedStatement{
+					{Statement: "ALTER TABLE `t1` ADD COLUMN `c` int", Status: ApplyStatusApplied},
+					{Statement: "DROP TABLE `t2`", Status: ApplyStatusApplied},
+				},
+				Verified: true,
+			},
+			expect: `-- applying 2 statements to MySQL server root@tcp(db:3306)/test
+ALTER TABLE ` + "`t1`" + ` ADD COLUMN ` + "`c`" + ` int;
+-- applied
+DROP TABLE ` + "`t2`" + `;
+-- applied
+-- verified: MySQL server root@tcp(db:3306)/test matches the source schema
+`,
+		},
+		{
+			name: "failed",
+			result: &ApplyResult{
+				Target: "MySQL server root@tcp(db:3306)/test",
+				Statements: []*AppliedStatement{
+					{Statement: "ALTER TABLE `t1` ADD COLUMN `c` int", Status: ApplyStatusFailed, Error: "Error 1205 (HY000): Lock wait timeout exceeded"},
+					{Statement: "DROP TABLE `t2`", Status: ApplyStatusSkipped},
+				},
+			},
+			expect: `-- applying 2 statements to MySQL server root@tcp(db:3306)/test
+ALTER TABLE ` + "`t1`" + ` ADD COLUMN ` + "`c`" + ` int;
+-- failed: Error 1205 (HY000): Lock wait timeout exceeded
+DROP TABLE ` + "`t2`" + `;
+-- skipped
+`,
+		},
+		{
+			name: "remaining",
+			result: &ApplyResult{
+				Target: "MySQL server root@tcp(db:3306)/test",
+				Statements: []*AppliedStatement{
+					{Statement: "DROP TABLE `t2`", Status: ApplyStatusApplied},
+				},
+				Remaining: []string{"DROP TABLE `t2`"},
+			},
+			expect: `-- applying 1 statement to MySQL server root@tcp(db:3306)/test
+DROP TABLE ` + "`t2`" + `;
+-- applied
+-- verification failed: MySQL server root@tcp(db:3306)/test differs from the source schema by:
+DROP TABLE ` + "`t2`" + `;
+`,
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			var b strings.Builder
+			require.NoError(t, tcase.result.Write(&b))
+			assert.Equal(t, tcase.expect, b.String())
+		})
+	}
+}
+
+func TestApplyStatements(t *testing.T) {
+	ctx := context.Background()
+	statements := []string{"CREATE TABLE `t1` (`id` int)", "CREATE TABLE `t2` (`id` int)", "CREATE TABLE `t3` (`id` int)"}
+	tcases := []struct {
+		name          string
+		opts          *ApplyOptions
+		expectStatus  []ApplyStatus
+		expectSession []string
+	}{
+		{
+			name:         "stop on error",
+			opts:         &ApplyOptions{},
+			expectStatus: []ApplyStatus{ApplyStatusApplied, ApplyStatusFailed, ApplyStatusSkipped},
+		},
+		{
+			name:         "continue on error",
+			opts:         &ApplyOptions{ContinueOnError: true},
+			expectStatus: []ApplyStatus{ApplyStatusApplied, ApplyStatusFailed, ApplyStatusApplied},
+		},
+		{
+			name:          "lock wait timeouts",
+			opts:          &ApplyOptions{LockWaitTimeout: 10 * time.Second, InnoDBLockWaitTimeout: 1500 * time.Millisecond},
+			expectStatus:  []ApplyStatus{ApplyStatusApplied, ApplyStatusFailed, ApplyStatusSkipped},
+			expectSession: []string{"SET SESSION lock_wait_timeout = 10", "SET SESSION innodb_lock_wait_timeout = 2"},
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			server := newFakeMySQL(t, nil)
+			server.exec = func(f *fakeMySQL, query string) error {
+				if strings.Contains(query, "`t2`") {
+					return &fakeMySQLError{code: 1205, message: "Lock wait timeout exceeded; try restarting transaction"}
+				}
+				return nil
+			}
+			db, err := base.OpenDatabase(server.DSN())
+			require.NoError(t, err)
+			defer db.Close()
+
+			var applied []*AppliedStatement
+			for _, statement := range statements {
+				applied = append(applied, &AppliedStatement{Statement: statement, Status: ApplyStatusPending})
+			}
+			err = applyStatements(ctx, db, applied, tcase.opts)
+			assert.ErrorIs(t, err, ErrApplyFailed)
+			assert.EqualError(t, err, "apply-to failed: 1 of 3 statements failed")
+			for i, expectStatus := range tcase.expectStatus {
+				assert.Equal(t, expectStatus, applied[i].Status, applied[i].Statement)
+			}
+			assert.Equal(t, "Error 1205 (HY000): Lock wait timeout exceeded; try restarting transaction", applied[1].Error)
+
+			var expectExecuted []string
+			expectExecuted = append(expectExecuted, tcase.expectSession...)
+			for _, s := range applied {
+				if s.Status != ApplyStatusSkipped {
+					expectExecuted = append(expectExecuted, s.Statement)
+				}
+			}
+			assert.Equal(t, expectExecuted, server.Executed())
+		})
+	}
+}
+
+func TestApplyTo(t *testing.T) {
+	ctx := context.Background()
+	liveT1 := "CREATE TABLE `t1` (\n  `id` int NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB"
+	liveT2 := "CREATE TABLE `t2` (\n  `id` int NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB"
+	desiredT1 := "CREATE TABLE `t1` (\n  `id` int NOT NULL,\n  `name` varchar(12) DEFAULT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB"
+	desired := []string{
+		"create table t1 (id int not null primary key, name varchar(12)) engine=innodb",
+		"create table t3 (id int not null primary key) engine=innodb",
+	}
+	sourceFile := writeSchemaFile(t, desired)
+	defer os.RemoveAll(sourceFile)
+
+	// applyDesired changes the fake server's schema as MySQL would for the statements that turn it into the desired schema
+	applyDesired := func(f *fakeMySQL, query string) error {
+		switch {
+		case strings.HasPrefix(query, "ALTER TABLE `t1`"):
+			f.SetEntity("t1", desiredT1)
+		case strings.HasPrefix(query, "DROP TABLE `t2`"):
+			f.SetEntity("t2", "")
+		case strings.HasPrefix(query, "CREATE TABLE `t3`"):
+			f.SetEntity("t3", strings.ReplaceAll(liveT2, "`t2`", "`t3`"))
+		default:
+			return errors.New("unexpected statement " + query)
+		}
+		return nil
+	}
+	newServer := func(t *testing.T) *fakeMySQL {
+		return newFakeMySQL(t, map[string]string{"t1": liveT1, "t2": liveT2})
+	}
+	runner, err := NewRunner(nil)
+	require.NoError(t, err)
+
+	t.Run("apply and verify", func(t *testing.T) {
+		server := newServer(t)
+		server.exec = applyDesired
+		var confirmed []string
+		result, err := runner.ApplyTo(ctx, sourceFile, server.DSN(), &ApplyOptions{
+			LockWaitTimeout: 5 * time.Second,
+			Confirm: func(target string, statements []string) (bool, error) {
+				assert.Equal(t, "MySQL server "+server.DSN(), target)
+				confirmed = statements
+				return true, nil
+			},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Verified)
+		require.Len(t, result.Statements, 3)
+		var statements []string
+		for _, s := range result.Statements {
+			assert.Equal(t, ApplyStatusApplied, s.Status)
+			statements = append(statements, s.Statement)
+		}
+		assert.Equal(t, statements, confirmed)
+		assert.Equal(t, append([]string{"SET SESSION lock_wait_timeout = 5"}, statements...), server.Executed())
+
+		result, err = runner.ApplyTo(ctx, sourceFile, server.DSN(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.Statements)
+	})
+	t.Run("dry run", func(t *testing.T) {
+		server := newServer(t)
+		result, err := runner.ApplyTo(ctx, sourceFile, server.DSN(), &ApplyOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Len(t, result.Statements, 3)
+		assert.Empty(t, server.Executed())
+	})
+	t.Run("mapping", func(t *testing.T) {
+		// The left side of a mapping names an entity on the target server, and the right side in the source
+		server := newServer(t)
+		mapping, err := ParseMapping([]string{"t2=t3"})
+		require.NoError(t, err)
+		mappingRunner, err := NewRunner(&Options{Mapping: mapping})
+		require.NoError(t, err)
+		result, err := mappingRunner.ApplyTo(ctx, sourceFile, server.DSN(), &ApplyOptions{DryRun: true})
+		require.NoError(t, err)
+		require.Len(t, result.Statements, 2)
+		assert.Equal(t, "RENAME TABLE `t2` TO `t3`", result.Statements[0].Statement)
+		assert.True(t, strings.HasPrefix(result.Statements[1].Statement, "ALTER TABLE `t1`"), result.Statements[1].Statement)
+
+		mapping, err = ParseMapping([]string{"t3=t2"})
+		require.NoError(t, err)
+		mappingRunner, err = NewRunner(&Options{Mapping: mapping})
+		require.NoError(t, err)
+		_, err = mappingRunner.ApplyTo(ctx, sourceFile, server.DSN(), &ApplyOptions{DryRun: true})
+		assert.ErrorContains(t, err, "entity t3 not found")
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		server := newServer(t)
+		_, err := runner.ApplyTo(ctx, sourceFile, server.DSN(), &ApplyOptions{
+			Confirm: func(string, []string) (bool, error) { return false, nil },
+		})
+		assert.ErrorIs(t, err, ErrApplyCancelled)
+		assert.Empty(t, server.Executed())
+	})
+	t.Run("statement fails", func(t *testing.T) {
+		server := newServer(t)
+		server.exec = func(f *fakeMySQL, query string) error {
+			if strings.HasPrefix(query, "DROP TABLE") {
+				return &fakeMySQLError{code: 1205, message: "Lock wait timeout exceeded; try restarting transaction"}
+			}
+			return applyDesired(f, query)
+		}
+		result, err := runner.ApplyTo(ctx, sourceFile, server.DSN(), nil)
+		assert.ErrorIs(t, err, ErrApplyFailed)
+		require.NotNil(t, result)
+		assert.False(t, result.Verified)
+		var failed int
+		for _, s := range result.Statements {
+			if s.Status == ApplyStatusFailed {
+				failed++
+			}
+		}
+		assert.Equal(t, 1, failed)
+	})
+	t.Run("verification fails", func(t *testing.T) {
+		server := newServer(t)
+		server.exec = func(f *fakeMySQL, query string) error {
+			if strings.HasPrefix(query, "ALTER TABLE `t1`") {
+				// Accepted, but not applied
+				return nil
+			}
+			return applyDesired(f, query)
+		}
+		result, err := runner.ApplyTo(ctx, sourceFile, server.DSN(), nil)
+		assert.ErrorIs(t, err, ErrApplyVerification)
+		require.NotNil(t, result)
+		assert.False(t, result.Verified)
+		require.Len(t, result.Remaining, 1)
+		assert.Contains(t, result.Remaining[0], "ALTER TABLE `t1` ADD COLUMN `name` varchar(12)")
+	})
+	t.Run("target not a database", func(t *testing.T) {
+		_, err := runner.ApplyTo(ctx, sourceFile, sourceFile, nil)
+		assert.ErrorIs(t, err, ErrApplyTarget)
+	})
+}
+
+func TestExecApplyTo(t *testing.T) {
+	ctx := context.Background()
+	sourceFile := writeSchemaFile(t, []string{"create table t1 (id int not null primary key) engine=innodb"})
+	defer os.RemoveAll(sourceFile)
+
+	t.Run("declined", func(t *testing.T) {
+		server := newFakeMySQL(t, nil)
+		var prompt strings.Builder
+		_, err := Exec(ctx, "apply-to", sourceFile, server.DSN(), &ExecOptions{Stdin: strings.NewReader("n\n"), Prompt: &prompt})
+		assert.ErrorIs(t, err, ErrApplyCancelled)
+		assert.Contains(t, prompt.String(), "CREATE TABLE `t1`")
+		assert.True(t, strings.HasSuffix(prompt.String(), "Apply 1 statement to MySQL server "+server.DSN()+"? [y/N] "), prompt.String())
+		assert.Empty(t, server.Executed())
+	})
+	t.Run("dry run", func(t *testing.T) {
+		server := newFakeMySQL(t, nil)
+		output, err := Exec(ctx, "apply-to", sourceFile, server.DSN(), &ExecOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(output, "-- dry run: 1 statement to apply to MySQL server "), output)
+		assert.Empty(t, server.Executed())
+	})
+	t.Run("stdin source requires --yes", func(t *testing.T) {
+		server := newFakeMySQL(t, nil)
+		_, err := Exec(ctx, "apply-to", "", server.DSN(), nil)
+		assert.ErrorContains(t, err, "use --yes or --dry-run")
+	})
+}
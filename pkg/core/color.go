@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Color modes, as given to ColorEnabled
+const (
+	// ColorAuto colorizes output written to a terminal, unless the NO_COLOR environment variable is set
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+const (
+	colorReset     = "\033[m"
+	colorBold      = "\033[1m"
+	colorNormal    = "\033[22m"
+	colorRed       = "\033[31m"
+	colorGreen     = "\033[32m"
+	colorBoldRed   = "\033[1;31m"
+	colorBoldGreen = "\033[1;32m"
+)
+
+// ColorEnabled returns whether output written to the given file should be colorized, in the given color mode. See
+// https://no-color.org for NO_COLOR.
+func ColorEnabled(mode string, out *os.File) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto, "":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fileInfo, err := out.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return fileInfo.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("unknown color mode %q, expected %s, %s or %s", mode, ColorAuto, ColorAlways, ColorNever)
+	}
+}
+
+// isIdentifierByte returns whether b may be part of an unquoted SQL word
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// highlightKeywords makes the keywords of a line of canonical SQL bold. Canonical SQL writes keywords in upper
+// case, data types in lower case, and quotes identifiers with backticks, so keywords are the upper case words
+// outside of quotes. Bold is turned off with colorNormal rather than colorReset, to keep the color of the line.
+func highlightKeywords(line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == '`' || c == '\'' || c == '"':
+			// Copy the quoted text, where the quote character is escaped by doubling it, or with a backslash
+			j := i + 1
+			for j < len(line) {
+				if line[j] == '\\' && c != '`' {
+					j += 2
+					continue
+				}
+				if line[j] == c {
+					if j+1 < len(line) && line[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(line))
+			b.WriteString(line[i:j])
+			i = j
+		case isIdentifierByte(c):
+			j := i
+			for j < len(line) && isIdentifierByte(line[j]) {
+				j++
+			}
+			word := line[i:j]
+			if word[0] >= 'A' && word[0] <= 'Z' && strings.ToUpper(word) == word {
+				b.WriteString(colorBold + word + colorNormal)
+			} else {
+				b.WriteString(word)
+			}
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// colorizeDiff adds terminal colors to a diff output: header lines are bold. In textual output, added
+// and removed lines are green and red, respectively, and the first line of each entity, which names it, is
+// bold. In SQL output, CREATE statements are green and DROP statements are red, across all of their lines,
+// and keywords are bold.
+func colorizeDiff(output string, textual bool) string {
+	var b strings.Builder
+	statementColor := ""
+	for _, line := range strings.SplitAfter(output, "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		eol := line[len(text):]
+		if text == "" {
+			b.WriteString(line)
+			continue
+		}
+		color := ""
+		switch {
+		case strings.HasPrefix(text, "diff --") || strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "+++ "):
+			color = colorBold
+		case textual:
+			entityHeader := strings.HasPrefix(text[1:], "CREATE ")
+			switch {
+			case strings.HasPrefix(text, "+") && entityHeader:
+				color = colorBoldGreen
+			case strings.HasPrefix(text, "+"):
+				color = colorGreen
+			case strings.HasPrefix(text, "-") && entityHeader:
+				color = colorBoldRed
+			case strings.HasPrefix(text, "-"):
+				color = colorRed
+			case entityHeader:
+				color = colorBold
+			}
+		default:
+			if statementColor == "" {
+				switch {
+				case strings.HasPrefix(text, "CREATE "):
+					statementColor = colorGreen
+				case strings.HasPrefix(text, "DROP "):
+					statementColor = colorRed
+				}
+			}
+			color = statementColor
+			if strings.HasSuffix(text, ";") {
+				statementColor = ""
+			}
+			text = highlightKeywords(text)
+		}
+		if color == "" {
+			b.WriteString(text)
+			b.WriteString(eol)
+			continue
+		}
+		b.WriteString(color)
+		b.WriteString(text)
+		b.WriteString(colorReset)
+		b.WriteString(eol)
+	}
+	return b.String()
+}
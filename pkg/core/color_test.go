@@ -0,0 +1,111 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColorizeDiff(t *testing.T) {
+	kw := func(keyword string) string { return "\033[1m" + keyword + "\033[22m" }
+	tcases := []struct {
+		name    string
+		output  string
+		textual bool
+		expect  string
+	}{
+		{
+			name:   "empty",
+			output: "",
+			expect: "",
+		},
+		{
+			name:   "sql",
+			output: "--- a/t.sql\n+++ b/t.sql\nDROP VIEW `v`;\nALTER TABLE `t` ADD COLUMN `i` int;\nCREATE TABLE `t2` (\n\t`id` int\n);\n",
+			expect: "\033[1m--- a/t.sql\033[m\n\033[1m+++ b/t.sql\033[m\n" +
+				"\033[31m" + kw("DROP") + " " + kw("VIEW") + " `v`;\033[m\n" +
+				kw("ALTER") + " " + kw("TABLE") + " `t` " + kw("ADD") + " " + kw("COLUMN") + " `i` int;\n" +
+				"\033[32m" + kw("CREATE") + " " + kw("TABLE") + " `t2` (\033[m\n\033[32m\t`id` int\033[m\n\033[32m);\033[m\n",
+		},
+		{
+			name:    "textual",
+			output:  " CREATE TABLE `t` (\n-\t`id` int,\n+\t`id` bigint,\n \tPRIMARY KEY (`id`)\n );\n",
+			textual: true,
+			expect:  "\033[1m CREATE TABLE `t` (\033[m\n\033[31m-\t`id` int,\033[m\n\033[32m+\t`id` bigint,\033[m\n \tPRIMARY KEY (`id`)\n );\n",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			assert.Equal(t, tcase.expect, colorizeDiff(tcase.output, tcase.textual))
+		})
+	}
+}
+
+func TestHighlightKeywords(t *testing.T) {
+	kw := func(keyword string) string { return "\033[1m" + keyword + "\033[22m" }
+	tcases := []struct {
+		line   string
+		expect string
+	}{
+		{
+			line:   "",
+			expect: "",
+		},
+		{
+			line:   "\t`id` int NOT NULL DEFAULT 0,",
+			expect: "\t`id` int " + kw("NOT") + " " + kw("NULL") + " " + kw("DEFAULT") + " 0,",
+		},
+		{
+			line:   "\t`NAME` varchar(12) COMMENT 'IT''S A NAME \\' NOT',",
+			expect: "\t`NAME` varchar(12) " + kw("COMMENT") + " 'IT''S A NAME \\' NOT',",
+		},
+		{
+			line:   "\t`ts` timestamp DEFAULT CURRENT_TIMESTAMP() ON UPDATE 1E3",
+			expect: "\t`ts` timestamp " + kw("DEFAULT") + " " + kw("CURRENT_TIMESTAMP") + "() " + kw("ON") + " " + kw("UPDATE") + " 1E3",
+		},
+		{
+			line:   "`unterminated",
+			expect: "`unterminated",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.line, func(t *testing.T) {
+			assert.Equal(t, tcase.expect, highlightKeywords(tcase.line))
+		})
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "output"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	tcases := []struct {
+		mode        string
+		noColor     string
+		expect      bool
+		expectError string
+	}{
+		{mode: ColorAlways, expect: true},
+		{mode: ColorAlways, noColor: "1", expect: true},
+		{mode: ColorNever, expect: false},
+		{mode: ColorAuto, expect: false},
+		{mode: "", expect: false},
+		{mode: "sometimes", expectError: `unknown color mode "sometimes"`},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.mode, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tcase.noColor)
+			enabled, err := ColorEnabled(tcase.mode, file)
+			if tcase.expectError != "" {
+				assert.ErrorContains(t, err, tcase.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tcase.expect, enabled)
+		})
+	}
+}
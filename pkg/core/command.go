@@ -0,0 +1,177 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Commands which Exec runs other than by Runner.Run
+const (
+	commandApply         Command = "apply"
+	commandApplyTo       Command = "apply-to"
+	commandGitDiffDriver Command = "git-diff-driver"
+)
+
+// commandSpec describes the inputs of a command, and the options which apply to it. Runner.Run reads the inputs
+// of a command by its spec, and Exec checks by it that the flags given apply to the command.
+type commandSpec struct {
+	command Command
+	// execOnly is true for commands which Exec runs, but Runner.Run does not
+	execOnly bool
+	// sourceTarget is true for commands which read both a source and a target, and so support Options.StdinPair
+	sourceTarget bool
+	// mapping is true for commands which apply Options.Mapping
+	mapping bool
+	// report is true for commands which result in Result.Diffs, which can be written as a markdown or HTML report
+	report bool
+	// schemaDiff is true for commands which diff whole schemas, and so support --emit and fleet mode
+	schemaDiff bool
+	// args is true for commands which accept positional arguments
+	args bool
+}
+
+var commandSpecs = map[Command]*commandSpec{}
+
+func init() {
+	for _, spec := range []*commandSpec{
+		{command: CommandLoad},
+		{command: CommandDiff, sourceTarget: true, mapping: true, report: true, schemaDiff: true},
+		{command: CommandOrderedDiff, sourceTarget: true, mapping: true, report: true, schemaDiff: true},
+		{command: CommandDiffTable, sourceTarget: true, report: true},
+		{command: CommandDiffView, sourceTarget: true, report: true},
+		{command: CommandSuggestRenames, sourceTarget: true, mapping: true},
+		{command: CommandMerge},
+		{command: CommandFingerprint},
+		{command: CommandVerify, sourceTarget: true},
+		{command: CommandERD},
+		{command: CommandExplainOrder, sourceTarget: true},
+		{command: CommandPlan, sourceTarget: true},
+		{command: commandApply, execOnly: true},
+		{command: commandApplyTo, execOnly: true, mapping: true},
+		{command: commandGitDiffDriver, execOnly: true, args: true},
+	} {
+		commandSpecs[spec.command] = spec
+	}
+}
+
+// lookupCommand returns the spec of the given command. When exec is false, commands which only Exec runs are unknown.
+func lookupCommand(command Command, exec bool) (*commandSpec, error) {
+	spec, ok := commandSpecs[command]
+	if !ok || (spec.execOnly && !exec) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, command)
+	}
+	return spec, nil
+}
+
+// validate checks that the given flags apply to the command, and to each other.
+func (spec *commandSpec) validate(opts *ExecOptions) error {
+	command := spec.command
+	fleet := len(opts.Targets) > 0 || opts.TargetsFile != ""
+	mapping := len(opts.Mapping) > 0 || opts.MappingFile != ""
+	emit := opts.Emit != "" && opts.Emit != EmitSQL
+	if len(opts.Args) > 0 && !spec.args {
+		return fmt.Errorf("unexpected arguments for command %s: %v", command, opts.Args)
+	}
+	switch opts.Output {
+	case "", "text":
+	case "json":
+		if !fleet {
+			return errors.New("--output json is supported with --targets and --targets-file")
+		}
+	case "markdown", "html":
+		if !spec.report {
+			return fmt.Errorf("--output %s applies to diff commands, not to %s", opts.Output, command)
+		}
+		if opts.Textual || opts.SuggestRenames || fleet || emit {
+			return fmt.Errorf("--output %s is not supported with --textual, --suggest-renames, --targets, --targets-file and --emit", opts.Output)
+		}
+	default:
+		return fmt.Errorf("unknown output format %q, expected text, json, markdown or html", opts.Output)
+	}
+	if opts.EmitDDLStrategy != "" && opts.Emit != EmitVitess {
+		return fmt.Errorf("--emit-ddl-strategy applies to --emit %s", EmitVitess)
+	}
+	if len(opts.EmitKeyspaces) > 0 && opts.Emit != EmitVitess {
+		return fmt.Errorf("--emit-keyspace applies to --emit %s", EmitVitess)
+	}
+	if emit {
+		if !spec.schemaDiff {
+			return fmt.Errorf("--emit applies to diff and ordered-diff, not to %s", command)
+		}
+		if opts.Textual || opts.SuggestRenames || fleet {
+			return errors.New("--emit is not supported with --textual, --suggest-renames, --targets and --targets-file")
+		}
+	} else if opts.EmitTemplate != "" {
+		return fmt.Errorf("--emit-template applies to --emit %s, %s and %s", EmitGhost, EmitPTOSC, EmitVitess)
+	}
+	if opts.Snapshot != "" && command != CommandLoad {
+		return fmt.Errorf("--snapshot applies to the load command, not to %s", command)
+	}
+	if opts.MergeDriver {
+		if command != CommandMerge {
+			return fmt.Errorf("--merge-driver applies to the merge command, not to %s", command)
+		}
+		if opts.Textual {
+			return errors.New("--merge-driver writes the merged schema as SQL, and is not supported with --textual")
+		}
+	}
+	if opts.SuggestRenames && !spec.schemaDiff && command != CommandSuggestRenames {
+		return fmt.Errorf("--suggest-renames applies to diff and ordered-diff, not to %s", command)
+	}
+	if opts.PlanDir != "" && command != CommandPlan {
+		return fmt.Errorf("--plan-dir applies to the plan command, not to %s", command)
+	}
+	if opts.PlanPolicies != nil && command != CommandPlan {
+		return fmt.Errorf("--plan-policies applies to the plan command, not to %s", command)
+	}
+	if mapping && !spec.mapping {
+		return fmt.Errorf("--map and --map-file are not supported by %s", command)
+	}
+	if fleet {
+		switch {
+		case !spec.schemaDiff:
+			return fmt.Errorf("--targets and --targets-file are supported by diff and ordered-diff, not by %s", command)
+		case mapping:
+			return ErrFleetMapping
+		case opts.SuggestRenames:
+			return errors.New("--suggest-renames is not supported with --targets and --targets-file")
+		}
+	}
+	return nil
+}
+
+// runnerOptions returns the Runner options implied by the given flags. The mapping is read only by commands which
+// apply it, and the plan policies are parsed only by plan.
+func (spec *commandSpec) runnerOptions(opts *ExecOptions) (*Options, error) {
+	runnerOpts := &Options{
+		Hints:                 diffHints(opts),
+		IncludeInternalTables: opts.IncludeInternalTables,
+		Warnings:              opts.Warnings,
+		StdinPair:             opts.StdinPair,
+		StdinDelimiter:        opts.StdinDelimiter,
+		Stdin:                 opts.Stdin,
+	}
+	if spec.mapping {
+		mappingValues := opts.Mapping
+		if opts.MappingFile != "" {
+			fileValues, err := ReadMappingFile(opts.MappingFile)
+			if err != nil {
+				return nil, err
+			}
+			mappingValues = append(fileValues, mappingValues...)
+		}
+		mapping, err := ParseMapping(mappingValues)
+		if err != nil {
+			return nil, err
+		}
+		runnerOpts.Mapping = mapping
+	}
+	if spec.command == CommandPlan && opts.PlanPolicies != nil {
+		policies, err := ParsePlanPolicies(opts.PlanPolicies)
+		if err != nil {
+			return nil, err
+		}
+		runnerOpts.PlanPolicies = policies
+	}
+	return runnerOpts, nil
+}
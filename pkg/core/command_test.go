@@ -0,0 +1,123 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCommand(t *testing.T) {
+	spec, err := lookupCommand(CommandDiff, false)
+	require.NoError(t, err)
+	assert.Equal(t, CommandDiff, spec.command)
+
+	_, err = lookupCommand(commandApplyTo, false)
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+	_, err = lookupCommand(commandApplyTo, true)
+	assert.NoError(t, err)
+	_, err = lookupCommand("no-such-command", true)
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+}
+
+func TestCommandSpecValidate(t *testing.T) {
+	tcases := []struct {
+		name        string
+		command     Command
+		opts        *ExecOptions
+		expectError string
+	}{
+		{
+			name:    "no flags",
+			command: CommandLoad,
+			opts:    &ExecOptions{},
+		},
+		{
+			name:        "arguments",
+			command:     CommandDiff,
+			opts:        &ExecOptions{Args: []string{"x"}},
+			expectError: "unexpected arguments for command diff: [x]",
+		},
+		{
+			name:    "git-diff-driver arguments",
+			command: commandGitDiffDriver,
+			opts:    &ExecOptions{Args: []string{"x"}},
+		},
+		{
+			name:    "mapping",
+			command: CommandSuggestRenames,
+			opts:    &ExecOptions{Mapping: []string{"t1=t2"}},
+		},
+		{
+			name:        "unsupported mapping",
+			command:     CommandDiffTable,
+			opts:        &ExecOptions{MappingFile: "mapping.txt"},
+			expectError: "--map and --map-file are not supported by diff-table",
+		},
+		{
+			name:        "fleet mapping",
+			command:     CommandDiff,
+			opts:        &ExecOptions{Targets: []string{"*.sql"}, Mapping: []string{"t1=t2"}},
+			expectError: ErrFleetMapping.Error(),
+		},
+		{
+			name:        "unsupported fleet",
+			command:     CommandVerify,
+			opts:        &ExecOptions{TargetsFile: "targets.txt"},
+			expectError: "--targets and --targets-file are supported by diff and ordered-diff, not by verify",
+		},
+		{
+			name:    "report",
+			command: CommandDiffView,
+			opts:    &ExecOptions{Output: "html"},
+		},
+		{
+			name:        "unsupported report",
+			command:     CommandSuggestRenames,
+			opts:        &ExecOptions{Output: "markdown"},
+			expectError: "--output markdown applies to diff commands, not to suggest-renames",
+		},
+		{
+			name:        "unsupported emit",
+			command:     CommandDiffTable,
+			opts:        &ExecOptions{Emit: EmitGhost},
+			expectError: "--emit applies to diff and ordered-diff, not to diff-table",
+		},
+		{
+			name:        "emit keyspace",
+			command:     CommandDiff,
+			opts:        &ExecOptions{Emit: EmitGhost, EmitKeyspaces: []string{"t1=ks"}},
+			expectError: "--emit-keyspace applies to --emit vitess",
+		},
+		{
+			name:        "unsupported plan policies",
+			command:     CommandDiff,
+			opts:        &ExecOptions{PlanPolicies: []string{"none"}},
+			expectError: "--plan-policies applies to the plan command, not to diff",
+		},
+		{
+			name:        "unsupported suggest renames",
+			command:     CommandVerify,
+			opts:        &ExecOptions{SuggestRenames: true},
+			expectError: "--suggest-renames applies to diff and ordered-diff, not to verify",
+		},
+		{
+			name:        "merge driver",
+			command:     CommandMerge,
+			opts:        &ExecOptions{MergeDriver: true, Textual: true},
+			expectError: "--merge-driver writes the merged schema as SQL, and is not supported with --textual",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			spec, err := lookupCommand(tcase.command, true)
+			require.NoError(t, err)
+			err = spec.validate(tcase.opts)
+			if tcase.expectError != "" {
+				assert.EqualError(t, err, tcase.expectError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 
 	"vitess.io/vitess/go/vt/schemadiff"
@@ -15,37 +16,51 @@ var (
 	}
 )
 
+// DefaultDiffHints returns a copy of the diff hints schemadiff uses by default.
+func DefaultDiffHints() *schemadiff.DiffHints {
+	hints := *defaultDiffHints
+	return &hints
+}
+
 // LoadSchema returns a Schema, loaded from given input. The Schema is loaded, validated and normalized.
 // Input can be stdin, file, directory, or MySQL URI.
-func LoadSchema(env *schemadiff.Environment, inputSourceValue string) (*schemadiff.Schema, error) {
-	return base.ReadSchemaFromSource(env, inputSourceValue)
+func LoadSchema(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, readOpts *base.ReadOptions) (*schemadiff.Schema, error) {
+	return base.ReadSchemaFromSource(ctx, env, inputSourceValue, readOpts)
 }
 
 // DiffSchemas returns a rich diff between two given schemas.
+// hints may be nil, in which case default hints apply.
 // Inputs can be stdin, file, directory, or MySQL URI.
-func DiffSchemas(env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string) (*schemadiff.SchemaDiff, error) {
-	sourceSchema, err := base.ReadSchemaFromSource(env, inputSourceValue)
+func DiffSchemas(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string, hints *schemadiff.DiffHints, readOpts *base.ReadOptions) (*schemadiff.SchemaDiff, error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
+	sourceSchema, err := base.ReadSchemaFromSource(ctx, env, inputSourceValue, readOpts)
 	if err != nil {
 		return nil, err
 	}
-	targetSchema, err := base.ReadSchemaFromSource(env, targetInputSourceValue)
+	targetSchema, err := base.ReadSchemaFromSource(ctx, env, targetInputSourceValue, readOpts)
 	if err != nil {
 		return nil, err
 	}
-	return sourceSchema.SchemaDiff(targetSchema, defaultDiffHints)
+	return sourceSchema.SchemaDiff(targetSchema, hints)
 }
 
 // DiffTables returns a rich diff between two given tables. The function expect the inputs to each
 // contain a single CREATE TABLE statement, and returns with error if not so. The two tables are allowed to have different names.
+// hints may be nil, in which case default hints apply.
 // Inputs can be stdin, file, directory, or MySQL URI.
-func DiffTables(env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string) (schemadiff.EntityDiff, error) {
+func DiffTables(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string, hints *schemadiff.DiffHints, readOpts *base.ReadOptions) (schemadiff.EntityDiff, error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
 	readTableSQL := func(sourceValue string) (string, error) {
-		sqls, err := base.ReadSQLsFromSource(env, sourceValue)
+		sqls, err := base.ReadSQLsFromSource(ctx, env, sourceValue, readOpts)
 		if err != nil {
 			return "", err
 		}
 		if len(sqls) != 1 {
-			return "", fmt.Errorf("expected one CREATE TABLE statement, found %d entities in %v", len(sqls), sourceValue)
+			return "", fmt.Errorf("expected one CREATE TABLE statement, found %d entities in %s", len(sqls), base.DescribeInputSource(sourceValue))
 		}
 		return sqls[0], nil
 	}
@@ -57,20 +72,24 @@ func DiffTables(env *schemadiff.Environment, inputSourceValue string, targetInpu
 	if err != nil {
 		return nil, err
 	}
-	return schemadiff.DiffCreateTablesQueries(env, sourceTable, targetTable, defaultDiffHints)
+	return schemadiff.DiffCreateTablesQueries(env, sourceTable, targetTable, hints)
 }
 
 // DiffViews returns a rich diff between two given views. The function expect the inputs to each
 // contain a single CREATE VIEW statement, and returns with error if not so. The two views are allowed to have different names.
+// hints may be nil, in which case default hints apply.
 // Inputs can be stdin, file, directory, or MySQL URI.
-func DiffViews(env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string) (schemadiff.EntityDiff, error) {
+func DiffViews(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string, hints *schemadiff.DiffHints, readOpts *base.ReadOptions) (schemadiff.EntityDiff, error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
 	readViewSQL := func(sourceValue string) (string, error) {
-		sqls, err := base.ReadSQLsFromSource(env, sourceValue)
+		sqls, err := base.ReadSQLsFromSource(ctx, env, sourceValue, readOpts)
 		if err != nil {
 			return "", err
 		}
 		if len(sqls) != 1 {
-			return "", fmt.Errorf("expected one CREATE VIEW statement, found %d entities in %v", len(sqls), sourceValue)
+			return "", fmt.Errorf("expected one CREATE VIEW statement, found %d entities in %s", len(sqls), base.DescribeInputSource(sourceValue))
 		}
 		return sqls[0], nil
 	}
@@ -82,5 +101,5 @@ func DiffViews(env *schemadiff.Environment, inputSourceValue string, targetInput
 	if err != nil {
 		return nil, err
 	}
-	return schemadiff.DiffCreateViewsQueries(env, sourceView, targetView, defaultDiffHints)
+	return schemadiff.DiffCreateViewsQueries(env, sourceView, targetView, hints)
 }
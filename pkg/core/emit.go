@@ -0,0 +1,311 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// Emit formats, which EmitDiffs supports
+const (
+	// EmitSQL writes all diffs as SQL statements, which is the default output of diff commands
+	EmitSQL = "sql"
+	// EmitGhost writes ALTER TABLE diffs as gh-ost command lines
+	EmitGhost = "gh-ost"
+	// EmitPTOSC writes ALTER TABLE diffs as pt-online-schema-change command lines
+	EmitPTOSC = "pt-osc"
+	// EmitVitess writes a shell script which applies the diffs with vtctldclient ApplySchema, annotating each
+	// diff with its ddl_strategy
+	EmitVitess = "vitess"
+
+	// DefaultVitessDDLStrategy is the ddl_strategy of diffs other than CREATE statements, with EmitVitess
+	DefaultVitessDDLStrategy = "vitess"
+	// vitessSQLFileDelimiter ends the here-document of each SQL file in an EmitVitess script
+	vitessSQLFileDelimiter = "SCHEMADIFF_SQL"
+)
+
+// defaultEmitTemplates are the command line templates of the online schema change tools, by emit format
+var defaultEmitTemplates = map[string]string{
+	EmitGhost:  `gh-ost --database={{quote .Database}} --table={{quote .Table}} --alter={{quote .Alter}} --execute`,
+	EmitPTOSC:  `pt-online-schema-change --alter={{quote .Alter}} {{quote (printf "D=%s,t=%s" .Database .Table)}} --execute`,
+	EmitVitess: `vtctldclient ApplySchema --ddl-strategy {{quote .DDLStrategy}} --sql-file {{quote .SQLFile}} {{quote .Keyspace}}`,
+}
+
+// EmitOptions configure EmitDiffs.
+type EmitOptions struct {
+	// Format is one of EmitSQL, EmitGhost, EmitPTOSC and EmitVitess
+	Format string
+	// Database is the name of the database the diffs apply to, as given to the online schema change tool. With
+	// EmitVitess, it is the keyspace of entities which Keyspaces does not map.
+	Database string
+	// Keyspaces maps entity names onto the keyspaces they belong to, with EmitVitess. See ParseEmitKeyspaces.
+	Keyspaces map[string]string
+	// Template, if non empty, is a text/template of the command line of the online schema change tool, which
+	// overrides the default template of Format. Its data is an OnlineSchemaChange, or a VitessApplySchema with
+	// EmitVitess, and its quote function quotes a value for the shell. Use it to add options, such as
+	// --max-load or --chunk-size.
+	Template string
+	// DDLStrategy is the ddl_strategy of diffs other than CREATE statements, with EmitVitess. Defaults to
+	// DefaultVitessDDLStrategy.
+	DDLStrategy string
+}
+
+// OnlineSchemaChange is an ALTER TABLE to run through an online schema change tool. It is the data of the
+// EmitOptions.Template.
+type OnlineSchemaChange struct {
+	Database string
+	Table    string
+	// Alter is the ALTER TABLE statement without its "ALTER TABLE <table>" prefix, e.g. "ADD COLUMN `c` int"
+	Alter string
+	// Statement is the complete ALTER TABLE statement
+	Statement string
+}
+
+// VitessApplySchema is a vtctldclient ApplySchema call, which applies statements with a single ddl_strategy. It
+// is the data of the EmitOptions.Template with EmitVitess.
+type VitessApplySchema struct {
+	Keyspace    string
+	DDLStrategy string
+	// SQLFile is the name of the file which holds the statements
+	SQLFile    string
+	Statements []string
+}
+
+// shellQuote quotes a value as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// onlineSchemaChange returns the online schema change for the given diff, or nil if the diff should run as plain
+// SQL: CREATE and DROP statements, and ALTER TABLE statements which MySQL can run as INSTANT DDL.
+func onlineSchemaChange(d schemadiff.EntityDiff, database string) (*OnlineSchemaChange, error) {
+	alterDiff, ok := d.(*schemadiff.AlterTableEntityDiff)
+	if !ok || d.InstantDDLCapability() == schemadiff.InstantDDLCapabilityPossible {
+		return nil, nil
+	}
+	statement := d.CanonicalStatementString()
+	prefix := "ALTER TABLE " + sqlparser.CanonicalString(alterDiff.AlterTable().Table) + " "
+	alter, ok := strings.CutPrefix(statement, prefix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected ALTER TABLE statement for %s: %s", d.EntityName(), statement)
+	}
+	return &OnlineSchemaChange{
+		Database:  database,
+		Table:     d.EntityName(),
+		Alter:     alter,
+		Statement: statement,
+	}, nil
+}
+
+// EmitDiffs writes the preamble statements and the diffs, one per line, in the given format. With EmitGhost and
+// EmitPTOSC, ALTER TABLE diffs which MySQL cannot run as INSTANT DDL are written as command lines of the online
+// schema change tool, and other diffs as SQL statements. With EmitVitess, EmitDiffs writes a shell script; see
+// emitVitess.
+func EmitDiffs(w io.Writer, preamble []string, diffs []schemadiff.EntityDiff, opts *EmitOptions) error {
+	text := opts.Template
+	if text == "" {
+		text = defaultEmitTemplates[opts.Format]
+	}
+	switch opts.Format {
+	case EmitSQL:
+	case EmitGhost, EmitPTOSC, EmitVitess:
+		if opts.Database == "" && (opts.Format != EmitVitess || len(opts.Keyspaces) == 0) {
+			return fmt.Errorf("--emit %s requires a database name: use --emit-database, or a MySQL DSN source", opts.Format)
+		}
+	default:
+		return fmt.Errorf("unknown emit format %q, expected %s, %s, %s or %s", opts.Format, EmitSQL, EmitGhost, EmitPTOSC, EmitVitess)
+	}
+	tmpl, err := template.New(opts.Format).Funcs(template.FuncMap{"quote": shellQuote}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("parsing --emit-template: %w", err)
+	}
+	if opts.Format == EmitVitess {
+		return emitVitess(w, preamble, diffs, opts, tmpl)
+	}
+
+	var b strings.Builder
+	for _, stmt := range preamble {
+		fmt.Fprintf(&b, "%s;\n", stmt)
+	}
+	for _, d := range diffs {
+		var change *OnlineSchemaChange
+		if opts.Format != EmitSQL {
+			if change, err = onlineSchemaChange(d, opts.Database); err != nil {
+				return err
+			}
+		}
+		if change == nil {
+			fmt.Fprintf(&b, "%s;\n", d.CanonicalStatementString())
+			continue
+		}
+		if err := tmpl.Execute(&b, change); err != nil {
+			return fmt.Errorf("executing --emit-template for %s: %w", change.Table, err)
+		}
+		b.WriteString("\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// ParseEmitKeyspaces parses a list of "entity=keyspace" values into EmitOptions.Keyspaces. It returns an error on
+// a malformed value, or when an entity is given more than one keyspace.
+func ParseEmitKeyspaces(values []string) (map[string]string, error) {
+	keyspaces := map[string]string{}
+	for _, value := range values {
+		entity, keyspace, ok := strings.Cut(value, "=")
+		entity = strings.TrimSpace(entity)
+		keyspace = strings.TrimSpace(keyspace)
+		if !ok || entity == "" || keyspace == "" {
+			return nil, fmt.Errorf("invalid keyspace %q, expected entity=keyspace", value)
+		}
+		if existing, ok := keyspaces[entity]; ok && existing != keyspace {
+			return nil, fmt.Errorf("entity %s is given more than one keyspace", entity)
+		}
+		keyspaces[entity] = keyspace
+	}
+	return keyspaces, nil
+}
+
+// vitessKeyspace returns the keyspace of a statement on the given entities: the keyspace opts.Keyspaces maps them
+// onto, or otherwise opts.Database. It returns an error if the entities are mapped onto different keyspaces, as
+// a statement applies to a single keyspace.
+func vitessKeyspace(opts *EmitOptions, entityNames ...string) (string, error) {
+	keyspace := ""
+	for _, name := range entityNames {
+		mapped, ok := opts.Keyspaces[name]
+		if !ok {
+			continue
+		}
+		if keyspace != "" && keyspace != mapped {
+			return "", fmt.Errorf("statement on %s spans keyspaces %s and %s", strings.Join(entityNames, ", "), keyspace, mapped)
+		}
+		keyspace = mapped
+	}
+	if keyspace == "" {
+		keyspace = opts.Database
+	}
+	if keyspace == "" {
+		return "", fmt.Errorf("no keyspace for %s: use --emit-keyspace or --emit-database", strings.Join(entityNames, ", "))
+	}
+	return keyspace, nil
+}
+
+// preambleEntityNames returns the names of the entities a preamble statement renames or redefines.
+func preambleEntityNames(parser *sqlparser.Parser, statement string) ([]string, error) {
+	stmt, err := parser.ParseStrictDDL(statement)
+	if err != nil {
+		return nil, err
+	}
+	switch stmt := stmt.(type) {
+	case *sqlparser.RenameTable:
+		var names []string
+		for _, pair := range stmt.TablePairs {
+			names = append(names, pair.FromTable.Name.String(), pair.ToTable.Name.String())
+		}
+		return names, nil
+	case *sqlparser.AlterTable:
+		return []string{stmt.Table.Name.String()}, nil
+	case *sqlparser.AlterView:
+		return []string{stmt.ViewName.Name.String()}, nil
+	}
+	return nil, fmt.Errorf("unexpected preamble statement: %s", statement)
+}
+
+// vitessDDLStrategy returns the ddl_strategy of the given diff: direct for CREATE statements, the given strategy
+// with --prefer-instant-ddl for ALTER TABLE statements which MySQL can run as INSTANT DDL, and the given
+// strategy for all other diffs.
+func vitessDDLStrategy(d schemadiff.EntityDiff, strategy string) string {
+	switch d.Statement().(type) {
+	case *sqlparser.CreateTable, *sqlparser.CreateView:
+		return "direct"
+	case *sqlparser.AlterTable:
+		if strategy != "direct" && d.InstantDDLCapability() == schemadiff.InstantDDLCapabilityPossible {
+			return strategy + " --prefer-instant-ddl"
+		}
+	}
+	return strategy
+}
+
+// emitVitess writes a shell script which applies the preamble statements and the diffs, grouped per keyspace: the
+// keyspace of each entity in opts.Keyspaces, or otherwise opts.Database. Keyspaces are applied in the order of
+// their first statement, and the statements of each keyspace in order. Consecutive statements of a keyspace with
+// the same ddl_strategy are written into the same SQL file, applied by a single vtctldclient ApplySchema call.
+// Preamble statements, which rename entities, use the direct strategy, since Online DDL does not support renames.
+func emitVitess(w io.Writer, preamble []string, diffs []schemadiff.EntityDiff, opts *EmitOptions, tmpl *template.Template) error {
+	strategy := opts.DDLStrategy
+	if strategy == "" {
+		strategy = DefaultVitessDDLStrategy
+	}
+	var keyspaces []string
+	keyspaceCalls := map[string][]*VitessApplySchema{}
+	add := func(keyspace string, ddlStrategy string, statement string) {
+		calls := keyspaceCalls[keyspace]
+		if len(calls) == 0 {
+			keyspaces = append(keyspaces, keyspace)
+		}
+		if len(calls) == 0 || calls[len(calls)-1].DDLStrategy != ddlStrategy {
+			calls = append(calls, &VitessApplySchema{
+				Keyspace:    keyspace,
+				DDLStrategy: ddlStrategy,
+				SQLFile:     fmt.Sprintf("%s-%03d.sql", keyspace, len(calls)+1),
+			})
+		}
+		call := calls[len(calls)-1]
+		call.Statements = append(call.Statements, statement)
+		keyspaceCalls[keyspace] = calls
+	}
+	var parser *sqlparser.Parser
+	for _, stmt := range preamble {
+		keyspace := opts.Database
+		if len(opts.Keyspaces) > 0 {
+			if parser == nil {
+				var err error
+				if parser, err = sqlparser.New(sqlparser.Options{MySQLServerVersion: DefaultMySQLVersion}); err != nil {
+					return err
+				}
+			}
+			names, err := preambleEntityNames(parser, stmt)
+			if err != nil {
+				return err
+			}
+			if keyspace, err = vitessKeyspace(opts, names...); err != nil {
+				return err
+			}
+		}
+		add(keyspace, "direct", stmt)
+	}
+	for _, d := range diffs {
+		keyspace, err := vitessKeyspace(opts, d.EntityName())
+		if err != nil {
+			return err
+		}
+		add(keyspace, vitessDDLStrategy(d, strategy), d.CanonicalStatementString())
+	}
+	var calls []*VitessApplySchema
+	for _, keyspace := range keyspaces {
+		calls = append(calls, keyspaceCalls[keyspace]...)
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\nset -e\n")
+	for _, call := range calls {
+		fmt.Fprintf(&b, "\ncat > %s <<'%s'\n", shellQuote(call.SQLFile), vitessSQLFileDelimiter)
+		for _, statement := range call.Statements {
+			if strings.Contains("\n"+statement+"\n", "\n"+vitessSQLFileDelimiter+"\n") {
+				return fmt.Errorf("statement contains a %s line: %s", vitessSQLFileDelimiter, statement)
+			}
+			fmt.Fprintf(&b, "-- ddl_strategy: %s\n%s;\n", call.DDLStrategy, statement)
+		}
+		fmt.Fprintf(&b, "%s\n", vitessSQLFileDelimiter)
+		if err := tmpl.Execute(&b, call); err != nil {
+			return fmt.Errorf("executing --emit-template for %s: %w", call.SQLFile, err)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,279 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+func TestShellQuote(t *testing.T) {
+	expectedQuoting := map[string]string{
+		"":                      "''",
+		"t1":                    "'t1'",
+		"ADD COLUMN `c` int":    "'ADD COLUMN `c` int'",
+		"COMMENT 'it''s' $HOME": `'COMMENT '\''it'\'''\''s'\'' $HOME'`,
+	}
+	for k, v := range expectedQuoting {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, v, shellQuote(k))
+		})
+	}
+}
+
+func TestEmitDiffs(t *testing.T) {
+	ctx := context.Background()
+	env := schemadiff.NewTestEnv()
+	from, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table t1 (id int primary key, name varchar(12))",
+		"create table t2 (id int primary key)",
+		"create table t4 (id int primary key)",
+	})
+	require.NoError(t, err)
+	to, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table t1 (id int primary key, name varchar(12), key name_idx (name))",
+		"create table t2 (id int primary key, c int)",
+		"create table t3 (id int primary key)",
+	})
+	require.NoError(t, err)
+	diff, err := from.SchemaDiff(to, DefaultDiffHints())
+	require.NoError(t, err)
+	diffs, err := diff.OrderedDiffs(ctx)
+	require.NoError(t, err)
+
+	sqlLines := []string{
+		"RENAME TABLE `t0` TO `t9`;",
+		"ALTER TABLE `t2` ADD COLUMN `c` int;",
+		"CREATE TABLE `t3` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n);",
+		"DROP TABLE `t4`;",
+	}
+	tcases := []struct {
+		name        string
+		opts        *EmitOptions
+		expectAlter string
+		expectError string
+	}{
+		{
+			name:        "sql",
+			opts:        &EmitOptions{Format: EmitSQL},
+			expectAlter: "ALTER TABLE `t1` ADD KEY `name_idx` (`name`);",
+		},
+		{
+			name:        "gh-ost",
+			opts:        &EmitOptions{Format: EmitGhost, Database: "test"},
+			expectAlter: "gh-ost --database='test' --table='t1' --alter='ADD KEY `name_idx` (`name`)' --execute",
+		},
+		{
+			name:        "pt-osc",
+			opts:        &EmitOptions{Format: EmitPTOSC, Database: "test"},
+			expectAlter: "pt-online-schema-change --alter='ADD KEY `name_idx` (`name`)' 'D=test,t=t1' --execute",
+		},
+		{
+			name: "template",
+			opts: &EmitOptions{
+				Format:   EmitGhost,
+				Database: "test",
+				Template: `gh-ost --max-load=Threads_running=25 --chunk-size=500 --database={{.Database}} --table={{.Table}} --alter={{quote .Alter}} # {{.Statement}}`,
+			},
+			expectAlter: "gh-ost --max-load=Threads_running=25 --chunk-size=500 --database=test --table=t1 --alter='ADD KEY `name_idx` (`name`)' # ALTER TABLE `t1` ADD KEY `name_idx` (`name`)",
+		},
+		{
+			name:        "no database",
+			opts:        &EmitOptions{Format: EmitPTOSC},
+			expectError: "--emit pt-osc requires a database name",
+		},
+		{
+			name:        "invalid template",
+			opts:        &EmitOptions{Format: EmitGhost, Database: "test", Template: "gh-ost {{.Table"},
+			expectError: "parsing --emit-template",
+		},
+		{
+			name:        "unknown field",
+			opts:        &EmitOptions{Format: EmitGhost, Database: "test", Template: "gh-ost {{.Host}}"},
+			expectError: "executing --emit-template for t1",
+		},
+		{
+			name:        "unknown format",
+			opts:        &EmitOptions{Format: "osc"},
+			expectError: `unknown emit format "osc"`,
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			var b strings.Builder
+			err := EmitDiffs(&b, []string{"RENAME TABLE `t0` TO `t9`"}, diffs, tcase.opts)
+			if tcase.expectError != "" {
+				assert.ErrorContains(t, err, tcase.expectError)
+				return
+			}
+			require.NoError(t, err)
+			output := b.String()
+			assert.True(t, strings.HasPrefix(output, sqlLines[0]+"\n"), output)
+			for _, line := range append(sqlLines, tcase.expectAlter) {
+				assert.Contains(t, output, line+"\n")
+			}
+		})
+	}
+}
+
+func TestExecEmit(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(12))"})
+	defer os.RemoveAll(fileFrom)
+	fileTo := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(12), key name_idx (name))"})
+	defer os.RemoveAll(fileTo)
+
+	output, err := Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{Emit: EmitGhost, EmitDatabase: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, "gh-ost --database='test' --table='t1' --alter='ADD KEY `name_idx` (`name`)' --execute\n", output)
+
+	_, err = Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{Emit: EmitGhost})
+	assert.ErrorContains(t, err, "use --emit-database, or a MySQL DSN source")
+	_, err = Exec(ctx, "load", fileFrom, "", &ExecOptions{Emit: EmitGhost})
+	assert.ErrorContains(t, err, "--emit applies to diff and ordered-diff, not to load")
+	_, err = Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{EmitTemplate: "gh-ost"})
+	assert.ErrorContains(t, err, "--emit-template applies to --emit gh-ost, pt-osc and vitess")
+}
+
+func TestEmitVitess(t *testing.T) {
+	ctx := context.Background()
+	env := schemadiff.NewTestEnv()
+	diffSchemas := func(t *testing.T, fromQueries []string, toQueries []string) []schemadiff.EntityDiff {
+		from, err := schemadiff.NewSchemaFromQueries(env, fromQueries)
+		require.NoError(t, err)
+		to, err := schemadiff.NewSchemaFromQueries(env, toQueries)
+		require.NoError(t, err)
+		diff, err := from.SchemaDiff(to, DefaultDiffHints())
+		require.NoError(t, err)
+		diffs, err := diff.OrderedDiffs(ctx)
+		require.NoError(t, err)
+		return diffs
+	}
+
+	t.Run("strategies", func(t *testing.T) {
+		diffs := diffSchemas(t,
+			[]string{
+				"create table t1 (id int primary key, name varchar(12))",
+				"create table t2 (id int primary key)",
+				"create table t4 (id int primary key)",
+				"create view v1 as select id from t2",
+			},
+			[]string{
+				"create table t1 (id int primary key, name varchar(12), key name_idx (name))",
+				"create table t2 (id int primary key, c int)",
+				"create table t3 (id int primary key)",
+				"create view v1 as select id, c from t2",
+				"create view v2 as select id from t3",
+			},
+		)
+		expectStrategies := map[string]string{
+			"t1": "online --postpone-completion",
+			"t2": "online --postpone-completion --prefer-instant-ddl",
+			"t3": "direct",
+			"t4": "online --postpone-completion",
+			"v1": "online --postpone-completion",
+			"v2": "direct",
+		}
+		require.Len(t, diffs, len(expectStrategies))
+		for _, d := range diffs {
+			assert.Equal(t, expectStrategies[d.EntityName()], vitessDDLStrategy(d, "online --postpone-completion"), d.EntityName())
+		}
+		assert.Equal(t, "direct", vitessDDLStrategy(diffs[0], "direct"))
+	})
+	t.Run("script", func(t *testing.T) {
+		diffs := diffSchemas(t,
+			[]string{"create table t1 (id int primary key, name varchar(12))"},
+			[]string{"create table t1 (id int primary key, name varchar(12), key name_idx (name))"},
+		)
+		var b strings.Builder
+		err := EmitDiffs(&b, []string{"RENAME TABLE `t0` TO `t9`"}, diffs, &EmitOptions{Format: EmitVitess, Database: "commerce"})
+		require.NoError(t, err)
+		expect := "#!/bin/sh\nset -e\n" +
+			"\ncat > 'commerce-001.sql' <<'SCHEMADIFF_SQL'\n" +
+			"-- ddl_strategy: direct\nRENAME TABLE `t0` TO `t9`;\n" +
+			"SCHEMADIFF_SQL\n" +
+			"vtctldclient ApplySchema --ddl-strategy 'direct' --sql-file 'commerce-001.sql' 'commerce'\n" +
+			"\ncat > 'commerce-002.sql' <<'SCHEMADIFF_SQL'\n" +
+			"-- ddl_strategy: vitess\nALTER TABLE `t1` ADD KEY `name_idx` (`name`);\n" +
+			"SCHEMADIFF_SQL\n" +
+			"vtctldclient ApplySchema --ddl-strategy 'vitess' --sql-file 'commerce-002.sql' 'commerce'\n"
+		assert.Equal(t, expect, b.String())
+
+		b.Reset()
+		err = EmitDiffs(&b, nil, diffs, &EmitOptions{
+			Format:   EmitVitess,
+			Database: "commerce",
+			Template: "vtctldclient --server=vtctld:15999 ApplySchema --ddl-strategy {{quote .DDLStrategy}} --sql-file {{.SQLFile}} {{.Keyspace}} # {{len .Statements}} statements",
+		})
+		require.NoError(t, err)
+		assert.Contains(t, b.String(), "vtctldclient --server=vtctld:15999 ApplySchema --ddl-strategy 'vitess' --sql-file commerce-001.sql commerce # 1 statements\n")
+	})
+	t.Run("keyspaces", func(t *testing.T) {
+		diffs := diffSchemas(t,
+			[]string{"create table customer (id int primary key)", "create table product (id int primary key)"},
+			[]string{"create table customer (id int primary key, name varchar(12))", "create table product (id int primary key, sku varchar(12))", "create table corder (id int primary key)"},
+		)
+		var b strings.Builder
+		err := EmitDiffs(&b, []string{"RENAME TABLE `customer_old` TO `customer_v1`"}, diffs, &EmitOptions{
+			Format:    EmitVitess,
+			Database:  "commerce",
+			Keyspaces: map[string]string{"customer": "customer", "customer_v1": "customer", "corder": "customer"},
+		})
+		require.NoError(t, err)
+		output := b.String()
+		assert.Contains(t, output, "vtctldclient ApplySchema --ddl-strategy 'direct' --sql-file 'customer-001.sql' 'customer'\n")
+		assert.Contains(t, output, "vtctldclient ApplySchema --ddl-strategy 'vitess --prefer-instant-ddl' --sql-file 'commerce-001.sql' 'commerce'\n")
+		assert.Contains(t, output, "-- ddl_strategy: direct\nCREATE TABLE `corder`")
+		assert.NotContains(t, output, "commerce-002.sql")
+		product := strings.Index(output, "ALTER TABLE `product`")
+		require.True(t, product >= 0, output)
+		assert.Greater(t, product, strings.Index(output, "'commerce-001.sql' <<"), output)
+
+		err = EmitDiffs(&b, []string{"RENAME TABLE `customer_old` TO `product_v1`"}, nil, &EmitOptions{
+			Format:    EmitVitess,
+			Keyspaces: map[string]string{"customer_old": "customer", "product_v1": "commerce"},
+		})
+		assert.ErrorContains(t, err, "spans keyspaces customer and commerce")
+		err = EmitDiffs(&b, nil, diffs, &EmitOptions{Format: EmitVitess, Keyspaces: map[string]string{"customer": "customer"}})
+		assert.ErrorContains(t, err, "no keyspace for")
+	})
+	t.Run("exec", func(t *testing.T) {
+		fileFrom := writeSchemaFile(t, []string{"create table t1 (id int primary key)"})
+		defer os.RemoveAll(fileFrom)
+		fileTo := writeSchemaFile(t, []string{"create table t1 (id int primary key)", "create table t2 (id int primary key)"})
+		defer os.RemoveAll(fileTo)
+
+		output, err := Exec(ctx, "ordered-diff", fileFrom, fileTo, &ExecOptions{Emit: EmitVitess, EmitDatabase: "commerce"})
+		require.NoError(t, err)
+		assert.Contains(t, output, "-- ddl_strategy: direct\nCREATE TABLE `t2`")
+		_, err = Exec(ctx, "ordered-diff", fileFrom, fileTo, &ExecOptions{Emit: EmitGhost, EmitDatabase: "commerce", EmitDDLStrategy: "online"})
+		assert.ErrorContains(t, err, "--emit-ddl-strategy applies to --emit vitess")
+	})
+	t.Run("diff is ordered", func(t *testing.T) {
+		fileFrom := writeSchemaFile(t, []string{"create table t1 (id int primary key)", "create view v1 as select id from t1"})
+		defer os.RemoveAll(fileFrom)
+		fileTo := writeSchemaFile(t, nil)
+		defer os.RemoveAll(fileTo)
+
+		output, err := Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{Emit: EmitVitess, EmitDatabase: "commerce"})
+		require.NoError(t, err)
+		dropView, dropTable := strings.Index(output, "DROP VIEW `v1`"), strings.Index(output, "DROP TABLE `t1`")
+		require.True(t, dropView >= 0 && dropTable >= 0, output)
+		assert.Less(t, dropView, dropTable, output)
+	})
+}
+
+func TestParseEmitKeyspaces(t *testing.T) {
+	keyspaces, err := ParseEmitKeyspaces([]string{"customer=customer", " corder = customer ", "product=commerce", "product=commerce"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"customer": "customer", "corder": "customer", "product": "commerce"}, keyspaces)
+
+	_, err = ParseEmitKeyspaces([]string{"customer"})
+	assert.EqualError(t, err, `invalid keyspace "customer", expected entity=keyspace`)
+	_, err = ParseEmitKeyspaces([]string{"customer=customer", "customer=commerce"})
+	assert.EqualError(t, err, "entity customer is given more than one keyspace")
+}
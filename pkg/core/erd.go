@@ -0,0 +1,585 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// ERD formats, which ERD.Write supports
+const (
+	// ERDDot is a Graphviz DOT digraph
+	ERDDot = "dot"
+	// ERDMermaid is a Mermaid erDiagram, which GitHub renders in markdown
+	ERDMermaid = "mermaid"
+	// ERDPlantUML is a PlantUML entity diagram
+	ERDPlantUML = "plantuml"
+)
+
+// ERD column modes, which select the columns ERD.Write lists in each table
+const (
+	ERDColumnsAll = "all"
+	// ERDColumnsKeys lists the columns of the primary key, of unique keys and of foreign keys
+	ERDColumnsKeys = "keys"
+	ERDColumnsNone = "none"
+)
+
+// ERDStatus is the change of an entity, a column or a relation, in an ERD of a diff.
+type ERDStatus string
+
+const (
+	ERDUnchanged ERDStatus = ""
+	ERDAdded     ERDStatus = "added"
+	ERDRemoved   ERDStatus = "removed"
+	ERDChanged   ERDStatus = "changed"
+)
+
+// erdStatusColors are the fill and text colors of changed entities, columns and relations
+var erdStatusColors = map[ERDStatus]struct{ fill, text string }{
+	ERDAdded:   {fill: "#dafbe1", text: "#1a7f37"},
+	ERDRemoved: {fill: "#ffebe9", text: "#cf222e"},
+	ERDChanged: {fill: "#fff8c5", text: "#9a6700"},
+}
+
+// ERDColumn is a column of a table, in an ERD.
+type ERDColumn struct {
+	Name string
+	// Type is the column's data type, without its length or options, e.g. "varchar"
+	Type       string
+	PrimaryKey bool
+	UniqueKey  bool
+	ForeignKey bool
+	Status     ERDStatus
+}
+
+// isKey returns whether the column is part of a primary, unique or foreign key
+func (c *ERDColumn) isKey() bool {
+	return c.PrimaryKey || c.UniqueKey || c.ForeignKey
+}
+
+// keys returns the key markers of the column, e.g. ["PK", "FK"]
+func (c *ERDColumn) keys() (keys []string) {
+	if c.PrimaryKey {
+		keys = append(keys, "PK")
+	}
+	if c.UniqueKey {
+		keys = append(keys, "UK")
+	}
+	if c.ForeignKey {
+		keys = append(keys, "FK")
+	}
+	return keys
+}
+
+// ERDEntity is a table or a view, in an ERD.
+type ERDEntity struct {
+	Name string
+	View bool
+	// Columns are the columns of a table. Views have none.
+	Columns []*ERDColumn
+	Status  ERDStatus
+}
+
+// ERDRelation is a foreign key from a child table to its parent table, or the dependency of a view on an entity
+// it selects from.
+type ERDRelation struct {
+	// From is the child table, or the view
+	From string
+	// To is the parent table, or the entity the view selects from
+	To string
+	// View is true for view dependencies
+	View bool
+	// Name is the name of the foreign key constraint
+	Name              string
+	Columns           []string
+	ReferencedColumns []string
+	Status            ERDStatus
+}
+
+// key identifies the relation when comparing relations of two schemas. Foreign keys are compared by their
+// columns rather than by name, since constraint names are commonly generated.
+func (r *ERDRelation) key() string {
+	return fmt.Sprintf("%s\x00%s\x00%t\x00%s\x00%s", r.From, r.To, r.View, strings.Join(r.Columns, ","), strings.Join(r.ReferencedColumns, ","))
+}
+
+// ERD is an entity-relationship diagram of a schema, or of the diff between two schemas.
+type ERD struct {
+	Entities  []*ERDEntity
+	Relations []*ERDRelation
+}
+
+// ERDOptions configure ERD.Write.
+type ERDOptions struct {
+	// Format is one of ERDDot, ERDMermaid and ERDPlantUML. Defaults to ERDMermaid.
+	Format string
+	// Columns is one of ERDColumnsAll, ERDColumnsKeys and ERDColumnsNone. Defaults to ERDColumnsAll.
+	Columns string
+}
+
+func identifierNames(identifiers []sqlparser.IdentifierCI) (names []string) {
+	for _, identifier := range identifiers {
+		names = append(names, identifier.String())
+	}
+	return names
+}
+
+// tableERD returns the ERD entity of a table, and its foreign keys.
+func tableERD(table *schemadiff.CreateTableEntity) (*ERDEntity, []*ERDRelation) {
+	entity := &ERDEntity{Name: table.Name()}
+	var relations []*ERDRelation
+	primaryKey, uniqueKey, foreignKey := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, key := range table.TableSpec.Indexes {
+		for _, keyColumn := range key.Columns {
+			switch key.Info.Type {
+			case sqlparser.IndexTypePrimary:
+				primaryKey[keyColumn.Column.Lowered()] = true
+			case sqlparser.IndexTypeUnique:
+				uniqueKey[keyColumn.Column.Lowered()] = true
+			}
+		}
+	}
+	for _, constraint := range table.TableSpec.Constraints {
+		fk, ok := constraint.Details.(*sqlparser.ForeignKeyDefinition)
+		if !ok {
+			continue
+		}
+		for _, column := range fk.Source {
+			foreignKey[column.Lowered()] = true
+		}
+		relations = append(relations, &ERDRelation{
+			From:              table.Name(),
+			To:                fk.ReferenceDefinition.ReferencedTable.Name.String(),
+			Name:              constraint.Name.String(),
+			Columns:           identifierNames(fk.Source),
+			ReferencedColumns: identifierNames(fk.ReferenceDefinition.ReferencedColumns),
+		})
+	}
+	for _, col := range table.TableSpec.Columns {
+		name := col.Name.Lowered()
+		entity.Columns = append(entity.Columns, &ERDColumn{
+			Name:       col.Name.String(),
+			Type:       col.Type.Type,
+			PrimaryKey: primaryKey[name],
+			UniqueKey:  uniqueKey[name],
+			ForeignKey: foreignKey[name],
+		})
+	}
+	return entity, relations
+}
+
+// viewERD returns the ERD entity of a view, and its dependencies on the tables and views it selects from. Common
+// table expressions are no dependencies, but the tables they select from are.
+func viewERD(view *schemadiff.CreateViewEntity) (*ERDEntity, []*ERDRelation) {
+	entity := &ERDEntity{Name: view.Name(), View: true}
+	var relations []*ERDRelation
+	dependencies := map[string]bool{}
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+		if cte, ok := node.(*sqlparser.CommonTableExpr); ok {
+			dependencies[cte.ID.String()] = true
+		}
+		return true, nil
+	}, view.Select)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+		if node, ok := node.(*sqlparser.AliasedTableExpr); ok {
+			// Derived tables are walked into, down to the tables they select from
+			if tableName, ok := node.Expr.(sqlparser.TableName); ok && !dependencies[tableName.Name.String()] {
+				dependencies[tableName.Name.String()] = true
+				relations = append(relations, &ERDRelation{From: view.Name(), To: tableName.Name.String(), View: true})
+			}
+		}
+		return true, nil
+	}, view.Select)
+	return entity, relations
+}
+
+// SchemaERD returns the entity-relationship diagram of the given schema. Views selecting from tables which are not
+// entities of the schema, such as DUAL, have no relations to them.
+func SchemaERD(schema *schemadiff.Schema) *ERD {
+	erd := &ERD{}
+	for _, table := range schema.Tables() {
+		entity, relations := tableERD(table)
+		erd.Entities = append(erd.Entities, entity)
+		erd.Relations = append(erd.Relations, relations...)
+	}
+	for _, view := range schema.Views() {
+		entity, relations := viewERD(view)
+		erd.Entities = append(erd.Entities, entity)
+		for _, relation := range relations {
+			if schema.Entity(relation.To) != nil {
+				erd.Relations = append(erd.Relations, relation)
+			}
+		}
+	}
+	return erd
+}
+
+// DiffERD returns the entity-relationship diagram of the diff from the source schema to the target schema: the
+// entities and relations of the target schema, followed by those of the source schema which the diff removes.
+// Each entity, relation and column of a changed table has the status of its change.
+func DiffERD(source *schemadiff.Schema, target *schemadiff.Schema, hints *schemadiff.DiffHints) (*ERD, error) {
+	sourceERD, targetERD := SchemaERD(source), SchemaERD(target)
+	sourceEntities := map[string]*ERDEntity{}
+	for _, entity := range sourceERD.Entities {
+		sourceEntities[entity.Name] = entity
+	}
+	erd := &ERD{}
+	for _, entity := range targetERD.Entities {
+		sourceEntity, ok := sourceEntities[entity.Name]
+		delete(sourceEntities, entity.Name)
+		switch {
+		case !ok:
+			entity.Status = ERDAdded
+		case sourceEntity.View != entity.View:
+			entity.Status = ERDChanged
+		default:
+			d, err := source.Entity(entity.Name).Diff(target.Entity(entity.Name), hints)
+			if err != nil {
+				return nil, err
+			}
+			if !d.IsEmpty() {
+				entity.Status = ERDChanged
+				entity.Columns = diffERDColumns(sourceEntity.Columns, entity.Columns)
+			}
+		}
+		erd.Entities = append(erd.Entities, entity)
+	}
+	for _, entity := range sourceERD.Entities {
+		if _, ok := sourceEntities[entity.Name]; ok {
+			entity.Status = ERDRemoved
+			erd.Entities = append(erd.Entities, entity)
+		}
+	}
+
+	sourceRelations := map[string]*ERDRelation{}
+	for _, relation := range sourceERD.Relations {
+		sourceRelations[relation.key()] = relation
+	}
+	for _, relation := range targetERD.Relations {
+		if _, ok := sourceRelations[relation.key()]; ok {
+			delete(sourceRelations, relation.key())
+		} else {
+			relation.Status = ERDAdded
+		}
+		erd.Relations = append(erd.Relations, relation)
+	}
+	for _, relation := range sourceERD.Relations {
+		if _, ok := sourceRelations[relation.key()]; ok {
+			relation.Status = ERDRemoved
+			erd.Relations = append(erd.Relations, relation)
+		}
+	}
+	return erd, nil
+}
+
+// diffERDColumns returns the columns of a changed table: its target columns, marked as added or changed, followed
+// by its removed source columns.
+func diffERDColumns(sourceColumns []*ERDColumn, targetColumns []*ERDColumn) (columns []*ERDColumn) {
+	sourceByName := map[string]*ERDColumn{}
+	for _, col := range sourceColumns {
+		sourceByName[strings.ToLower(col.Name)] = col
+	}
+	for _, col := range targetColumns {
+		name := strings.ToLower(col.Name)
+		sourceCol, ok := sourceByName[name]
+		delete(sourceByName, name)
+		switch {
+		case !ok:
+			col.Status = ERDAdded
+		case sourceCol.Type != col.Type || strings.Join(sourceCol.keys(), ",") != strings.Join(col.keys(), ","):
+			col.Status = ERDChanged
+		}
+		columns = append(columns, col)
+	}
+	for _, col := range sourceColumns {
+		if _, ok := sourceByName[strings.ToLower(col.Name)]; ok {
+			col.Status = ERDRemoved
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// filter removes the entities for which included returns false, and their relations
+func (e *ERD) filter(included func(entityName string) bool) {
+	names := map[string]bool{}
+	var entities []*ERDEntity
+	for _, entity := range e.Entities {
+		if included(entity.Name) {
+			entities = append(entities, entity)
+			names[entity.Name] = true
+		}
+	}
+	var relations []*ERDRelation
+	for _, relation := range e.Relations {
+		if names[relation.From] && names[relation.To] {
+			relations = append(relations, relation)
+		}
+	}
+	e.Entities, e.Relations = entities, relations
+}
+
+// Write writes the diagram in the given format.
+func (e *ERD) Write(w io.Writer, opts *ERDOptions) error {
+	if opts == nil {
+		opts = &ERDOptions{}
+	}
+	format, columnsMode := opts.Format, opts.Columns
+	if format == "" {
+		format = ERDMermaid
+	}
+	var showColumn func(c *ERDColumn) bool
+	switch columnsMode {
+	case "", ERDColumnsAll:
+		showColumn = func(c *ERDColumn) bool { return true }
+	case ERDColumnsKeys:
+		showColumn = (*ERDColumn).isKey
+	case ERDColumnsNone:
+		showColumn = func(c *ERDColumn) bool { return false }
+	default:
+		return fmt.Errorf("unknown ERD columns mode %q, expected %s, %s or %s", columnsMode, ERDColumnsAll, ERDColumnsKeys, ERDColumnsNone)
+	}
+	var b strings.Builder
+	switch format {
+	case ERDDot:
+		e.writeDot(&b, showColumn)
+	case ERDMermaid:
+		e.writeMermaid(&b, showColumn)
+	case ERDPlantUML:
+		e.writePlantUML(&b, showColumn)
+	default:
+		return fmt.Errorf("unknown ERD format %q, expected %s, %s or %s", format, ERDDot, ERDMermaid, ERDPlantUML)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes a Graphviz DOT ID
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+func (e *ERD) writeDot(b *strings.Builder, showColumn func(c *ERDColumn) bool) {
+	b.WriteString("digraph schema {\n\trankdir=LR;\n\tnode [shape=plaintext, fontname=\"Helvetica\"];\n\tedge [fontname=\"Helvetica\", fontsize=10];\n")
+	for _, entity := range e.Entities {
+		colors, changed := erdStatusColors[entity.Status]
+		if entity.View {
+			attributes := `shape=box, style="rounded,dashed"`
+			if changed {
+				attributes = fmt.Sprintf(`shape=box, style="rounded,dashed,filled", fillcolor="%s", color="%s"`, colors.fill, colors.text)
+			}
+			fmt.Fprintf(b, "\t%s [%s, label=%s];\n", dotQuote(entity.Name), attributes, dotQuote(entity.Name))
+			continue
+		}
+		header := "#f6f8fa"
+		if changed {
+			header = colors.fill
+		}
+		fmt.Fprintf(b, "\t%s [label=<<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\" CELLPADDING=\"4\"><TR><TD BGCOLOR=\"%s\"><B>%s</B></TD></TR>",
+			dotQuote(entity.Name), header, html.EscapeString(entity.Name))
+		for _, col := range entity.Columns {
+			if !showColumn(col) {
+				continue
+			}
+			text := html.EscapeString(strings.Join(append([]string{col.Name, col.Type}, col.keys()...), " "))
+			if col.Status == ERDRemoved {
+				text = "<S>" + text + "</S>"
+			}
+			if colors, ok := erdStatusColors[col.Status]; ok {
+				text = fmt.Sprintf(`<FONT COLOR="%s">%s</FONT>`, colors.text, text)
+			}
+			fmt.Fprintf(b, `<TR><TD ALIGN="LEFT">%s</TD></TR>`, text)
+		}
+		b.WriteString("</TABLE>>];\n")
+	}
+	for _, relation := range e.Relations {
+		var attributes []string
+		if relation.View {
+			attributes = append(attributes, "style=dashed")
+		} else if relation.Name != "" {
+			attributes = append(attributes, "label="+dotQuote(relation.Name))
+		}
+		if colors, ok := erdStatusColors[relation.Status]; ok {
+			attributes = append(attributes, fmt.Sprintf(`color="%s", fontcolor="%s"`, colors.text, colors.text))
+		}
+		fmt.Fprintf(b, "\t%s -> %s", dotQuote(relation.From), dotQuote(relation.To))
+		if len(attributes) > 0 {
+			fmt.Fprintf(b, " [%s]", strings.Join(attributes, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+}
+
+var (
+	// erdIdentifierRegexp matches names which Mermaid and PlantUML accept as entity identifiers
+	erdIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// erdNonWordRegexp matches characters which Mermaid does not accept in attribute types and names
+	erdNonWordRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// erdIdentifiers returns the identifiers of the entities in Mermaid and PlantUML output: an entity's name if it is
+// a valid identifier, or a generated identifier otherwise, which is not the name of another entity.
+func (e *ERD) erdIdentifiers() map[string]string {
+	identifiers := map[string]string{}
+	taken := map[string]bool{}
+	for _, entity := range e.Entities {
+		if erdIdentifierRegexp.MatchString(entity.Name) {
+			identifiers[entity.Name] = entity.Name
+			taken[entity.Name] = true
+		}
+	}
+	for i, entity := range e.Entities {
+		if _, ok := identifiers[entity.Name]; ok {
+			continue
+		}
+		identifier := fmt.Sprintf("entity_%d", i+1)
+		for n := 1; taken[identifier]; n++ {
+			identifier = fmt.Sprintf("entity_%d_%d", i+1, n)
+		}
+		identifiers[entity.Name] = identifier
+		taken[identifier] = true
+	}
+	return identifiers
+}
+
+// erdLabel returns the displayed name of an entity, which notes views and the status of the entity
+func erdLabel(entity *ERDEntity) string {
+	var notes []string
+	if entity.View {
+		notes = append(notes, "view")
+	}
+	if entity.Status != ERDUnchanged {
+		notes = append(notes, string(entity.Status))
+	}
+	if len(notes) == 0 {
+		return entity.Name
+	}
+	return fmt.Sprintf("%s (%s)", entity.Name, strings.Join(notes, ", "))
+}
+
+// erdQuote quotes a Mermaid or PlantUML string, which cannot contain double quotes
+func erdQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+func (e *ERD) writeMermaid(b *strings.Builder, showColumn func(c *ERDColumn) bool) {
+	identifiers := e.erdIdentifiers()
+	b.WriteString("erDiagram\n")
+	for _, entity := range e.Entities {
+		id := identifiers[entity.Name]
+		fmt.Fprintf(b, "    %s", id)
+		if label := erdLabel(entity); label != id {
+			fmt.Fprintf(b, "[%s]", erdQuote(label))
+		}
+		var columns []*ERDColumn
+		for _, col := range entity.Columns {
+			if showColumn(col) {
+				columns = append(columns, col)
+			}
+		}
+		if len(columns) == 0 {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(" {\n")
+		for _, col := range columns {
+			fmt.Fprintf(b, "        %s %s", erdNonWordRegexp.ReplaceAllString(col.Type, "_"), erdNonWordRegexp.ReplaceAllString(col.Name, "_"))
+			if keys := col.keys(); len(keys) > 0 {
+				fmt.Fprintf(b, " %s", strings.Join(keys, ","))
+			}
+			if col.Status != ERDUnchanged {
+				fmt.Fprintf(b, " %s", erdQuote(string(col.Status)))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, relation := range e.Relations {
+		label := relation.Name
+		if relation.View {
+			label = "selects from"
+		}
+		if relation.Status != ERDUnchanged {
+			label = strings.TrimSpace(fmt.Sprintf("%s (%s)", label, relation.Status))
+		}
+		if relation.View {
+			fmt.Fprintf(b, "    %s }o..o{ %s : %s\n", identifiers[relation.From], identifiers[relation.To], erdQuote(label))
+		} else {
+			fmt.Fprintf(b, "    %s ||--o{ %s : %s\n", identifiers[relation.To], identifiers[relation.From], erdQuote(label))
+		}
+	}
+}
+
+func (e *ERD) writePlantUML(b *strings.Builder, showColumn func(c *ERDColumn) bool) {
+	identifiers := e.erdIdentifiers()
+	b.WriteString("@startuml\nhide circle\nskinparam linetype ortho\n")
+	for _, entity := range e.Entities {
+		fmt.Fprintf(b, "\nentity %s as %s", erdQuote(entity.Name), identifiers[entity.Name])
+		if entity.View {
+			b.WriteString(" <<view>>")
+		}
+		if colors, ok := erdStatusColors[entity.Status]; ok {
+			fmt.Fprintf(b, " %s", colors.fill)
+		}
+		var keyColumns, otherColumns []string
+		for _, col := range entity.Columns {
+			if !showColumn(col) {
+				continue
+			}
+			text := fmt.Sprintf("%s : %s", col.Name, col.Type)
+			for _, key := range col.keys() {
+				text += fmt.Sprintf(" <<%s>>", key)
+			}
+			if col.Status == ERDRemoved {
+				text = "<s>" + text + "</s>"
+			}
+			if colors, ok := erdStatusColors[col.Status]; ok {
+				text = fmt.Sprintf("<color:%s>%s</color>", colors.text, text)
+			}
+			if col.PrimaryKey {
+				keyColumns = append(keyColumns, "  * "+text)
+			} else {
+				otherColumns = append(otherColumns, "  "+text)
+			}
+		}
+		if len(keyColumns) == 0 && len(otherColumns) == 0 {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(" {\n")
+		for _, line := range keyColumns {
+			fmt.Fprintf(b, "%s\n", line)
+		}
+		if len(keyColumns) > 0 && len(otherColumns) > 0 {
+			b.WriteString("  --\n")
+		}
+		for _, line := range otherColumns {
+			fmt.Fprintf(b, "%s\n", line)
+		}
+		b.WriteString("}\n")
+	}
+	if len(e.Relations) > 0 {
+		b.WriteString("\n")
+	}
+	for _, relation := range e.Relations {
+		color := ""
+		if colors, ok := erdStatusColors[relation.Status]; ok {
+			color = "[" + colors.text + "]"
+		}
+		if relation.View {
+			fmt.Fprintf(b, "%s .%s.> %s\n", identifiers[relation.From], color, identifiers[relation.To])
+			continue
+		}
+		fmt.Fprintf(b, "%s ||-%s-o{ %s", identifiers[relation.To], color, identifiers[relation.From])
+		if relation.Name != "" {
+			fmt.Fprintf(b, " : %s", relation.Name)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("@enduml\n")
+}
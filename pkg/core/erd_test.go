@@ -0,0 +1,288 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+// testERD is the diagram of the diff of a schema with a users table, an orders table referencing it, and a view
+func testERD() *ERD {
+	return &ERD{
+		Entities: []*ERDEntity{
+			{
+				Name: "users",
+				Columns: []*ERDColumn{
+					{Name: "id", Type: "int", PrimaryKey: true},
+					{Name: "email", Type: "varchar", UniqueKey: true},
+					{Name: "name", Type: "varchar"},
+				},
+			},
+			{
+				Name:   "orders",
+				Status: ERDChanged,
+				Columns: []*ERDColumn{
+					{Name: "id", Type: "int", PrimaryKey: true},
+					{Name: "user_id", Type: "int", ForeignKey: true, Status: ERDAdded},
+					{Name: "total", Type: "decimal", Status: ERDRemoved},
+				},
+			},
+			{Name: "order totals", View: true, Status: ERDAdded},
+		},
+		Relations: []*ERDRelation{
+			{From: "orders", To: "users", Name: "orders_user_fk", Columns: []string{"user_id"}, ReferencedColumns: []string{"id"}, Status: ERDAdded},
+			{From: "order totals", To: "orders", View: true, Status: ERDAdded},
+		},
+	}
+}
+
+func TestERDWrite(t *testing.T) {
+	tcases := []struct {
+		name        string
+		opts        *ERDOptions
+		expect      string
+		expectError string
+	}{
+		{
+			name: "mermaid",
+			opts: nil,
+			expect: `erDiagram
+    users {
+        int id PK
+        varchar email UK
+        varchar name
+    }
+    orders["orders (changed)"] {
+        int id PK
+        int user_id FK "added"
+        decimal total "removed"
+    }
+    entity_3["order totals (view, added)"]
+    users ||--o{ orders : "orders_user_fk (added)"
+    entity_3 }o..o{ orders : "selects from (added)"
+`,
+		},
+		{
+			name: "mermaid keys",
+			opts: &ERDOptions{Format: ERDMermaid, Columns: ERDColumnsKeys},
+			expect: `erDiagram
+    users {
+        int id PK
+        varchar email UK
+    }
+    orders["orders (changed)"] {
+        int id PK
+        int user_id FK "added"
+    }
+    entity_3["order totals (view, added)"]
+    users ||--o{ orders : "orders_user_fk (added)"
+    entity_3 }o..o{ orders : "selects from (added)"
+`,
+		},
+		{
+			name: "dot",
+			opts: &ERDOptions{Format: ERDDot, Columns: ERDColumnsNone},
+			expect: `digraph schema {
+	rankdir=LR;
+	node [shape=plaintext, fontname="Helvetica"];
+	edge [fontname="Helvetica", fontsize=10];
+	"users" [label=<<TABLE BORDER="0" CELLBORDER="1" CELLSPACING="0" CELLPADDING="4"><TR><TD BGCOLOR="#f6f8fa"><B>users</B></TD></TR></TABLE>>];
+	"orders" [label=<<TABLE BORDER="0" CELLBORDER="1" CELLSPACING="0" CELLPADDING="4"><TR><TD BGCOLOR="#fff8c5"><B>orders</B></TD></TR></TABLE>>];
+	"order totals" [shape=box, style="rounded,dashed,filled", fillcolor="#dafbe1", color="#1a7f37", label="order totals"];
+	"orders" -> "users" [label="orders_user_fk", color="#1a7f37", fontcolor="#1a7f37"];
+	"order totals" -> "orders" [style=dashed, color="#1a7f37", fontcolor="#1a7f37"];
+}
+`,
+		},
+		{
+			name: "plantuml",
+			opts: &ERDOptions{Format: ERDPlantUML},
+			expect: `@startuml
+hide circle
+skinparam linetype ortho
+
+entity "users" as users {
+  * id : int <<PK>>
+  --
+  email : varchar <<UK>>
+  name : varchar
+}
+
+entity "orders" as orders #fff8c5 {
+  * id : int <<PK>>
+  --
+  <color:#1a7f37>user_id : int <<FK>></color>
+  <color:#cf222e><s>total : decimal</s></color>
+}
+
+entity "order totals" as entity_3 <<view>> #dafbe1
+
+users ||-[#1a7f37]-o{ orders : orders_user_fk
+entity_3 .[#1a7f37].> orders
+@enduml
+`,
+		},
+		{
+			name:        "unknown format",
+			opts:        &ERDOptions{Format: "svg"},
+			expectError: `unknown ERD format "svg"`,
+		},
+		{
+			name:        "unknown columns",
+			opts:        &ERDOptions{Columns: "some"},
+			expectError: `unknown ERD columns mode "some"`,
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			var b strings.Builder
+			err := testERD().Write(&b, tcase.opts)
+			if tcase.expectError != "" {
+				assert.ErrorContains(t, err, tcase.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tcase.expect, b.String())
+		})
+	}
+}
+
+func TestERDWriteDotColumns(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, testERD().Write(&b, &ERDOptions{Format: ERDDot}))
+	output := b.String()
+	assert.Contains(t, output, `<TR><TD ALIGN="LEFT">email varchar UK</TD></TR>`)
+	assert.Contains(t, output, `<TR><TD ALIGN="LEFT"><FONT COLOR="#1a7f37">user_id int FK</FONT></TD></TR>`)
+	assert.Contains(t, output, `<TR><TD ALIGN="LEFT"><FONT COLOR="#cf222e"><S>total decimal</S></FONT></TD></TR>`)
+}
+
+func TestERDFilter(t *testing.T) {
+	erd := testERD()
+	erd.filter(func(entityName string) bool { return entityName != "users" })
+	require.Len(t, erd.Entities, 2)
+	assert.Equal(t, "orders", erd.Entities[0].Name)
+	require.Len(t, erd.Relations, 1)
+	assert.Equal(t, "order totals", erd.Relations[0].From)
+}
+
+func TestSchemaERD(t *testing.T) {
+	env := schemadiff.NewTestEnv()
+	schema, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table users (id int primary key, email varchar(64), unique key email_uidx (email))",
+		"create table orders (id int primary key, user_id int, constraint orders_user_fk foreign key (user_id) references users (id))",
+		"create view order_users as select o.id, u.email from orders o join (select id, email from users) u on o.user_id = u.id",
+	})
+	require.NoError(t, err)
+
+	erd := SchemaERD(schema)
+	expectEntities := []*ERDEntity{
+		{Name: "orders", Columns: []*ERDColumn{{Name: "id", Type: "int", PrimaryKey: true}, {Name: "user_id", Type: "int", ForeignKey: true}}},
+		{Name: "users", Columns: []*ERDColumn{{Name: "id", Type: "int", PrimaryKey: true}, {Name: "email", Type: "varchar", UniqueKey: true}}},
+		{Name: "order_users", View: true},
+	}
+	assert.ElementsMatch(t, expectEntities, erd.Entities)
+	expectRelations := []*ERDRelation{
+		{From: "orders", To: "users", Name: "orders_user_fk", Columns: []string{"user_id"}, ReferencedColumns: []string{"id"}},
+		{From: "order_users", To: "orders", View: true},
+		{From: "order_users", To: "users", View: true},
+	}
+	assert.ElementsMatch(t, expectRelations, erd.Relations)
+}
+
+func TestViewERD(t *testing.T) {
+	env := schemadiff.NewTestEnv()
+	entity, err := singleEntity(env, "create view recent_orders as with recent as (select id from orders where id > 100) select recent.id from recent join users on recent.id = users.id")
+	require.NoError(t, err)
+	view, ok := entity.(*schemadiff.CreateViewEntity)
+	require.True(t, ok)
+	_, relations := viewERD(view)
+	expectRelations := []*ERDRelation{
+		{From: "recent_orders", To: "orders", View: true},
+		{From: "recent_orders", To: "users", View: true},
+	}
+	assert.ElementsMatch(t, expectRelations, relations)
+}
+
+func TestERDIdentifiers(t *testing.T) {
+	erd := &ERD{
+		Entities: []*ERDEntity{
+			{Name: "order totals", View: true},
+			{Name: "entity_1"},
+			{Name: "entity_1_1"},
+		},
+	}
+	assert.Equal(t, map[string]string{"order totals": "entity_1_2", "entity_1": "entity_1", "entity_1_1": "entity_1_1"}, erd.erdIdentifiers())
+}
+
+func TestDiffERD(t *testing.T) {
+	env := schemadiff.NewTestEnv()
+	source, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table users (id int primary key, name varchar(64))",
+		"create table orders (id int primary key, total decimal(10, 2))",
+		"create table audit (id int primary key)",
+	})
+	require.NoError(t, err)
+	target, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table users (id int primary key, name varchar(64))",
+		"create table orders (id bigint primary key, user_id int, constraint orders_user_fk foreign key (user_id) references users (id))",
+		"create view user_orders as select id from orders",
+	})
+	require.NoError(t, err)
+
+	erd, err := DiffERD(source, target, DefaultDiffHints())
+	require.NoError(t, err)
+	statuses := map[string]ERDStatus{}
+	for _, entity := range erd.Entities {
+		statuses[entity.Name] = entity.Status
+	}
+	assert.Equal(t, map[string]ERDStatus{"users": ERDUnchanged, "orders": ERDChanged, "user_orders": ERDAdded, "audit": ERDRemoved}, statuses)
+	assert.Equal(t, "audit", erd.Entities[len(erd.Entities)-1].Name)
+
+	for _, entity := range erd.Entities {
+		if entity.Name != "orders" {
+			continue
+		}
+		expectColumns := []*ERDColumn{
+			{Name: "id", Type: "bigint", PrimaryKey: true, Status: ERDChanged},
+			{Name: "user_id", Type: "int", ForeignKey: true, Status: ERDAdded},
+			{Name: "total", Type: "decimal", Status: ERDRemoved},
+		}
+		assert.Equal(t, expectColumns, entity.Columns)
+	}
+	relationStatuses := map[string]ERDStatus{}
+	for _, relation := range erd.Relations {
+		relationStatuses[relation.From+"->"+relation.To] = relation.Status
+	}
+	assert.Equal(t, map[string]ERDStatus{"orders->users": ERDAdded, "user_orders->orders": ERDAdded}, relationStatuses)
+}
+
+func TestExecERD(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(12))"})
+	defer os.RemoveAll(fileFrom)
+	fileTo := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, name varchar(12))",
+		"create table t2 (id int primary key, t1_id int, foreign key (t1_id) references t1 (id))",
+	})
+	defer os.RemoveAll(fileTo)
+
+	output, err := Exec(ctx, "erd", fileFrom, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "erDiagram\n    t1 {\n        int id PK\n        varchar name\n    }\n", output)
+
+	output, err = Exec(ctx, "erd", fileFrom, fileTo, &ExecOptions{ERDFormat: ERDDot, ERDColumns: ERDColumnsKeys})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(output, "digraph schema {\n"), output)
+	assert.Contains(t, output, `<TD BGCOLOR="#dafbe1"><B>t2</B></TD>`)
+	assert.Contains(t, output, `"t2" -> "t1"`)
+
+	_, err = Exec(ctx, "erd", fileFrom, "", &ExecOptions{ERDFormat: "svg"})
+	assert.ErrorContains(t, err, `unknown ERD format "svg"`)
+	_, err = Exec(ctx, "erd", fileFrom, fileTo, &ExecOptions{Mapping: []string{"t1=t9"}})
+	assert.ErrorContains(t, err, "not supported by erd")
+}
@@ -1,114 +1,400 @@
 package core
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
 	"vitess.io/vitess/go/mysql/collations"
 	"vitess.io/vitess/go/vt/schemadiff"
 	"vitess.io/vitess/go/vt/vtenv"
+
+	"github.com/planetscale/schemadiff/pkg/base"
 )
 
 var (
 	ErrIdenticalSourceTarget = errors.New("--source and --target must be different")
+	ErrMissingMergeInputs    = errors.New("merge requires --base, --ours and --theirs")
+	ErrUnknownCommand        = errors.New("unknown command")
+	ErrFleetMapping          = errors.New("--map and --map-file are not supported with --targets and --targets-file")
+	ErrFleetTarget           = errors.New("--target is not supported with --targets and --targets-file")
+	ErrApplyTarget           = errors.New("apply-to requires --target to be a MySQL DSN")
+	ErrApplyCancelled        = errors.New("apply-to cancelled")
+	ErrApplyFailed           = errors.New("apply-to failed")
+	ErrApplyVerification     = errors.New("apply-to verification failed")
+	ErrRoundTrip             = errors.New("diff round trip does not converge")
+	ErrImpossibleOrder       = errors.New("diffs cannot be ordered")
+	ErrPlanIncomplete        = errors.New("plan does not reach the target schema")
+	ErrStdinPairInputs       = errors.New("--stdin-pair reads both the source and the target from standard input; --source and --target must be empty or \"-\"")
+	ErrStdinPairConsumed     = errors.New("--stdin-pair reads standard input once, and it has already been read")
 
 	timeout = time.Minute * 5
 )
 
-const mysqlVersion = "8.0.35"
-
-// Exec is the main execution entry for this app, called by the main() function.
-// Teh function returns a textual output, which is later send to standard output.
-func Exec(ctx context.Context, command string, source string, target string, textual bool) (output string, err error) {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+// DefaultMySQLVersion is the MySQL version schemadiff emulates, unless otherwise specified
+const DefaultMySQLVersion = "8.0.35"
 
+// NewEnv returns a schemadiff environment for the given MySQL version.
+func NewEnv(mysqlVersion string) (*schemadiff.Environment, error) {
 	collEnv := collations.NewEnvironment(mysqlVersion)
 	vtenv, err := vtenv.New(vtenv.Options{
 		MySQLServerVersion: mysqlVersion,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return schemadiff.NewEnv(vtenv, collEnv.DefaultConnectionCharset()), nil
+}
+
+// ExecOptions are the optional flags that modify the behavior of Exec. A nil value is valid and implies defaults.
+type ExecOptions struct {
+	// Args are the positional arguments that follow the command. Only git-diff-driver accepts such arguments.
+	Args []string
+	// Color, when true, adds terminal colors to diff output. See ColorEnabled for resolving the --color flag.
+	Color bool
+	// Textual, when true, outputs textual diff rather than semantic SQL diff
+	Textual bool
+	// IncludeInternalTables, when true, reads online schema change artifacts and Vitess internal tables from MySQL sources
+	IncludeInternalTables bool
+	// Warnings, if non nil, receives non-fatal notices, such as skipped entities. Typically set to standard error.
+	Warnings io.Writer
+	// Mapping is a list of "old=new" entity renames and "table.old=new" column renames, applied to the source schema before diffing
+	Mapping []string
+	// MappingFile is a file with mapping values, one per line
+	MappingFile string
+	// HeuristicRenames, when true, lets schemadiff heuristically identify renamed columns and tables
+	HeuristicRenames bool
+	// Base, Ours and Theirs are the three inputs to the merge command
+	Base   string
+	Ours   string
+	Theirs string
+	// MergeDriver, when true, makes the merge command write the merged schema into the Ours input, as expected
+	// of a git merge driver, rather than return it as output. The merged schema is written as plain SQL, which is
+	// why MergeDriver is not supported with Textual, and Color does not apply.
+	MergeDriver bool
+	// SuggestRenames, when true, makes diff commands output a scored report of candidate table and column renames,
+	// rather than the diff. The report is valid input to MappingFile.
+	SuggestRenames bool
+	// StdinPair, when true, makes diff commands read both the source and the target from standard input, separated
+	// by a StdinDelimiter line, or sent as a {"source": ..., "target": ...} JSON object
+	StdinPair bool
+	// Snapshot, if non empty, is a file into which the load command writes a snapshot of the loaded schema, with
+	// checksums and metadata. See base.Snapshot.
+	Snapshot string
+	// Targets are target patterns to diff the source against, in fleet mode. See ExpandTargets.
+	Targets []string
+	// TargetsFile is a file with target patterns, one per line, to diff the source against, in fleet mode
+	TargetsFile string
+	// Concurrency is the maximum number of targets read concurrently in fleet mode. Defaults to DefaultFleetConcurrency.
+	Concurrency int
+	// Output is the output format: "text" (the default), "json", "markdown" or "html". JSON output is supported in
+	// fleet mode. Markdown and HTML output are reports of the diff, supported by diff commands. See
+	// Result.WriteMarkdown and Result.WriteHTML.
+	Output string
+	// StdinDelimiter is the line separating the source from the target in StdinPair mode. Defaults to base.DefaultStdinDelimiter.
+	StdinDelimiter string
+	// DryRun, when true, makes apply-to output the statements it would execute, without executing them
+	DryRun bool
+	// Yes, when true, makes apply-to execute statements without prompting for confirmation
+	Yes bool
+	// LockWaitTimeout and InnoDBLockWaitTimeout, if positive, set the lock_wait_timeout and innodb_lock_wait_timeout
+	// of the apply-to session
+	LockWaitTimeout       time.Duration
+	InnoDBLockWaitTimeout time.Duration
+	// ContinueOnError, when true, makes apply-to execute all statements even if some fail, rather than stop at the first failure
+	ContinueOnError bool
+	// Emit is the format of the diff statements: "sql" (the default), "gh-ost", "pt-osc" or "vitess". See EmitDiffs.
+	// Other than "sql", the diff command emits the statements in an order valid to apply, as ordered-diff does.
+	Emit string
+	// EmitDatabase is the database name given to the online schema change tool, or the keyspace with "vitess".
+	// Defaults to the database of the source, if it is a MySQL DSN.
+	EmitDatabase string
+	// EmitTemplate, if non empty, overrides the command line template of the online schema change tool. See EmitOptions.
+	EmitTemplate string
+	// EmitDDLStrategy is the ddl_strategy of diffs other than CREATE statements, with "vitess". Defaults to
+	// DefaultVitessDDLStrategy.
+	EmitDDLStrategy string
+	// EmitKeyspaces are "entity=keyspace" values, which apply the statements on the entity to the keyspace, with
+	// "vitess". See ParseEmitKeyspaces.
+	EmitKeyspaces []string
+	// ERDFormat is the format of the erd command output: "dot", "mermaid" (the default) or "plantuml"
+	ERDFormat string
+	// ERDColumns selects the columns of each table in the erd command output: "all" (the default), "keys" or "none"
+	ERDColumns string
+	// PlanPolicies are the names of the expand/contract policies of the plan command. Defaults to all policies.
+	// See ParsePlanPolicies.
+	PlanPolicies []string
+	// PlanDir, if non empty, is a directory into which the plan command writes each phase as a migration file,
+	// rather than output the phases. The output then lists the written files.
+	PlanDir string
+	// Stdin is standard input, from which apply-to reads the confirmation. Defaults to os.Stdin.
+	Stdin io.Reader
+	// Prompt receives the apply-to confirmation prompt. Defaults to os.Stderr.
+	Prompt io.Writer
+}
+
+// diffHints returns the diff hints implied by the given options
+func diffHints(opts *ExecOptions) *schemadiff.DiffHints {
+	hints := *defaultDiffHints
+	if opts.HeuristicRenames {
+		hints.ColumnRenameStrategy = schemadiff.ColumnRenameHeuristicStatement
+		hints.TableRenameStrategy = schemadiff.TableRenameHeuristicStatement
+	}
+	return &hints
+}
+
+// Exec is the main execution entry for this app, called by the main() function. It adapts command line
+// flags to a Runner, after checking that the flags apply to the command. The function returns a textual output,
+// which is later send to standard output.
+func Exec(ctx context.Context, command string, source string, target string, opts *ExecOptions) (output string, err error) {
+	if command != string(commandApplyTo) {
+		// apply-to runs DDL on a live server, which may take any amount of time
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if opts == nil {
+		opts = &ExecOptions{}
+	}
+	spec, err := lookupCommand(Command(command), true)
 	if err != nil {
 		return "", err
 	}
-	env := schemadiff.NewEnv(vtenv, collEnv.DefaultConnectionCharset())
-	var bld strings.Builder
-	writeDiff := func(d schemadiff.EntityDiff) {
-		if textual {
-			_, _, unified := d.Annotated()
-			bld.WriteString(unified.Export())
-		} else {
-			bld.WriteString(d.CanonicalStatementString())
-		}
-		bld.WriteString(";\n")
+	if err := spec.validate(opts); err != nil {
+		return "", err
 	}
-	getDiffs := func(ordered bool) (err error) {
-		if source == target {
-			return ErrIdenticalSourceTarget
+	runnerOpts, err := spec.runnerOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	runner, err := NewRunner(runnerOpts)
+	if err != nil {
+		return "", err
+	}
+
+	if len(opts.Targets) > 0 || opts.TargetsFile != "" {
+		return execFleet(ctx, runner, command, source, target, opts)
+	}
+
+	var bld strings.Builder
+	switch Command(command) {
+	case commandGitDiffDriver:
+		// git invokes an external diff driver with: path old-file old-hex old-mode new-file new-hex new-mode,
+		// or with just the path, for an unmerged path.
+		switch len(opts.Args) {
+		case 1:
+			return fmt.Sprintf("* Unmerged path %s\n", opts.Args[0]), nil
+		case 7:
+		default:
+			return "", fmt.Errorf("git-diff-driver expects 7 arguments: path old-file old-hex old-mode new-file new-hex new-mode, got %d", len(opts.Args))
 		}
-		diff, err := DiffSchemas(env, source, target)
+		path, oldFile, newFile := opts.Args[0], opts.Args[1], opts.Args[4]
+		diffs, err := runner.DiffGitBlobs(ctx, oldFile, newFile)
 		if err != nil {
-			return err
+			return "", fmt.Errorf("%s: %w", path, err)
 		}
-
-		var diffs []schemadiff.EntityDiff
-		if ordered {
-			diffs, err = diff.OrderedDiffs(ctx)
-			if err != nil {
-				return err
-			}
-		} else {
-			diffs = diff.UnorderedDiffs()
+		if len(diffs) == 0 {
+			return "", nil
 		}
-		for _, d := range diffs {
-			writeDiff(d)
+		bld.WriteString(gitDiffHeader(path, oldFile, newFile))
+		result := &Result{Diffs: diffs}
+		if err := result.Write(&bld, opts.Textual); err != nil {
+			return "", err
 		}
-		return nil
-	}
-	switch command {
-	case "load":
-		schema, err := LoadSchema(env, source)
+	case commandApply:
+	case commandApplyTo:
+		return execApplyTo(ctx, runner, source, target, opts)
+	default:
+		cmd := Command(command)
+		if opts.SuggestRenames && (cmd == CommandDiff || cmd == CommandOrderedDiff) {
+			cmd = CommandSuggestRenames
+		}
+		if (opts.Output == "markdown" || opts.Output == "html" || (opts.Emit != "" && opts.Emit != EmitSQL)) && cmd == CommandDiff {
+			// Reports and emitted scripts list the statements in an order valid to apply
+			cmd = CommandOrderedDiff
+		}
+		result, err := runner.Run(ctx, cmd, &Request{
+			Source: source,
+			Target: target,
+			Base:   opts.Base,
+			Ours:   opts.Ours,
+			Theirs: opts.Theirs,
+		})
 		if err != nil {
 			return "", err
 		}
-		for _, e := range schema.Entities() {
-			writeDiff(e.Create())
+		if result.ERD != nil {
+			if err := result.ERD.Write(&bld, &ERDOptions{Format: opts.ERDFormat, Columns: opts.ERDColumns}); err != nil {
+				return "", err
+			}
+			return bld.String(), nil
 		}
-	case "diff":
-		if err := getDiffs(false); err != nil {
-			return "", err
+		switch opts.Output {
+		case "markdown":
+			if err := result.WriteMarkdown(&bld); err != nil {
+				return "", err
+			}
+			return bld.String(), nil
+		case "html":
+			if err := result.WriteHTML(&bld); err != nil {
+				return "", err
+			}
+			return bld.String(), nil
 		}
-	case "ordered-diff":
-		if err := getDiffs(true); err != nil {
+		if opts.Emit != "" && opts.Emit != EmitSQL {
+			database := opts.EmitDatabase
+			if database == "" {
+				database = base.DatabaseName(source)
+			}
+			keyspaces, err := ParseEmitKeyspaces(opts.EmitKeyspaces)
+			if err != nil {
+				return "", err
+			}
+			err = EmitDiffs(&bld, result.Preamble, result.Diffs, &EmitOptions{
+				Format:      opts.Emit,
+				Database:    database,
+				Keyspaces:   keyspaces,
+				Template:    opts.EmitTemplate,
+				DDLStrategy: opts.EmitDDLStrategy,
+			})
+			if err != nil {
+				return "", err
+			}
+			return bld.String(), nil
+		}
+		if err := result.Write(&bld, opts.Textual); err != nil {
 			return "", err
 		}
-	case "diff-table":
-		if source == target {
-			return "", ErrIdenticalSourceTarget
+		if result.Verification != nil && !result.Verification.Converges() {
+			return bld.String(), ErrRoundTrip
 		}
-		diff, err := DiffTables(env, source, target)
-		if err != nil {
-			return "", err
+		if result.OrderExplanation != nil && result.OrderExplanation.Error != "" {
+			return bld.String(), ErrImpossibleOrder
+		}
+		if result.Plan != nil && !result.Plan.Valid() {
+			return bld.String(), ErrPlanIncomplete
 		}
-		if !diff.IsEmpty() {
-			writeDiff(diff)
+		if result.Plan != nil && opts.PlanDir != "" {
+			fileNames, err := result.Plan.WriteFiles(opts.PlanDir)
+			if err != nil {
+				return "", err
+			}
+			var files strings.Builder
+			for _, fileName := range fileNames {
+				fmt.Fprintf(&files, "%s\n", fileName)
+			}
+			return files.String(), nil
 		}
-	case "diff-view":
-		if source == target {
-			return "", ErrIdenticalSourceTarget
+		if opts.Snapshot != "" {
+			snapshot, err := runner.Snapshot(ctx, result, source)
+			if err != nil {
+				return "", err
+			}
+			if err := snapshot.WriteFile(opts.Snapshot); err != nil {
+				return "", err
+			}
+		}
+		if cmd == CommandMerge && opts.MergeDriver {
+			fileInfo, err := os.Stat(opts.Ours)
+			if err != nil {
+				return "", err
+			}
+			if err := os.WriteFile(opts.Ours, []byte(bld.String()), fileInfo.Mode().Perm()); err != nil {
+				return "", err
+			}
+			return "", nil
 		}
-		diff, err := DiffViews(env, source, target)
+	}
+	if opts.Color {
+		return colorizeDiff(bld.String(), opts.Textual), nil
+	}
+	return bld.String(), nil
+}
+
+// execFleet runs a diff command in fleet mode, diffing the source against all targets given by opts.Targets and
+// opts.TargetsFile.
+func execFleet(ctx context.Context, runner *Runner, command string, source string, target string, opts *ExecOptions) (string, error) {
+	if target != "" {
+		return "", ErrFleetTarget
+	}
+	patterns := opts.Targets
+	if opts.TargetsFile != "" {
+		filePatterns, err := ReadTargetsFile(opts.TargetsFile)
 		if err != nil {
 			return "", err
 		}
-		if !diff.IsEmpty() {
-			writeDiff(diff)
+		patterns = append(filePatterns, patterns...)
+	}
+	targets, err := ExpandTargets(patterns)
+	if err != nil {
+		return "", err
+	}
+	if len(targets) == 0 {
+		return "", errors.New("no targets given")
+	}
+	result, err := runner.DiffFleet(ctx, source, targets, &FleetOptions{
+		Concurrency: opts.Concurrency,
+		Ordered:     command == string(CommandOrderedDiff),
+	})
+	if err != nil {
+		return "", err
+	}
+	var bld strings.Builder
+	if opts.Output == "json" {
+		err = result.WriteJSON(&bld)
+	} else {
+		err = result.Write(&bld)
+	}
+	return bld.String(), err
+}
+
+// execApplyTo runs the apply-to command, prompting for confirmation unless opts.Yes or opts.DryRun are set. The
+// output lists the statements and their status even when applying them fails.
+func execApplyTo(ctx context.Context, runner *Runner, source string, target string, opts *ExecOptions) (string, error) {
+	applyOpts := &ApplyOptions{
+		DryRun:                opts.DryRun,
+		LockWaitTimeout:       opts.LockWaitTimeout,
+		InnoDBLockWaitTimeout: opts.InnoDBLockWaitTimeout,
+		ContinueOnError:       opts.ContinueOnError,
+	}
+	if !opts.Yes && !opts.DryRun {
+		if sourceSource, _, err := base.DetectSchemaSource(source); err == nil {
+			if _, ok := sourceSource.(*base.StdinSource); ok {
+				return "", errors.New("apply-to reads the source from standard input, and cannot prompt for confirmation; use --yes or --dry-run")
+			}
+		}
+		stdin, prompt := opts.Stdin, opts.Prompt
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		if prompt == nil {
+			prompt = os.Stderr
+		}
+		applyOpts.Confirm = func(target string, statements []string) (bool, error) {
+			for _, statement := range statements {
+				fmt.Fprintf(prompt, "%s;\n", statement)
+			}
+			fmt.Fprintf(prompt, "Apply %s to %s? [y/N] ", pluralStatements(len(statements)), target)
+			answer, err := bufio.NewReader(stdin).ReadString('\n')
+			if err != nil && err != io.EOF {
+				return false, err
+			}
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			return answer == "y" || answer == "yes", nil
 		}
-	case "apply":
-	default:
-		return "", fmt.Errorf("unknown command: %s", command)
 	}
-	return bld.String(), nil
+	result, err := runner.ApplyTo(ctx, source, target, applyOpts)
+	if result == nil {
+		return "", err
+	}
+	var bld strings.Builder
+	if writeErr := result.Write(&bld); writeErr != nil {
+		return "", writeErr
+	}
+	return bld.String(), err
 }
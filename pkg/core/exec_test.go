@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"vitess.io/vitess/go/vt/schemadiff"
 	"vitess.io/vitess/go/vt/sqlparser"
+
+	"github.com/planetscale/schemadiff/pkg/base"
 )
 
 // This unit-test file validates the high level operation of the Exec function, and specifically its
@@ -101,7 +104,7 @@ func TestExecLoad(t *testing.T) {
 		fileFrom := writeSchemaFile(t, schemaFrom)
 		require.NotEmpty(t, fileFrom)
 		defer os.RemoveAll(fileFrom)
-		schema, err := Exec(ctx, "load", fileFrom, "", false)
+		schema, err := Exec(ctx, "load", fileFrom, "", nil)
 		assert.NoError(t, err)
 		assert.Equal(t, sqlsToMultiStatementText(loadFrom), schema)
 	})
@@ -109,7 +112,7 @@ func TestExecLoad(t *testing.T) {
 		fileFrom := writeSchemaFile(t, schemaFrom)
 		require.NotEmpty(t, fileFrom)
 		defer os.RemoveAll(fileFrom)
-		schema, err := Exec(ctx, "load", fileFrom, "", true)
+		schema, err := Exec(ctx, "load", fileFrom, "", &ExecOptions{Textual: true})
 		assert.NoError(t, err)
 		expect := []string{}
 		for _, sql := range loadFrom {
@@ -127,16 +130,53 @@ func TestExecLoad(t *testing.T) {
 		dirFrom := writeSchemaDir(t, schemaFrom)
 		require.NotEmpty(t, dirFrom)
 		defer os.RemoveAll(dirFrom)
-		schema, err := Exec(ctx, "load", dirFrom, "", false)
+		schema, err := Exec(ctx, "load", dirFrom, "", nil)
 		assert.NoError(t, err)
 		assert.Equal(t, sqlsToMultiStatementText(loadFrom), schema)
 	})
 
+	t.Run("snapshot", func(t *testing.T) {
+		fileFrom := writeSchemaFile(t, schemaFrom)
+		require.NotEmpty(t, fileFrom)
+		defer os.RemoveAll(fileFrom)
+		fileTo := writeSchemaFile(t, schemaTo)
+		require.NotEmpty(t, fileTo)
+		defer os.RemoveAll(fileTo)
+		snapshotFile := filepath.Join(t.TempDir(), "snapshot.json")
+
+		schema, err := Exec(ctx, "load", fileFrom, "", &ExecOptions{Snapshot: snapshotFile})
+		assert.NoError(t, err)
+		assert.Equal(t, sqlsToMultiStatementText(loadFrom), schema)
+
+		snapshot, err := base.ReadSnapshotFile(snapshotFile)
+		require.NoError(t, err)
+		assert.Equal(t, "file "+fileFrom, snapshot.Source)
+		assert.Empty(t, snapshot.ServerVersion)
+		assert.Equal(t, DefaultMySQLVersion, snapshot.MySQLVersion)
+		assert.Equal(t, defaultDiffHints, snapshot.Hints)
+		assert.NotZero(t, snapshot.CapturedAt)
+		require.Len(t, snapshot.Entities, len(loadFrom))
+		for i, e := range snapshot.Entities {
+			assert.Equal(t, loadFrom[i], e.Statement)
+			assert.Equal(t, base.SnapshotChecksum(loadFrom[i]), e.SHA256)
+		}
+
+		schema, err = Exec(ctx, "load", snapshotFile, "", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, sqlsToMultiStatementText(loadFrom), schema)
+		diff, err := Exec(ctx, "diff", snapshotFile, fileTo, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, sqlsToMultiStatementText(diffsFromTo), diff)
+
+		_, err = Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{Snapshot: snapshotFile})
+		assert.ErrorContains(t, err, "--snapshot applies to the load command")
+	})
+
 	t.Run("to-file", func(t *testing.T) {
 		fileTo := writeSchemaFile(t, schemaTo)
 		require.NotEmpty(t, fileTo)
 		defer os.RemoveAll(fileTo)
-		schema, err := Exec(ctx, "load", fileTo, "", false)
+		schema, err := Exec(ctx, "load", fileTo, "", nil)
 		assert.NoError(t, err)
 		assert.Equal(t, sqlsToMultiStatementText(loadTo), schema)
 	})
@@ -145,7 +185,7 @@ func TestExecLoad(t *testing.T) {
 		dirTo := writeSchemaDir(t, schemaTo)
 		require.NotEmpty(t, dirTo)
 		defer os.RemoveAll(dirTo)
-		schema, err := Exec(ctx, "load", dirTo, "", false)
+		schema, err := Exec(ctx, "load", dirTo, "", nil)
 		assert.NoError(t, err)
 		assert.Equal(t, sqlsToMultiStatementText(loadTo), schema)
 	})
@@ -154,7 +194,7 @@ func TestExecLoad(t *testing.T) {
 		require.NotEmpty(t, emptyFile) // testing that the *name* is not empty...
 		defer os.RemoveAll(emptyFile)
 
-		schema, err := Exec(ctx, "load", emptyFile, "", false)
+		schema, err := Exec(ctx, "load", emptyFile, "", nil)
 		assert.NoError(t, err)
 		assert.Equal(t, "", schema)
 	})
@@ -280,7 +320,7 @@ func TestExecDiff(t *testing.T) {
 		t.Run(cmd, func(t *testing.T) {
 			for _, tcase := range tcases {
 				t.Run(tcase.name, func(t *testing.T) {
-					diff, err := Exec(ctx, cmd, tcase.source, tcase.target, tcase.textual)
+					diff, err := Exec(ctx, cmd, tcase.source, tcase.target, &ExecOptions{Textual: tcase.textual})
 					if tcase.expectError == "" {
 						assert.NoError(t, err)
 						switch cmd {
@@ -332,7 +372,7 @@ func TestExecDiffTable(t *testing.T) {
 		require.NotEmpty(t, to)
 		defer os.RemoveAll(to)
 
-		diff, err := Exec(ctx, "diff-table", from, to, false)
+		diff, err := Exec(ctx, "diff-table", from, to, nil)
 		assert.NoError(t, err)
 		assert.Equal(t, "ALTER TABLE `t1` MODIFY COLUMN `id` int unsigned;\n", diff)
 	})
@@ -345,7 +385,7 @@ func TestExecDiffTable(t *testing.T) {
 		require.NotEmpty(t, to)
 		defer os.RemoveAll(to)
 
-		diff, err := Exec(ctx, "diff-table", from, to, false)
+		diff, err := Exec(ctx, "diff-table", from, to, nil)
 		assert.NoError(t, err)
 		assert.Equal(t, "ALTER TABLE `t1` ADD COLUMN `age` int unsigned;\n", diff)
 	})
@@ -358,7 +398,7 @@ func TestExecDiffTable(t *testing.T) {
 		require.NotEmpty(t, to)
 		defer os.RemoveAll(to)
 
-		diff, err := Exec(ctx, "diff-table", from, to, true)
+		diff, err := Exec(ctx, "diff-table", from, to, &ExecOptions{Textual: true})
 		assert.NoError(t, err)
 		assert.Equal(t, " CREATE TABLE `t1` (\n \t`id` int,\n+\t`age` int unsigned,\n \tPRIMARY KEY (`id`)\n );\n", diff)
 	})
@@ -371,7 +411,7 @@ func TestExecDiffTable(t *testing.T) {
 		require.NotEmpty(t, to)
 		defer os.RemoveAll(to)
 
-		_, err := Exec(ctx, "diff-table", from, to, false)
+		_, err := Exec(ctx, "diff-table", from, to, nil)
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, schemadiff.ErrExpectedCreateTable)
 	})
@@ -384,7 +424,7 @@ func TestExecDiffTable(t *testing.T) {
 		require.NotEmpty(t, to)
 		defer os.RemoveAll(to)
 
-		diff, err := Exec(ctx, "diff-view", from, to, false)
+		diff, err := Exec(ctx, "diff-view", from, to, nil)
 		assert.NoError(t, err)
 		assert.Empty(t, diff)
 	})
@@ -397,7 +437,7 @@ func TestExecDiffTable(t *testing.T) {
 		require.NotEmpty(t, to)
 		defer os.RemoveAll(to)
 
-		diff, err := Exec(ctx, "diff-view", from, to, false)
+		diff, err := Exec(ctx, "diff-view", from, to, nil)
 		assert.NoError(t, err)
 		assert.Equal(t, "ALTER VIEW `v1` AS SELECT `id`, 1 FROM `t1`;\n", diff)
 	})
@@ -411,14 +451,432 @@ func TestExecDiffTable(t *testing.T) {
 		defer os.RemoveAll(to)
 
 		{
-			_, err := Exec(ctx, "diff-table", from, to, false)
+			_, err := Exec(ctx, "diff-table", from, to, nil)
 			assert.Error(t, err)
 			assert.ErrorContains(t, err, "expected one CREATE TABLE statement")
 		}
 		{
-			_, err := Exec(ctx, "diff-table", to, from, false)
+			_, err := Exec(ctx, "diff-table", to, from, nil)
 			assert.Error(t, err)
 			assert.ErrorContains(t, err, "expected one CREATE TABLE statement")
 		}
 	})
 }
+
+func TestExecDiffEntityMapping(t *testing.T) {
+	ctx := context.Background()
+
+	from := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, name varchar(10))",
+		"create table c1 (id int primary key, t1_id int, key t1_idx (t1_id), foreign key (t1_id) references t1 (id))",
+		"create view v1 as select id from t1",
+	})
+	require.NotEmpty(t, from)
+	defer os.RemoveAll(from)
+
+	to := writeSchemaFile(t, []string{
+		"create table users (id int primary key, name varchar(20))",
+		"create table c1 (id int primary key, t1_id int, key t1_idx (t1_id), foreign key (t1_id) references users (id))",
+		"create view v1 as select id from users",
+	})
+	require.NotEmpty(t, to)
+	defer os.RemoveAll(to)
+
+	t.Run("no mapping", func(t *testing.T) {
+		diff, err := Exec(ctx, "diff", from, to, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, diff, "DROP TABLE `t1`")
+		assert.Contains(t, diff, "CREATE TABLE `users`")
+	})
+	t.Run("mapping", func(t *testing.T) {
+		diff, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t1=users"}})
+		assert.NoError(t, err)
+		expect := []string{
+			"RENAME TABLE `t1` TO `users`",
+			"ALTER VIEW `v1` AS SELECT `id` FROM `users`",
+			"ALTER TABLE `users` MODIFY COLUMN `name` varchar(20)",
+		}
+		assert.Equal(t, sqlsToMultiStatementText(expect), diff)
+	})
+	t.Run("mapping file", func(t *testing.T) {
+		mappingFile, err := os.CreateTemp(os.TempDir(), "schemadiff-unittest-mapping-*")
+		require.NoError(t, err)
+		defer os.RemoveAll(mappingFile.Name())
+		_, err = mappingFile.WriteString("# renames\n\nt1=users\n")
+		require.NoError(t, err)
+
+		diff, err := Exec(ctx, "ordered-diff", from, to, &ExecOptions{MappingFile: mappingFile.Name()})
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(diff, "RENAME TABLE `t1` TO `users`;\n"))
+		assert.NotContains(t, diff, "DROP TABLE")
+	})
+	t.Run("unknown source entity", func(t *testing.T) {
+		_, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t9=users"}})
+		assert.ErrorContains(t, err, "source entity t9 not found")
+	})
+	t.Run("unknown target entity", func(t *testing.T) {
+		_, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t1=users9"}})
+		assert.ErrorContains(t, err, "target entity users9 not found")
+	})
+	t.Run("existing source entity", func(t *testing.T) {
+		_, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t1=c1"}})
+		assert.ErrorContains(t, err, "entity c1 already exists in source")
+	})
+	t.Run("unsupported command", func(t *testing.T) {
+		_, err := Exec(ctx, "load", from, "", &ExecOptions{MappingFile: "/nonexistent/schemadiff-mapping"})
+		assert.EqualError(t, err, "--map and --map-file are not supported by load")
+	})
+}
+
+func TestExecDiffColumnMapping(t *testing.T) {
+	ctx := context.Background()
+
+	from := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, name varchar(10), key name_idx (name))",
+		"create view v1 as select id, name from t1",
+	})
+	require.NotEmpty(t, from)
+	defer os.RemoveAll(from)
+
+	to := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, full_name varchar(10), key name_idx (full_name))",
+		"create view v1 as select id, full_name from t1",
+	})
+	require.NotEmpty(t, to)
+	defer os.RemoveAll(to)
+
+	t.Run("mapping", func(t *testing.T) {
+		diff, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t1.name=full_name"}})
+		assert.NoError(t, err)
+		expect := []string{
+			"ALTER TABLE `t1` RENAME COLUMN `name` TO `full_name`",
+			"ALTER VIEW `v1` AS SELECT `id`, `full_name` FROM `t1`",
+		}
+		assert.Equal(t, sqlsToMultiStatementText(expect), diff)
+	})
+	t.Run("view join", func(t *testing.T) {
+		// t2 has a name column too, which the mapping leaves as is
+		from := writeSchemaFile(t, []string{
+			"create table t1 (id int primary key, name varchar(10))",
+			"create table t2 (id int primary key, name varchar(10))",
+			"create view v1 as select a.id, a.name, b.name as other from t1 as a join t2 as b on a.id = b.id",
+			"create view v2 as select name from t2 where id in (select id from t1 where name = 'x')",
+		})
+		defer os.RemoveAll(from)
+		to := writeSchemaFile(t, []string{
+			"create table t1 (id int primary key, full_name varchar(10))",
+			"create table t2 (id int primary key, name varchar(10))",
+			"create view v1 as select a.id, a.full_name, b.name as other from t1 as a join t2 as b on a.id = b.id",
+			"create view v2 as select name from t2 where id in (select id from t1 where full_name = 'x')",
+		})
+		defer os.RemoveAll(to)
+
+		diff, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t1.name=full_name"}})
+		assert.NoError(t, err)
+		expect := []string{
+			"ALTER TABLE `t1` RENAME COLUMN `name` TO `full_name`",
+			"ALTER VIEW `v1` AS SELECT `a`.`id`, `a`.`full_name`, `b`.`name` AS `other` FROM `t1` AS `a` JOIN `t2` AS `b` ON `a`.`id` = `b`.`id`",
+			"ALTER VIEW `v2` AS SELECT `name` FROM `t2` WHERE `id` IN (SELECT `id` FROM `t1` WHERE `full_name` = 'x')",
+		}
+		assert.Equal(t, sqlsToMultiStatementText(expect), diff)
+	})
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t1.nm=full_name"}})
+		assert.ErrorContains(t, err, "source column nm not found")
+	})
+	t.Run("unknown table", func(t *testing.T) {
+		_, err := Exec(ctx, "diff", from, to, &ExecOptions{Mapping: []string{"t9.name=full_name"}})
+		assert.ErrorContains(t, err, "source table t9 not found")
+	})
+}
+
+func TestExecSuggestRenames(t *testing.T) {
+	ctx := context.Background()
+
+	from := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, name varchar(10), key name_idx (name))",
+		"create table t2 (id int primary key, ts timestamp)",
+	})
+	require.NotEmpty(t, from)
+	defer os.RemoveAll(from)
+
+	to := writeSchemaFile(t, []string{
+		"create table users (id int primary key, name varchar(10), key name_idx (name))",
+		"create table t2 (id int primary key, created_at timestamp)",
+	})
+	require.NotEmpty(t, to)
+	defer os.RemoveAll(to)
+
+	suggestions, err := Exec(ctx, "diff", from, to, &ExecOptions{SuggestRenames: true})
+	require.NoError(t, err)
+	expect := "# table t1 renamed to users? score 1.00: 100% same columns, 100% same keys\nt1=users\n" +
+		"# column t2.ts renamed to created_at? score 1.00: same type, same position, not indexed\nt2.ts=created_at\n"
+	assert.Equal(t, expect, suggestions)
+
+	t.Run("approved", func(t *testing.T) {
+		// The suggestions report is a valid mapping file
+		mappingFile, err := os.CreateTemp(os.TempDir(), "schemadiff-unittest-mapping-*")
+		require.NoError(t, err)
+		defer os.RemoveAll(mappingFile.Name())
+		_, err = mappingFile.WriteString(suggestions)
+		require.NoError(t, err)
+
+		diff, err := Exec(ctx, "diff", from, to, &ExecOptions{MappingFile: mappingFile.Name()})
+		assert.NoError(t, err)
+		expect := []string{
+			"RENAME TABLE `t1` TO `users`",
+			"ALTER TABLE `t2` RENAME COLUMN `ts` TO `created_at`",
+		}
+		assert.Equal(t, sqlsToMultiStatementText(expect), diff)
+
+		// Approved renames are not suggested again
+		suggestions, err := Exec(ctx, "diff", from, to, &ExecOptions{SuggestRenames: true, MappingFile: mappingFile.Name()})
+		assert.NoError(t, err)
+		assert.Empty(t, suggestions)
+	})
+	t.Run("unrelated types", func(t *testing.T) {
+		// Unindexed columns in the same position, but of unrelated types, are no rename
+		from := writeSchemaFile(t, []string{"create table t2 (id int primary key, ts timestamp)"})
+		require.NotEmpty(t, from)
+		defer os.RemoveAll(from)
+
+		to := writeSchemaFile(t, []string{"create table t2 (id int primary key, payload json)"})
+		require.NotEmpty(t, to)
+		defer os.RemoveAll(to)
+
+		suggestions, err := Exec(ctx, "diff", from, to, &ExecOptions{SuggestRenames: true})
+		assert.NoError(t, err)
+		assert.Empty(t, suggestions)
+	})
+	t.Run("heuristic", func(t *testing.T) {
+		from := writeSchemaFile(t, []string{"create table t2 (id int primary key, ts timestamp)"})
+		require.NotEmpty(t, from)
+		defer os.RemoveAll(from)
+
+		to := writeSchemaFile(t, []string{"create table t2 (id int primary key, created_at timestamp)"})
+		require.NotEmpty(t, to)
+		defer os.RemoveAll(to)
+
+		diff, err := Exec(ctx, "diff-table", from, to, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, diff, "DROP COLUMN `ts`")
+
+		diff, err = Exec(ctx, "diff-table", from, to, &ExecOptions{HeuristicRenames: true})
+		assert.NoError(t, err)
+		assert.Contains(t, diff, "RENAME COLUMN `ts` TO `created_at`")
+		assert.NotContains(t, diff, "DROP COLUMN")
+	})
+}
+
+func TestExecMerge(t *testing.T) {
+	ctx := context.Background()
+
+	base := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, name varchar(10))",
+	})
+	defer os.RemoveAll(base)
+
+	tcases := []struct {
+		name        string
+		ours        []string
+		theirs      []string
+		expect      []string
+		expectError string
+	}{
+		{
+			name:   "no changes",
+			ours:   []string{"create table t1 (id int primary key, name varchar(10))"},
+			theirs: []string{"create table t1 (id int primary key, name varchar(10))"},
+			expect: []string{"CREATE TABLE `t1` (\n\t`id` int,\n\t`name` varchar(10),\n\tPRIMARY KEY (`id`)\n)"},
+		},
+		{
+			name: "distinct entities",
+			ours: []string{
+				"create table t1 (id int primary key, name varchar(10))",
+				"create table t2 (id int primary key)",
+			},
+			theirs: []string{
+				"create table t1 (id int primary key, name varchar(10))",
+				"create view v1 as select id from t1",
+			},
+			expect: []string{
+				"CREATE TABLE `t1` (\n\t`id` int,\n\t`name` varchar(10),\n\tPRIMARY KEY (`id`)\n)",
+				"CREATE TABLE `t2` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)",
+				"CREATE VIEW `v1` AS SELECT `id` FROM `t1`",
+			},
+		},
+		{
+			name:   "same table, distinct columns and keys",
+			ours:   []string{"create table t1 (id int primary key, name varchar(10), a int)"},
+			theirs: []string{"create table t1 (id int primary key, name varchar(10), key name_idx (name))"},
+			expect: []string{"CREATE TABLE `t1` (\n\t`id` int,\n\t`name` varchar(10),\n\t`a` int,\n\tPRIMARY KEY (`id`),\n\tKEY `name_idx` (`name`)\n)"},
+		},
+		{
+			name:   "identical changes",
+			ours:   []string{"create table t1 (id int primary key, name varchar(20))"},
+			theirs: []string{"create table t1 (id int primary key, name varchar(20))"},
+			expect: []string{"CREATE TABLE `t1` (\n\t`id` int,\n\t`name` varchar(20),\n\tPRIMARY KEY (`id`)\n)"},
+		},
+		{
+			name:        "same column",
+			ours:        []string{"create table t1 (id int primary key, name varchar(20))"},
+			theirs:      []string{"create table t1 (id int primary key, name varchar(30))"},
+			expectError: "t1: both sides change column name",
+		},
+		{
+			name: "created differently",
+			ours: []string{
+				"create table t1 (id int primary key, name varchar(10))",
+				"create table t2 (id int primary key)",
+			},
+			theirs: []string{
+				"create table t1 (id int primary key, name varchar(10))",
+				"create table t2 (id bigint primary key)",
+			},
+			expectError: "t2: both sides change the entity",
+		},
+		{
+			name:        "dropped and changed",
+			ours:        nil,
+			theirs:      []string{"create table t1 (id int primary key, name varchar(30))"},
+			expectError: "t1: both sides change the entity",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			ours := writeSchemaFile(t, tcase.ours)
+			defer os.RemoveAll(ours)
+			theirs := writeSchemaFile(t, tcase.theirs)
+			defer os.RemoveAll(theirs)
+
+			merged, err := Exec(ctx, "merge", "", "", &ExecOptions{Base: base, Ours: ours, Theirs: theirs})
+			if tcase.expectError != "" {
+				assert.ErrorContains(t, err, tcase.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, sqlsToMultiStatementText(tcase.expect), merged)
+		})
+	}
+	t.Run("column position", func(t *testing.T) {
+		base := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(10), age int)"})
+		defer os.RemoveAll(base)
+		// ours adds a column AFTER the column theirs drops
+		ours := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(10), nick varchar(10), age int)"})
+		defer os.RemoveAll(ours)
+		theirs := writeSchemaFile(t, []string{"create table t1 (id int primary key, age int)"})
+		defer os.RemoveAll(theirs)
+
+		_, err := Exec(ctx, "merge", "", "", &ExecOptions{Base: base, Ours: ours, Theirs: theirs})
+		assert.ErrorContains(t, err, "t1: both sides change column name")
+	})
+	t.Run("missing input", func(t *testing.T) {
+		_, err := Exec(ctx, "merge", "", "", &ExecOptions{Base: base, Ours: base})
+		assert.ErrorIs(t, err, ErrMissingMergeInputs)
+	})
+	t.Run("merge driver", func(t *testing.T) {
+		ours := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(10), a int)"})
+		defer os.RemoveAll(ours)
+		theirs := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(10), key name_idx (name))"})
+		defer os.RemoveAll(theirs)
+
+		_, err := Exec(ctx, "merge", "", "", &ExecOptions{Base: base, Ours: ours, Theirs: theirs, MergeDriver: true, Textual: true})
+		assert.ErrorContains(t, err, "not supported with --textual")
+		_, err = Exec(ctx, "diff", base, ours, &ExecOptions{MergeDriver: true})
+		assert.ErrorContains(t, err, "--merge-driver applies to the merge command")
+
+		// The merged schema is written without colors
+		output, err := Exec(ctx, "merge", "", "", &ExecOptions{Base: base, Ours: ours, Theirs: theirs, MergeDriver: true, Color: true})
+		assert.NoError(t, err)
+		assert.Empty(t, output)
+
+		merged, err := os.ReadFile(ours)
+		require.NoError(t, err)
+		expect := []string{"CREATE TABLE `t1` (\n\t`id` int,\n\t`name` varchar(10),\n\t`a` int,\n\tPRIMARY KEY (`id`),\n\tKEY `name_idx` (`name`)\n)"}
+		assert.Equal(t, sqlsToMultiStatementText(expect), string(merged))
+	})
+}
+
+func TestExecGitDiffDriver(t *testing.T) {
+	ctx := context.Background()
+
+	from := writeSchemaFile(t, schemaFrom[0:1])
+	defer os.RemoveAll(from)
+	to := writeSchemaFile(t, schemaTo[0:1])
+	defer os.RemoveAll(to)
+	fromSchema := writeSchemaFile(t, schemaFrom)
+	defer os.RemoveAll(fromSchema)
+	toSchema := writeSchemaFile(t, schemaTo)
+	defer os.RemoveAll(toSchema)
+	// A table referencing a table which is not found in the same file:
+	fromChild := writeSchemaFile(t, []string{"create table c1 (id int primary key, t1_id int, key t1_idx (t1_id), foreign key (t1_id) references t1 (id))"})
+	defer os.RemoveAll(fromChild)
+	toChild := writeSchemaFile(t, []string{"create table c1 (id int primary key, t1_id int, ts timestamp, key t1_idx (t1_id), foreign key (t1_id) references t1 (id))"})
+	defer os.RemoveAll(toChild)
+
+	gitArgs := func(path string, oldFile string, newFile string) []string {
+		return []string{path, oldFile, "1111111", "100644", newFile, "2222222", "100644"}
+	}
+	tcases := []struct {
+		name        string
+		args        []string
+		expect      string
+		expectError string
+	}{
+		{
+			name:   "table",
+			args:   gitArgs("schema/t1.sql", from, to),
+			expect: "diff --schemadiff a/schema/t1.sql b/schema/t1.sql\n--- a/schema/t1.sql\n+++ b/schema/t1.sql\nALTER TABLE `t1` MODIFY COLUMN `id` int unsigned;\n",
+		},
+		{
+			name:   "identical",
+			args:   gitArgs("schema/t1.sql", from, from),
+			expect: "",
+		},
+		{
+			name:   "added",
+			args:   gitArgs("schema/t1.sql", "/dev/null", from),
+			expect: "diff --schemadiff a/schema/t1.sql b/schema/t1.sql\n--- /dev/null\n+++ b/schema/t1.sql\nCREATE TABLE `t1` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n);\n",
+		},
+		{
+			name:   "deleted",
+			args:   gitArgs("schema/t1.sql", from, "/dev/null"),
+			expect: "diff --schemadiff a/schema/t1.sql b/schema/t1.sql\n--- a/schema/t1.sql\n+++ /dev/null\nDROP TABLE `t1`;\n",
+		},
+		{
+			name:   "foreign key to other file",
+			args:   gitArgs("schema/c1.sql", fromChild, toChild),
+			expect: "diff --schemadiff a/schema/c1.sql b/schema/c1.sql\n--- a/schema/c1.sql\n+++ b/schema/c1.sql\nALTER TABLE `c1` ADD COLUMN `ts` timestamp;\n",
+		},
+		{
+			name:   "schema",
+			args:   gitArgs("schema.sql", fromSchema, toSchema),
+			expect: "diff --schemadiff a/schema.sql b/schema.sql\n--- a/schema.sql\n+++ b/schema.sql\n" + sqlsToMultiStatementText(diffsFromTo),
+		},
+		{
+			name:   "unmerged",
+			args:   []string{"schema/t1.sql"},
+			expect: "* Unmerged path schema/t1.sql\n",
+		},
+		{
+			name:        "missing arguments",
+			args:        []string{"schema/t1.sql", from, to},
+			expectError: "git-diff-driver expects 7 arguments",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			output, err := Exec(ctx, "git-diff-driver", "", "", &ExecOptions{Args: tcase.args})
+			if tcase.expectError != "" {
+				assert.ErrorContains(t, err, tcase.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tcase.expect, output)
+		})
+	}
+	t.Run("unexpected arguments", func(t *testing.T) {
+		_, err := Exec(ctx, "diff", from, to, &ExecOptions{Args: []string{"extra"}})
+		assert.ErrorContains(t, err, "unexpected arguments")
+	})
+}
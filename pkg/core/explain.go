@@ -0,0 +1,330 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+// OrderDependency is a dependency between two diffs, which constrains the order in which they apply.
+type OrderDependency struct {
+	// Diff and DependentDiff are indexes into OrderExplanation.Diffs. If the diffs are ordered, Diff applies first.
+	Diff          int
+	DependentDiff int
+	// Reason describes the relation between the entities of the diffs, e.g. "v1 selects from t1"
+	Reason string
+	Type   schemadiff.DiffDependencyType
+}
+
+// OrderExplanation explains the order of the diffs between two schemas: the dependencies between diffs, and the
+// layers of diffs which only depend on diffs of earlier layers. If the diffs cannot be ordered, it holds the
+// reason, and a cycle of diffs which must each apply before the next one.
+type OrderExplanation struct {
+	// Diffs are in a valid order, or, if ordering is impossible, in no particular order
+	Diffs        []schemadiff.EntityDiff
+	Dependencies []*OrderDependency
+	// Layers are indexes into Diffs. The diffs of a layer do not depend on each other, and may apply together.
+	Layers [][]int
+	// Error is the reason the diffs cannot be ordered. Empty if they can.
+	Error string
+	// Cycle are indexes into Diffs, each of which must apply before the next one, and the last before the first
+	Cycle []int
+}
+
+// dependencyTypeName describes the type of a dependency
+func dependencyTypeName(typ schemadiff.DiffDependencyType) string {
+	switch typ {
+	case schemadiff.DiffDependencyInOrderCompletion:
+		return "in-order completion"
+	case schemadiff.DiffDependencySequentialExecution:
+		return "sequential execution"
+	}
+	return "no dependency"
+}
+
+// entityReferences returns the names of the entities which the entities of a diff reference, before and after
+// the diff: the parent tables of a table's foreign keys, or the tables and views a view selects from.
+func entityReferences(d schemadiff.EntityDiff) map[string]bool {
+	references := map[string]bool{}
+	from, to := d.Entities()
+	for _, e := range []schemadiff.Entity{from, to} {
+		var relations []*ERDRelation
+		switch e := e.(type) {
+		case *schemadiff.CreateTableEntity:
+			if e != nil {
+				_, relations = tableERD(e)
+			}
+		case *schemadiff.CreateViewEntity:
+			if e != nil {
+				_, relations = viewERD(e)
+			}
+		}
+		for _, relation := range relations {
+			references[relation.To] = true
+		}
+	}
+	return references
+}
+
+// isViewDiff returns whether the diff is of a view
+func isViewDiff(d schemadiff.EntityDiff) bool {
+	from, to := d.Entities()
+	for _, e := range []schemadiff.Entity{from, to} {
+		if view, ok := e.(*schemadiff.CreateViewEntity); ok && view != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyReason describes why two diffs depend on each other: one is a subsequent diff of the other, a view
+// selects from the other's entity, or a table references the other's table with a foreign key.
+func dependencyReason(a schemadiff.EntityDiff, b schemadiff.EntityDiff) string {
+	if a.EntityName() == b.EntityName() {
+		return fmt.Sprintf("subsequent diff of %s", a.EntityName())
+	}
+	for _, pair := range [][2]schemadiff.EntityDiff{{b, a}, {a, b}} {
+		referencing, referenced := pair[0], pair[1]
+		if entityReferences(referencing)[referenced.EntityName()] {
+			if isViewDiff(referencing) {
+				return fmt.Sprintf("%s selects from %s", referencing.EntityName(), referenced.EntityName())
+			}
+			return fmt.Sprintf("%s references %s with a foreign key", referencing.EntityName(), referenced.EntityName())
+		}
+	}
+	return fmt.Sprintf("%s depends on %s", b.EntityName(), a.EntityName())
+}
+
+// applyInOrder returns whether the diffs apply onto the schema one after the other, with each intermediate
+// schema valid.
+func applyInOrder(schema *schemadiff.Schema, diffs ...schemadiff.EntityDiff) bool {
+	for _, d := range diffs {
+		var err error
+		if schema, err = schema.Apply([]schemadiff.EntityDiff{d}); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ExplainOrder explains the order of the diffs between the source schema and the target schema of diff.
+func ExplainOrder(ctx context.Context, source *schemadiff.Schema, diff *schemadiff.SchemaDiff) (*OrderExplanation, error) {
+	explanation := &OrderExplanation{}
+	ordered, err := diff.OrderedDiffs(ctx)
+	var impossibleOrderErr *schemadiff.ImpossibleApplyDiffOrderError
+	switch {
+	case err == nil:
+		explanation.Diffs = ordered
+	case errors.As(err, &impossibleOrderErr):
+		explanation.Diffs = diff.UnorderedDiffs()
+		explanation.Error = err.Error()
+	default:
+		return nil, err
+	}
+	positions := map[schemadiff.EntityDiff]int{}
+	for i, d := range explanation.Diffs {
+		positions[d] = i
+	}
+
+	seen := map[[2]int]bool{}
+	for _, dep := range diff.AllDependenciess() {
+		a, aOK := positions[dep.Diff()]
+		b, bOK := positions[dep.DependentDiff()]
+		if !aOK || !bOK || a == b {
+			continue
+		}
+		if explanation.Error == "" && a > b {
+			// Show the dependency in the order the diffs apply
+			a, b = b, a
+		}
+		if seen[[2]int{a, b}] || seen[[2]int{b, a}] {
+			continue
+		}
+		seen[[2]int{a, b}] = true
+		explanation.Dependencies = append(explanation.Dependencies, &OrderDependency{
+			Diff:          a,
+			DependentDiff: b,
+			Reason:        dependencyReason(explanation.Diffs[a], explanation.Diffs[b]),
+			Type:          dep.Type(),
+		})
+	}
+	sort.SliceStable(explanation.Dependencies, func(i, j int) bool {
+		if explanation.Dependencies[i].Diff != explanation.Dependencies[j].Diff {
+			return explanation.Dependencies[i].Diff < explanation.Dependencies[j].Diff
+		}
+		return explanation.Dependencies[i].DependentDiff < explanation.Dependencies[j].DependentDiff
+	})
+
+	if explanation.Error != "" {
+		var conflicting []int
+		for _, d := range impossibleOrderErr.ConflictingDiffs {
+			if i, ok := positions[d]; ok {
+				conflicting = append(conflicting, i)
+			}
+		}
+		if explanation.Cycle, err = explanation.findCycle(ctx, source, conflicting); err != nil {
+			return nil, err
+		}
+		return explanation, nil
+	}
+
+	// A diff's layer is one past the highest layer of the diffs it depends on
+	layerOf := make([]int, len(explanation.Diffs))
+	for _, dep := range explanation.Dependencies {
+		// Dependencies are sorted by Diff, which applies before DependentDiff, so that layerOf[dep.Diff] is final
+		layerOf[dep.DependentDiff] = max(layerOf[dep.DependentDiff], layerOf[dep.Diff]+1)
+	}
+	for i, layer := range layerOf {
+		for len(explanation.Layers) <= layer {
+			explanation.Layers = append(explanation.Layers, nil)
+		}
+		explanation.Layers[layer] = append(explanation.Layers[layer], i)
+	}
+	return explanation, nil
+}
+
+// applyOthers returns the source schema with the diffs other than the excluded ones applied, as far as they apply
+// in any order. Diffs which cannot apply without the excluded ones are left out.
+func (e *OrderExplanation) applyOthers(ctx context.Context, source *schemadiff.Schema, excluded ...int) (*schemadiff.Schema, error) {
+	done := map[int]bool{}
+	for _, i := range excluded {
+		done[i] = true
+	}
+	schema := source
+	for progress := true; progress; {
+		progress = false
+		for i, d := range e.Diffs {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if done[i] {
+				continue
+			}
+			if applied, err := schema.Apply([]schemadiff.EntityDiff{d}); err == nil {
+				schema = applied
+				done[i] = true
+				progress = true
+			}
+		}
+	}
+	return schema, nil
+}
+
+// findCycle returns the shortest cycle of the given conflicting diffs, each of which must apply before the next
+// one. Diff a must apply before diff b, if the two depend on each other, and b cannot apply before a. The order
+// of a and b is checked with the other diffs applied as far as they apply, so that two diffs which both depend on
+// a third diff do not appear to conflict. Two diffs which cannot apply in either order are a cycle only if they
+// apply together, as otherwise they may as well wait for a third diff, which cannot apply before them. The returned
+// cycle is nil if no such cycle is found.
+func (e *OrderExplanation) findCycle(ctx context.Context, source *schemadiff.Schema, conflicting []int) ([]int, error) {
+	isConflicting := map[int]bool{}
+	for _, i := range conflicting {
+		isConflicting[i] = true
+	}
+	mustPrecede := map[int][]int{}
+	for _, dep := range e.Dependencies {
+		a, b := dep.Diff, dep.DependentDiff
+		if !isConflicting[a] || !isConflicting[b] {
+			continue
+		}
+		schema, err := e.applyOthers(ctx, source, a, b)
+		if err != nil {
+			return nil, err
+		}
+		abValid := applyInOrder(schema, e.Diffs[a], e.Diffs[b])
+		baValid := applyInOrder(schema, e.Diffs[b], e.Diffs[a])
+		if !abValid && !baValid {
+			if _, err := schema.Apply([]schemadiff.EntityDiff{e.Diffs[a], e.Diffs[b]}); err == nil {
+				// Neither diff can apply before the other: the shortest possible cycle
+				return []int{a, b}, nil
+			}
+		}
+		if abValid && !baValid {
+			mustPrecede[a] = append(mustPrecede[a], b)
+		}
+		if baValid && !abValid {
+			mustPrecede[b] = append(mustPrecede[b], a)
+		}
+	}
+	var shortest []int
+	for _, start := range conflicting {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Breadth first search for the shortest path from start back to itself
+		previous := map[int]int{}
+		queue := []int{start}
+	search:
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			for _, next := range mustPrecede[node] {
+				if next == start {
+					cycle := []int{node}
+					for cycle[0] != start {
+						cycle = append([]int{previous[cycle[0]]}, cycle...)
+					}
+					if shortest == nil || len(cycle) < len(shortest) {
+						shortest = cycle
+					}
+					break search
+				}
+				if _, ok := previous[next]; !ok {
+					previous[next] = node
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return shortest, nil
+}
+
+// diffLabel describes the diff at index i, e.g. "#2 alter table t1"
+func (e *OrderExplanation) diffLabel(i int) string {
+	summary := SummarizeDiff(e.Diffs[i])
+	return fmt.Sprintf("#%d %s %s", i+1, summary.Change, summary.Entity)
+}
+
+func (e *OrderExplanation) String() string {
+	var b strings.Builder
+	for i := range e.Diffs {
+		fmt.Fprintf(&b, "%s\n", e.diffLabel(i))
+	}
+	arrow := "->"
+	if e.Error != "" {
+		arrow = "--"
+	}
+	if len(e.Dependencies) > 0 {
+		b.WriteString("\ndependencies:\n")
+		for _, dep := range e.Dependencies {
+			fmt.Fprintf(&b, "  %s %s %s: %s (%s)\n", e.diffLabel(dep.Diff), arrow, e.diffLabel(dep.DependentDiff), dep.Reason, dependencyTypeName(dep.Type))
+		}
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, "\nimpossible order: %s\n", e.Error)
+		if len(e.Cycle) == 0 {
+			b.WriteString("no cycle found between pairs of dependent diffs\n")
+			return b.String()
+		}
+		b.WriteString("cycle, where each diff must apply before the next one:\n")
+		for _, i := range append(e.Cycle, e.Cycle[0]) {
+			fmt.Fprintf(&b, "  %s\n", e.diffLabel(i))
+		}
+		return b.String()
+	}
+	if len(e.Layers) > 0 {
+		b.WriteString("\nlayers:\n")
+		for i, layer := range e.Layers {
+			var labels []string
+			for _, d := range layer {
+				labels = append(labels, e.diffLabel(d))
+			}
+			fmt.Fprintf(&b, "  %d: %s\n", i+1, strings.Join(labels, ", "))
+		}
+	}
+	return b.String()
+}
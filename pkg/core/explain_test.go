@@ -0,0 +1,237 @@
+package core
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// fakeEntityDiff is a diff of the given entity with the given statement, implementing what SummarizeDiff and Plan use
+type fakeEntityDiff struct {
+	schemadiff.EntityDiff
+	name      string
+	statement sqlparser.Statement
+	canonical string
+}
+
+func (d *fakeEntityDiff) EntityName() string                    { return d.name }
+func (d *fakeEntityDiff) Statement() sqlparser.Statement        { return d.statement }
+func (d *fakeEntityDiff) CanonicalStatementString() string      { return d.canonical }
+func (d *fakeEntityDiff) SubsequentDiff() schemadiff.EntityDiff { return nil }
+func (d *fakeEntityDiff) InstantDDLCapability() schemadiff.InstantDDLCapability {
+	return schemadiff.InstantDDLCapabilityImpossible
+}
+
+func TestOrderExplanationString(t *testing.T) {
+	diffs := []schemadiff.EntityDiff{
+		&fakeEntityDiff{name: "t1", statement: &sqlparser.CreateTable{}},
+		&fakeEntityDiff{name: "t2", statement: &sqlparser.AlterTable{}},
+		&fakeEntityDiff{name: "v1", statement: &sqlparser.CreateView{}},
+	}
+	t.Run("ordered", func(t *testing.T) {
+		e := &OrderExplanation{
+			Diffs: diffs,
+			Dependencies: []*OrderDependency{
+				{Diff: 0, DependentDiff: 2, Reason: "v1 selects from t1", Type: schemadiff.DiffDependencyInOrderCompletion},
+			},
+			Layers: [][]int{{0, 1}, {2}},
+		}
+		expect := "#1 create table t1\n#2 alter table t2\n#3 create view v1\n" +
+			"\ndependencies:\n  #1 create table t1 -> #3 create view v1: v1 selects from t1 (in-order completion)\n" +
+			"\nlayers:\n  1: #1 create table t1, #2 alter table t2\n  2: #3 create view v1\n"
+		assert.Equal(t, expect, e.String())
+	})
+	t.Run("impossible", func(t *testing.T) {
+		e := &OrderExplanation{
+			Diffs: diffs[1:],
+			Dependencies: []*OrderDependency{
+				{Diff: 0, DependentDiff: 1, Reason: "v1 selects from t2", Type: schemadiff.DiffDependencySequentialExecution},
+			},
+			Error: "no valid applicable order for diffs",
+			Cycle: []int{1, 0},
+		}
+		expect := "#1 alter table t2\n#2 create view v1\n" +
+			"\ndependencies:\n  #1 alter table t2 -- #2 create view v1: v1 selects from t2 (sequential execution)\n" +
+			"\nimpossible order: no valid applicable order for diffs\n" +
+			"cycle, where each diff must apply before the next one:\n  #2 create view v1\n  #1 alter table t2\n  #2 create view v1\n"
+		assert.Equal(t, expect, e.String())
+
+		e.Cycle = nil
+		assert.Contains(t, e.String(), "\nimpossible order: no valid applicable order for diffs\nno cycle found between pairs of dependent diffs\n")
+	})
+	t.Run("empty", func(t *testing.T) {
+		assert.Equal(t, "", (&OrderExplanation{}).String())
+	})
+}
+
+func TestExplainOrder(t *testing.T) {
+	ctx := context.Background()
+	env := schemadiff.NewTestEnv()
+	explain := func(t *testing.T, fromQueries []string, toQueries []string) *OrderExplanation {
+		from, err := schemadiff.NewSchemaFromQueries(env, fromQueries)
+		require.NoError(t, err)
+		to, err := schemadiff.NewSchemaFromQueries(env, toQueries)
+		require.NoError(t, err)
+		diff, err := from.SchemaDiff(to, DefaultDiffHints())
+		require.NoError(t, err)
+		explanation, err := ExplainOrder(ctx, from, diff)
+		require.NoError(t, err)
+		return explanation
+	}
+
+	t.Run("ordered", func(t *testing.T) {
+		e := explain(t,
+			[]string{"create table t1 (id int primary key)"},
+			[]string{
+				"create table t1 (id int primary key, name varchar(12))",
+				"create table t2 (id int primary key)",
+				"create table t3 (id int primary key, t2_id int, constraint t3_t2_fk foreign key (t2_id) references t2 (id))",
+				"create view v1 as select t2.id, t3.t2_id from t2 join t3 on t2.id = t3.t2_id",
+			},
+		)
+		require.Empty(t, e.Error)
+		require.Len(t, e.Diffs, 4)
+		reasons := map[string]bool{}
+		for _, dep := range e.Dependencies {
+			assert.Less(t, dep.Diff, dep.DependentDiff)
+			reasons[dep.Reason] = true
+		}
+		assert.True(t, reasons["t3 references t2 with a foreign key"], e.String())
+		assert.True(t, reasons["v1 selects from t2"], e.String())
+		assert.True(t, reasons["v1 selects from t3"], e.String())
+
+		layers := map[string]int{}
+		for i, layer := range e.Layers {
+			for _, d := range layer {
+				layers[e.Diffs[d].EntityName()] = i + 1
+			}
+		}
+		assert.Equal(t, map[string]int{"t1": 1, "t2": 1, "t3": 2, "v1": 3}, layers)
+	})
+	t.Run("impossible", func(t *testing.T) {
+		e := explain(t,
+			[]string{
+				"create table t (id int primary key, c1 int)",
+				"create view v as select id, c1 from t",
+			},
+			[]string{
+				"create table t (id int primary key, c2 int)",
+				"create view v as select id, c2 from t",
+			},
+		)
+		require.NotEmpty(t, e.Error)
+		require.Len(t, e.Cycle, 2, e.String())
+		var cycleEntities []string
+		for _, d := range e.Cycle {
+			cycleEntities = append(cycleEntities, e.Diffs[d].EntityName())
+		}
+		assert.ElementsMatch(t, []string{"t", "v"}, cycleEntities)
+		assert.Empty(t, e.Layers)
+	})
+	t.Run("impossible, with a chain of three diffs", func(t *testing.T) {
+		// w1 and w2 both depend on the new table p, and w2 depends on w1. Neither applies before the other onto the
+		// source schema, which has no p, but they are no cycle.
+		e := explain(t,
+			[]string{
+				"create table t (id int primary key, c1 int)",
+				"create view v as select id, c1 from t",
+			},
+			[]string{
+				"create table t (id int primary key, c2 int)",
+				"create view v as select id, c2 from t",
+				"create table p (id int primary key)",
+				"create view w1 as select p.id from p join t on p.id = t.id",
+				"create view w2 as select w1.id from w1 join p on w1.id = p.id",
+			},
+		)
+		require.NotEmpty(t, e.Error)
+		require.Len(t, e.Cycle, 2, e.String())
+		var cycleEntities []string
+		for _, d := range e.Cycle {
+			cycleEntities = append(cycleEntities, e.Diffs[d].EntityName())
+		}
+		assert.ElementsMatch(t, []string{"t", "v"}, cycleEntities)
+	})
+	t.Run("impossible, with a third diff blocking a pair", func(t *testing.T) {
+		// x selects from both t and v, and so waits for t, which waits for v. Neither x nor v applies before the other
+		// until t applies, but only t and v are a cycle.
+		from, err := schemadiff.NewSchemaFromQueries(env, []string{
+			"create table t (id int primary key, c1 int)",
+			"create view v as select id, c1 from t",
+		})
+		require.NoError(t, err)
+		to, err := schemadiff.NewSchemaFromQueries(env, []string{
+			"create table t (id int primary key, c2 int)",
+			"create view v as select id, c2 from t",
+			"create view x as select t.c2, v.id from t join v on t.id = v.id",
+		})
+		require.NoError(t, err)
+		diff, err := from.SchemaDiff(to, DefaultDiffHints())
+		require.NoError(t, err)
+		e := &OrderExplanation{Diffs: diff.UnorderedDiffs()}
+		require.Len(t, e.Diffs, 3)
+		indexes := map[string]int{}
+		for i, d := range e.Diffs {
+			indexes[d.EntityName()] = i
+		}
+		// The pair of x and v is checked first
+		e.Dependencies = []*OrderDependency{
+			{Diff: indexes["x"], DependentDiff: indexes["v"]},
+			{Diff: indexes["t"], DependentDiff: indexes["x"]},
+			{Diff: indexes["t"], DependentDiff: indexes["v"]},
+		}
+		cycle, err := e.findCycle(ctx, from, []int{0, 1, 2})
+		require.NoError(t, err)
+		var cycleEntities []string
+		for _, d := range cycle {
+			cycleEntities = append(cycleEntities, e.Diffs[d].EntityName())
+		}
+		assert.ElementsMatch(t, []string{"t", "v"}, cycleEntities)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = e.findCycle(canceled, from, []int{0, 1, 2})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestExecExplainOrder(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, []string{"create table t1 (id int primary key)"})
+	defer os.RemoveAll(fileFrom)
+	fileTo := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key)",
+		"create table t2 (id int primary key)",
+		"create view v1 as select id from t2",
+	})
+	defer os.RemoveAll(fileTo)
+
+	output, err := Exec(ctx, "explain-order", fileFrom, fileTo, nil)
+	require.NoError(t, err)
+	expect := "#1 create table t2\n#2 create view v1\n" +
+		"\ndependencies:\n  #1 create table t2 -> #2 create view v1: v1 selects from t2 (in-order completion)\n" +
+		"\nlayers:\n  1: #1 create table t2\n  2: #2 create view v1\n"
+	assert.Equal(t, expect, output)
+
+	fileCycle := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, c2 int)",
+		"create view v1 as select id, c2 from t1",
+	})
+	defer os.RemoveAll(fileCycle)
+	fileCycleFrom := writeSchemaFile(t, []string{
+		"create table t1 (id int primary key, c1 int)",
+		"create view v1 as select id, c1 from t1",
+	})
+	defer os.RemoveAll(fileCycleFrom)
+	output, err = Exec(ctx, "explain-order", fileCycleFrom, fileCycle, nil)
+	assert.ErrorIs(t, err, ErrImpossibleOrder)
+	assert.Contains(t, output, "cycle, where each diff must apply before the next one:\n")
+
+	_, err = Exec(ctx, "explain-order", fileFrom, fileTo, &ExecOptions{Mapping: []string{"t1=t9"}})
+	assert.ErrorContains(t, err, "not supported by explain-order")
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMySQL is a minimal in-process MySQL protocol server, enough for the go-sql-driver/mysql client to read the
+// schema of its single database, and to execute statements on it. Tables and views are given by their CREATE
+// statements. Statements other than SELECT and SHOW are recorded, and handed to the exec function, which may
+// change the tables and views, or fail the statement.
+type fakeMySQL struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	entities map[string]string // CREATE statement by entity name
+	executed []string
+	exec     func(f *fakeMySQL, query string) error
+}
+
+const fakeMySQLVersion = "8.0.35-fake"
+
+// newFakeMySQL starts a fake MySQL server with the given CREATE statements, by entity name. The server stops
+// when the test ends.
+func newFakeMySQL(t *testing.T, entities map[string]string) *fakeMySQL {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeMySQL{listener: listener, entities: map[string]string{}}
+	for name, statement := range entities {
+		f.entities[name] = statement
+	}
+	go func() {
+		for connectionID := uint32(1); ; connectionID++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(&fakeMySQLConn{Conn: conn}, connectionID)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+// DSN returns the DSN of the server's database.
+func (f *fakeMySQL) DSN() string {
+	return fmt.Sprintf("root@tcp(%s)/test", f.listener.Addr())
+}
+
+// SetEntity sets the CREATE statement of the given entity. An empty statement drops the entity.
+func (f *fakeMySQL) SetEntity(name string, statement string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if statement == "" {
+		delete(f.entities, name)
+		return
+	}
+	f.entities[name] = statement
+}
+
+// Executed returns the statements executed so far, other than SELECT and SHOW, in order.
+func (f *fakeMySQL) Executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.executed...)
+}
+
+func isViewStatement(statement string) bool {
+	head, _, _ := strings.Cut(strings.ToLower(statement), " as ")
+	return strings.Contains(head, " view ")
+}
+
+// fakeMySQLConn reads and writes MySQL protocol packets, tracking their sequence ID.
+type fakeMySQLConn struct {
+	net.Conn
+	sequenceID byte
+}
+
+func (c *fakeMySQLConn) readPacket() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c, header[:]); err != nil {
+		return nil, err
+	}
+	c.sequenceID = header[3] + 1
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err := io.ReadFull(c, payload)
+	return payload, err
+}
+
+func (c *fakeMySQLConn) writePacket(payload []byte) error {
+	length := len(payload)
+	packet := append([]byte{byte(length), byte(length >> 8), byte(length >> 16), c.sequenceID}, payload...)
+	c.sequenceID++
+	_, err := c.Write(packet)
+	return err
+}
+
+func appendLengthEncodedString(b []byte, s string) []byte {
+	switch length := len(s); {
+	case length < 251:
+		b = append(b, byte(length))
+	case length < 1<<16:
+		b = append(b, 0xfc, byte(length), byte(length>>8))
+	default:
+		b = append(b, 0xfd, byte(length), byte(length>>8), byte(length>>16))
+	}
+	return append(b, s...)
+}
+
+const (
+	fakeMySQLCapabilities = 0x1 | // CLIENT_LONG_PASSWORD
+		0x8 | // CLIENT_CONNECT_WITH_DB
+		0x200 | // CLIENT_PROTOCOL_41
+		0x2000 | // CLIENT_TRANSACTIONS
+		0x8000 | // CLIENT_SECURE_CONNECTION
+		0x80000 // CLIENT_PLUGIN_AUTH
+	fakeMySQLStatusAutocommit = 0x2
+)
+
+func (c *fakeMySQLConn) writeOK() error {
+	return c.writePacket([]byte{0x00, 0, 0, fakeMySQLStatusAutocommit, 0, 0, 0})
+}
+
+func (c *fakeMySQLConn) writeError(code uint16, message string) error {
+	payload := []byte{0xff, byte(code), byte(code >> 8)}
+	payload = append(payload, "#HY000"...)
+	return c.writePacket(append(payload, message...))
+}
+
+func (c *fakeMySQLConn) writeEOF() error {
+	return c.writePacket([]byte{0xfe, 0, 0, fakeMySQLStatusAutocommit, 0})
+}
+
+// writeResultSet writes a text protocol result set, where all columns are strings.
+func (c *fakeMySQLConn) writeResultSet(columns []string, rows [][]string) error {
+	if err := c.writePacket([]byte{byte(len(columns))}); err != nil {
+		return err
+	}
+	for _, column := range columns {
+		var payload []byte
+		for _, s := range []string{"def", "test", "", "", column, column} {
+			payload = appendLengthEncodedString(payload, s)
+		}
+		payload = append(payload, 0x0c, 0x21, 0x00) // length of fixed fields, utf8_general_ci
+		payload = binary.LittleEndian.AppendUint32(payload, 1024)
+		payload = append(payload, 0xfd, 0, 0, 0, 0, 0) // VAR_STRING, flags, decimals, filler
+		if err := c.writePacket(payload); err != nil {
+			return err
+		}
+	}
+	if err := c.writeEOF(); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var payload []byte
+		for _, value := range row {
+			payload = appendLengthEncodedString(payload, value)
+		}
+		if err := c.writePacket(payload); err != nil {
+			return err
+		}
+	}
+	return c.writeEOF()
+}
+
+func (f *fakeMySQL) handshake(c *fakeMySQLConn, connectionID uint32) error {
+	scramble := []byte("0123456789abcdefghij")
+	payload := []byte{10}
+	payload = append(payload, fakeMySQLVersion+"\x00"...)
+	payload = binary.LittleEndian.AppendUint32(payload, connectionID)
+	payload = append(payload, scramble[:8]...)
+	payload = append(payload, 0)
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(fakeMySQLCapabilities&0xffff))
+	payload = append(payload, 0xff) // utf8mb4_0900_ai_ci
+	payload = binary.LittleEndian.AppendUint16(payload, fakeMySQLStatusAutocommit)
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(fakeMySQLCapabilities>>16))
+	payload = append(payload, byte(len(scramble)+1))
+	payload = append(payload, make([]byte, 10)...)
+	payload = append(payload, scramble[8:]...)
+	payload = append(payload, 0)
+	payload = append(payload, "mysql_native_password\x00"...)
+	if err := c.writePacket(payload); err != nil {
+		return err
+	}
+	// Any user and password are accepted
+	if _, err := c.readPacket(); err != nil {
+		return err
+	}
+	return c.writeOK()
+}
+
+func (f *fakeMySQL) serve(c *fakeMySQLConn, connectionID uint32) {
+	defer c.Close()
+	if err := f.handshake(c, connectionID); err != nil {
+		return
+	}
+	for {
+		packet, err := c.readPacket()
+		if err != nil || len(packet) == 0 {
+			return
+		}
+		switch packet[0] {
+		case 0x01: // COM_QUIT
+			return
+		case 0x03: // COM_QUERY
+			err = f.query(c, string(packet[1:]))
+		case 0x0e: // COM_PING
+			err = c.writeOK()
+		default:
+			err = c.writeError(1047, fmt.Sprintf("unsupported command %d", packet[0]))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeMySQL) query(c *fakeMySQLConn, query string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	upperQuery := strings.ToUpper(query)
+	switch {
+	case upperQuery == "SELECT @@VERSION":
+		return c.writeResultSet([]string{"@@version"}, [][]string{{fakeMySQLVersion}})
+	case strings.HasPrefix(upperQuery, "SELECT TABLE_NAME, TABLE_TYPE FROM INFORMATION_SCHEMA.TABLES"):
+		var rows [][]string
+		for name, statement := range f.entities {
+			tableType := "BASE TABLE"
+			if isViewStatement(statement) {
+				tableType = "VIEW"
+			}
+			rows = append(rows, []string{name, tableType})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+		return c.writeResultSet([]string{"TABLE_NAME", "TABLE_TYPE"}, rows)
+	case strings.HasPrefix(upperQuery, "SHOW CREATE TABLE "), strings.HasPrefix(upperQuery, "SHOW CREATE VIEW "):
+		fields := strings.Fields(query)
+		name := strings.Trim(fields[len(fields)-1], "`")
+		statement, ok := f.entities[name]
+		if !ok {
+			return c.writeError(1146, fmt.Sprintf("Table 'test.%s' doesn't exist", name))
+		}
+		if isViewStatement(statement) {
+			return c.writeResultSet(
+				[]string{"View", "Create View", "character_set_client", "collation_connection"},
+				[][]string{{name, statement, "utf8mb4", "utf8mb4_0900_ai_ci"}},
+			)
+		}
+		return c.writeResultSet([]string{"Table", "Create Table"}, [][]string{{name, statement}})
+	case strings.HasPrefix(upperQuery, "SELECT "), strings.HasPrefix(upperQuery, "SHOW "):
+		return c.writeError(1064, "unsupported query: "+query)
+	}
+	f.executed = append(f.executed, query)
+	if f.exec != nil && !strings.HasPrefix(upperQuery, "SET ") {
+		f.mu.Unlock()
+		err := f.exec(f, query)
+		f.mu.Lock()
+		if err != nil {
+			var mysqlErr *fakeMySQLError
+			if errors.As(err, &mysqlErr) {
+				return c.writeError(mysqlErr.code, mysqlErr.message)
+			}
+			return c.writeError(1105, err.Error())
+		}
+	}
+	return c.writeOK()
+}
+
+// fakeMySQLError is an error the fake server returns with a specific MySQL error code.
+type fakeMySQLError struct {
+	code    uint16
+	message string
+}
+
+func (e *fakeMySQLError) Error() string {
+	return e.message
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// Fingerprint is a stable hash of a schema, and of each of its entities. Two schemas that only differ in view
+// DEFINERs, table AUTO_INCREMENT values or formatting have the same fingerprint.
+type Fingerprint struct {
+	// Hash is the hex encoded SHA-256 hash of the whole schema
+	Hash     string
+	Entities []*EntityFingerprint
+}
+
+// EntityFingerprint is the stable hash of a single entity.
+type EntityFingerprint struct {
+	// Kind is "table" or "view"
+	Kind string
+	Name string
+	// Hash is the hex encoded SHA-256 hash of the entity's normalized CREATE statement
+	Hash string
+}
+
+func (f *EntityFingerprint) String() string {
+	return fmt.Sprintf("%s %s %s", f.Kind, f.Name, f.Hash)
+}
+
+func (f *Fingerprint) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema %s\n", f.Hash)
+	for _, e := range f.Entities {
+		fmt.Fprintf(&b, "%s\n", e)
+	}
+	return b.String()
+}
+
+// fingerprintStatement returns the kind of the entity, and its canonical CREATE statement, without a view's
+// DEFINER nor a table's AUTO_INCREMENT value. The canonical form normalizes whitespace, case and quoting.
+func fingerprintStatement(e schemadiff.Entity) (kind string, statement string) {
+	switch stmt := e.Create().Statement().(type) {
+	case *sqlparser.CreateTable:
+		return "table", sqlparser.CanonicalString(withoutAutoIncrement(stmt))
+	case *sqlparser.CreateView:
+		stmt = sqlparser.CloneRefOfCreateView(stmt)
+		stmt.Definer = nil
+		return "view", sqlparser.CanonicalString(stmt)
+	}
+	return "entity", e.Create().CanonicalStatementString()
+}
+
+// withoutAutoIncrement returns a copy of the given CREATE TABLE statement, without its AUTO_INCREMENT table option.
+func withoutAutoIncrement(stmt *sqlparser.CreateTable) *sqlparser.CreateTable {
+	stmt = sqlparser.CloneRefOfCreateTable(stmt)
+	if stmt.TableSpec != nil {
+		var options sqlparser.TableOptions
+		for _, option := range stmt.TableSpec.Options {
+			if !strings.EqualFold(option.Name, "AUTO_INCREMENT") {
+				options = append(options, option)
+			}
+		}
+		stmt.TableSpec.Options = options
+	}
+	return stmt
+}
+
+func fingerprintHash(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+// FingerprintEntities returns the fingerprint of the given entities, which are typically those of a loaded,
+// and hence normalized, schema. The entities are listed by kind, then name, and the schema hash covers the kind,
+// name and hash of each, so that it does not depend on the order of the given entities.
+func FingerprintEntities(entities []schemadiff.Entity) *Fingerprint {
+	fingerprint := &Fingerprint{}
+	for _, e := range entities {
+		kind, statement := fingerprintStatement(e)
+		fingerprint.Entities = append(fingerprint.Entities, &EntityFingerprint{
+			Kind: kind,
+			Name: e.Name(),
+			Hash: fingerprintHash(statement),
+		})
+	}
+	sort.SliceStable(fingerprint.Entities, func(i, j int) bool {
+		if fingerprint.Entities[i].Kind != fingerprint.Entities[j].Kind {
+			return fingerprint.Entities[i].Kind < fingerprint.Entities[j].Kind
+		}
+		return fingerprint.Entities[i].Name < fingerprint.Entities[j].Name
+	})
+	var b strings.Builder
+	for _, e := range fingerprint.Entities {
+		fmt.Fprintf(&b, "%s\n", e)
+	}
+	fingerprint.Hash = fingerprintHash(b.String())
+	return fingerprint
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+func TestFingerprintEntities(t *testing.T) {
+	baseQueries := []string{
+		"create table t1 (id int primary key, name varchar(12)) auto_increment=7",
+		"create table t2 (id int primary key)",
+		"create definer=`root`@`localhost` view v1 as select id from t1",
+	}
+	tcases := []struct {
+		name       string
+		queries    []string
+		expectSame bool
+		// expectEntitiesSame lists, for entities t1, t2 and v1 in that order, whether their hash is expected to match baseQueries
+		expectEntitiesSame []bool
+	}{
+		{
+			name:               "identical",
+			queries:            baseQueries,
+			expectSame:         true,
+			expectEntitiesSame: []bool{true, true, true},
+		},
+		{
+			name: "whitespace, case and order",
+			queries: []string{
+				"CREATE DEFINER=`root`@`localhost` VIEW v1 AS\n\tSELECT id FROM t1",
+				"create table t2 (\n  id int,\n  primary key (id)\n)",
+				"CREATE TABLE `t1` (`id` INT PRIMARY KEY,   `name` VARCHAR(12)) AUTO_INCREMENT=7",
+			},
+			expectSame:         true,
+			expectEntitiesSame: []bool{true, true, true},
+		},
+		{
+			name: "auto_increment and definer",
+			queries: []string{
+				"create table t1 (id int primary key, name varchar(12)) auto_increment=12345",
+				"create table t2 (id int primary key)",
+				"create definer=`deployer`@`%` view v1 as select id from t1",
+			},
+			expectSame:         true,
+			expectEntitiesSame: []bool{true, true, true},
+		},
+		{
+			name: "no auto_increment nor definer",
+			queries: []string{
+				"create table t1 (id int primary key, name varchar(12))",
+				"create table t2 (id int primary key)",
+				"create view v1 as select id from t1",
+			},
+			expectSame:         true,
+			expectEntitiesSame: []bool{true, true, true},
+		},
+		{
+			name: "column type",
+			queries: []string{
+				"create table t1 (id int primary key, name varchar(12)) auto_increment=7",
+				"create table t2 (id bigint primary key)",
+				"create definer=`root`@`localhost` view v1 as select id from t1",
+			},
+			expectEntitiesSame: []bool{true, false, true},
+		},
+		{
+			name: "table option",
+			queries: []string{
+				"create table t1 (id int primary key, name varchar(12)) auto_increment=7 comment='users'",
+				"create table t2 (id int primary key)",
+				"create definer=`root`@`localhost` view v1 as select id from t1",
+			},
+			expectEntitiesSame: []bool{false, true, true},
+		},
+		{
+			name: "view security",
+			queries: []string{
+				"create table t1 (id int primary key, name varchar(12)) auto_increment=7",
+				"create table t2 (id int primary key)",
+				"create definer=`root`@`localhost` sql security invoker view v1 as select id from t1",
+			},
+			expectEntitiesSame: []bool{true, true, false},
+		},
+	}
+	env := schemadiff.NewTestEnv()
+	fingerprint := func(t *testing.T, queries []string) *Fingerprint {
+		schema, err := schemadiff.NewSchemaFromQueries(env, queries)
+		require.NoError(t, err)
+		return FingerprintEntities(schema.Entities())
+	}
+	baseFingerprint := fingerprint(t, baseQueries)
+	require.Len(t, baseFingerprint.Entities, 3)
+	for i, expect := range []string{"table t1", "table t2", "view v1"} {
+		assert.Equal(t, expect, baseFingerprint.Entities[i].Kind+" "+baseFingerprint.Entities[i].Name)
+		assert.Len(t, baseFingerprint.Entities[i].Hash, 64)
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			f := fingerprint(t, tcase.queries)
+			assert.Equal(t, tcase.expectSame, f.Hash == baseFingerprint.Hash)
+			require.Len(t, f.Entities, len(tcase.expectEntitiesSame))
+			for i, expectSame := range tcase.expectEntitiesSame {
+				assert.Equal(t, baseFingerprint.Entities[i].Name, f.Entities[i].Name)
+				assert.Equal(t, expectSame, f.Entities[i].Hash == baseFingerprint.Entities[i].Hash, f.Entities[i].Name)
+			}
+		})
+	}
+}
+
+func TestExecFingerprint(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, schemaFrom)
+	defer os.RemoveAll(fileFrom)
+	dirFrom := writeSchemaDir(t, schemaFrom)
+	defer os.RemoveAll(dirFrom)
+	fileTo := writeSchemaFile(t, schemaTo)
+	defer os.RemoveAll(fileTo)
+
+	output, err := Exec(ctx, "fingerprint", fileFrom, "", nil)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	require.Len(t, lines, 1+len(schemaFrom))
+	assert.Regexp(t, "^schema [0-9a-f]{64}$", lines[0])
+	assert.Regexp(t, "^table t1 [0-9a-f]{64}$", lines[1])
+	assert.Regexp(t, "^table t2 [0-9a-f]{64}$", lines[2])
+	assert.Regexp(t, "^view v1 [0-9a-f]{64}$", lines[3])
+
+	dirOutput, err := Exec(ctx, "fingerprint", dirFrom, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, output, dirOutput)
+
+	toOutput, err := Exec(ctx, "fingerprint", fileTo, "", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, lines[0], strings.Split(toOutput, "\n")[0])
+}
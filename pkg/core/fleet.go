@@ -0,0 +1,501 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/sync/errgroup"
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+const (
+	// DefaultFleetConcurrency is the default number of targets read and diffed concurrently in fleet mode
+	DefaultFleetConcurrency = 8
+	// maxExpandedTargets limits the number of targets a single brace pattern may expand to
+	maxExpandedTargets = 100000
+)
+
+// FleetOptions modify the behavior of Runner.DiffFleet. A nil value is valid and implies defaults.
+type FleetOptions struct {
+	// Concurrency is the maximum number of targets read and diffed concurrently. Defaults to DefaultFleetConcurrency.
+	Concurrency int
+	// Ordered, when true, lists the diffs of each group in an order valid to apply
+	Ordered bool
+}
+
+// FleetGroup is a group of targets which have the same diff from the source, or which failed with the same class
+// of error, such as "connect: connection refused".
+type FleetGroup struct {
+	// Targets are the descriptions of the targets, which do not reveal secrets such as passwords
+	Targets []string `json:"targets"`
+	// Drift summarizes how the targets differ from the source, e.g. "missing index t.idx". It is empty for
+	// targets identical to the source.
+	Drift []string `json:"drift,omitempty"`
+	// Diffs are the statements that turn the source into each of the targets
+	Diffs []string `json:"diffs,omitempty"`
+	// Error is the class of the error reading or diffing the targets, if any
+	Error string `json:"error,omitempty"`
+	// Errors are the errors of each of the targets, in the order of Targets, if Error is non empty. A target is
+	// redacted in its error as in Targets, so that a DSN password is not reported.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Identical returns true if the targets of the group are identical to the source.
+func (g *FleetGroup) Identical() bool {
+	return g.Error == "" && len(g.Diffs) == 0
+}
+
+// FleetResult is the result of diffing a source against many targets.
+type FleetResult struct {
+	// Source is the description of the source, which does not reveal secrets
+	Source string `json:"source"`
+	// Groups lists the group of targets identical to the source first, if any, then the other groups by
+	// descending number of targets, then the groups of targets that failed.
+	Groups []*FleetGroup `json:"groups"`
+}
+
+func pluralTargets(count int) string {
+	if count == 1 {
+		return "1 target"
+	}
+	return fmt.Sprintf("%d targets", count)
+}
+
+// Write writes the result as text: a summary line per group, e.g. "14 targets: missing index t.idx", followed by
+// its indented targets and diffs.
+func (r *FleetResult) Write(w io.Writer) error {
+	var b strings.Builder
+	for _, g := range r.Groups {
+		switch {
+		case g.Error != "":
+			fmt.Fprintf(&b, "%s: error: %s\n", pluralTargets(len(g.Targets)), g.Error)
+		case g.Identical():
+			fmt.Fprintf(&b, "%s: identical\n", pluralTargets(len(g.Targets)))
+		default:
+			fmt.Fprintf(&b, "%s: %s\n", pluralTargets(len(g.Targets)), strings.Join(g.Drift, ", "))
+		}
+		for i, target := range g.Targets {
+			if i < len(g.Errors) {
+				fmt.Fprintf(&b, "  %s: %s\n", target, g.Errors[i])
+				continue
+			}
+			fmt.Fprintf(&b, "  %s\n", target)
+		}
+		if len(g.Diffs) > 0 {
+			b.WriteString("  diff:\n")
+			for _, d := range g.Diffs {
+				fmt.Fprintf(&b, "    %s;\n", strings.ReplaceAll(d, "\n", "\n    "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the result as an indented JSON object.
+func (r *FleetResult) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// driftSummary describes how a target differs from the source, given one diff from the source to the target.
+// Each description is from the target's point of view: an ADD COLUMN diff means the target has an extra column.
+func driftSummary(diff schemadiff.EntityDiff) (drift []string) {
+	name := diff.EntityName()
+	switch stmt := diff.Statement().(type) {
+	case *sqlparser.CreateTable:
+		drift = append(drift, fmt.Sprintf("extra table %s", name))
+	case *sqlparser.DropTable:
+		drift = append(drift, fmt.Sprintf("missing table %s", name))
+	case *sqlparser.RenameTable:
+		drift = append(drift, fmt.Sprintf("renamed table %s", name))
+	case *sqlparser.CreateView:
+		drift = append(drift, fmt.Sprintf("extra view %s", name))
+	case *sqlparser.DropView:
+		drift = append(drift, fmt.Sprintf("missing view %s", name))
+	case *sqlparser.AlterView:
+		drift = append(drift, fmt.Sprintf("different view %s", name))
+	case *sqlparser.AlterTable:
+		for _, opt := range stmt.AlterOptions {
+			switch opt := opt.(type) {
+			case *sqlparser.AddColumns:
+				for _, col := range opt.Columns {
+					drift = append(drift, fmt.Sprintf("extra column %s.%s", name, col.Name.String()))
+				}
+			case *sqlparser.DropColumn:
+				drift = append(drift, fmt.Sprintf("missing column %s.%s", name, opt.Name.Name.String()))
+			case *sqlparser.ModifyColumn:
+				drift = append(drift, fmt.Sprintf("different column %s.%s", name, opt.NewColDefinition.Name.String()))
+			case *sqlparser.ChangeColumn:
+				drift = append(drift, fmt.Sprintf("different column %s.%s", name, opt.OldColumn.Name.String()))
+			case *sqlparser.AlterColumn:
+				drift = append(drift, fmt.Sprintf("different column %s.%s", name, opt.Column.Name.String()))
+			case *sqlparser.RenameColumn:
+				drift = append(drift, fmt.Sprintf("renamed column %s.%s", name, opt.OldName.Name.String()))
+			case *sqlparser.AddIndexDefinition:
+				drift = append(drift, fmt.Sprintf("extra index %s.%s", name, opt.IndexDefinition.Info.Name.String()))
+			case *sqlparser.RenameIndex:
+				drift = append(drift, fmt.Sprintf("renamed index %s.%s", name, opt.OldName.String()))
+			case *sqlparser.AlterIndex:
+				drift = append(drift, fmt.Sprintf("different index %s.%s", name, opt.Name.String()))
+			case *sqlparser.DropKey:
+				switch opt.Type {
+				case sqlparser.PrimaryKeyType:
+					drift = append(drift, fmt.Sprintf("missing index %s.PRIMARY", name))
+				case sqlparser.NormalKeyType:
+					drift = append(drift, fmt.Sprintf("missing index %s.%s", name, opt.Name.String()))
+				default:
+					drift = append(drift, fmt.Sprintf("missing constraint %s.%s", name, opt.Name.String()))
+				}
+			case *sqlparser.AddConstraintDefinition:
+				drift = append(drift, fmt.Sprintf("extra constraint %s.%s", name, opt.ConstraintDefinition.Name.String()))
+			case *sqlparser.AlterCheck:
+				drift = append(drift, fmt.Sprintf("different constraint %s.%s", name, opt.Name.String()))
+			case sqlparser.TableOptions:
+				for _, tableOption := range opt {
+					drift = append(drift, fmt.Sprintf("different table option %s.%s", name, strings.ToLower(tableOption.Name)))
+				}
+			default:
+				drift = append(drift, fmt.Sprintf("different table %s", name))
+			}
+		}
+		if stmt.PartitionSpec != nil || stmt.PartitionOption != nil {
+			drift = append(drift, fmt.Sprintf("different partitioning of %s", name))
+		}
+	default:
+		drift = append(drift, fmt.Sprintf("different %s", name))
+	}
+	if subsequent := diff.SubsequentDiff(); subsequent != nil {
+		drift = append(drift, driftSummary(subsequent)...)
+	}
+	return drift
+}
+
+// errorClass describes the error reading or diffing the given target without the details specific to the target,
+// such as its host or path, so that targets failing the same way share the class, e.g. "MySQL error 1045".
+func errorClass(target string, err error) string {
+	var mysqlErr *mysql.MySQLError
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &mysqlErr):
+		return fmt.Sprintf("MySQL error %d", mysqlErr.Number)
+	case errors.As(err, &dnsErr):
+		return dnsErr.Err
+	case errors.As(err, &opErr) && opErr.Err != nil:
+		return opErr.Err.Error()
+	case errors.As(err, &pathErr):
+		return fmt.Sprintf("%s: %s", pathErr.Op, pathErr.Err.Error())
+	}
+	return replaceTarget(err.Error(), target, "<target>")
+}
+
+// replaceTarget replaces the given target in an error message, with or without its scheme prefix, e.g. "mysql://",
+// since driver and DSN parsing errors echo the DSN as given to them.
+func replaceTarget(message string, target string, replacement string) string {
+	if target == "" {
+		return message
+	}
+	message = strings.ReplaceAll(message, target, replacement)
+	if _, value, ok := strings.Cut(target, "://"); ok && value != "" {
+		message = strings.ReplaceAll(message, value, replacement)
+	}
+	return message
+}
+
+// redactedTarget describes the given target for a report, without a DSN password. See base.DescribeInputSource.
+// A target that is not detected as any input source is returned as is, other than a password before an "@".
+func redactedTarget(target string) string {
+	if _, _, err := base.DetectSchemaSource(target); err == nil {
+		return base.DescribeInputSource(target)
+	}
+	start := 0
+	if i := strings.Index(target, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if at := strings.LastIndex(target, "@"); at > start {
+		if colon := strings.Index(target[start:at], ":"); colon >= 0 {
+			return target[:start+colon+1] + "***" + target[at:]
+		}
+	}
+	return target
+}
+
+// fleetTargetResult is the diff of a single target, or the error reading or diffing it.
+type fleetTargetResult struct {
+	diffs []string
+	drift []string
+	err   error
+	// errClass is the class of err. See errorClass.
+	errClass string
+}
+
+// key returns a key identical for targets which have the same diff, or the same class of error.
+func (t *fleetTargetResult) key() string {
+	if t.err != nil {
+		return "error:" + t.errClass
+	}
+	return "diff:" + strings.Join(t.diffs, ";\n")
+}
+
+// DiffFleet diffs the source against each of the targets, and groups the targets by identical diff. The source
+// is read once. Targets are read and diffed concurrently. A target that fails to read or diff does not fail the
+// function; it is reported in a group of targets that failed with the same class of error, along with its own error.
+func (r *Runner) DiffFleet(ctx context.Context, source string, targets []string, opts *FleetOptions) (*FleetResult, error) {
+	if opts == nil {
+		opts = &FleetOptions{}
+	}
+	if !r.opts.Mapping.IsEmpty() {
+		return nil, ErrFleetMapping
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultFleetConcurrency
+	}
+	sourceSchema, err := LoadSchema(ctx, r.env, source, r.readOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	diffTarget := func(target string) *fleetTargetResult {
+		failed := func(err error) *fleetTargetResult {
+			return &fleetTargetResult{err: err, errClass: errorClass(target, err)}
+		}
+		targetSQLs, err := base.ReadSQLsFromSource(ctx, r.env, target, r.readOpts)
+		if err != nil {
+			return failed(err)
+		}
+		targetSchema, err := schemadiff.NewSchemaFromQueries(r.env, targetSQLs)
+		if err != nil {
+			return failed(err)
+		}
+		diff, err := sourceSchema.SchemaDiff(targetSchema, r.opts.Hints)
+		if err != nil {
+			return failed(err)
+		}
+		diffs := diff.UnorderedDiffs()
+		if opts.Ordered {
+			if diffs, err = diff.OrderedDiffs(ctx); err != nil {
+				return failed(err)
+			}
+		}
+		result := &fleetTargetResult{}
+		for _, d := range r.filterDiffs(diffs) {
+			result.diffs = append(result.diffs, d.CanonicalStatementString())
+			result.drift = append(result.drift, driftSummary(d)...)
+		}
+		return result
+	}
+
+	targetResults := make([]*fleetTargetResult, len(targets))
+	var errs errgroup.Group
+	errs.SetLimit(concurrency)
+	for i, target := range targets {
+		i, target := i, target
+		errs.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			targetResults[i] = diffTarget(target)
+			return nil
+		})
+	}
+	if err := errs.Wait(); err != nil {
+		return nil, err
+	}
+
+	result := &FleetResult{Source: base.DescribeInputSource(source)}
+	groups := map[string]*FleetGroup{}
+	for i, targetResult := range targetResults {
+		key := targetResult.key()
+		group, ok := groups[key]
+		if !ok {
+			group = &FleetGroup{Diffs: targetResult.diffs, Drift: dedupeStrings(targetResult.drift)}
+			if targetResult.err != nil {
+				group.Error = targetResult.errClass
+			}
+			groups[key] = group
+			result.Groups = append(result.Groups, group)
+		}
+		group.Targets = append(group.Targets, base.DescribeInputSource(targets[i]))
+		if targetResult.err != nil {
+			group.Errors = append(group.Errors, replaceTarget(targetResult.err.Error(), targets[i], redactedTarget(targets[i])))
+		}
+	}
+	rank := func(g *FleetGroup) int {
+		switch {
+		case g.Identical():
+			return 0
+		case g.Error != "":
+			return 2
+		}
+		return 1
+	}
+	sort.SliceStable(result.Groups, func(i, j int) bool {
+		gi, gj := result.Groups[i], result.Groups[j]
+		if rank(gi) != rank(gj) {
+			return rank(gi) < rank(gj)
+		}
+		return len(gi.Targets) > len(gj.Targets)
+	})
+	return result, nil
+}
+
+func dedupeStrings(values []string) (deduped []string) {
+	seen := map[string]bool{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			deduped = append(deduped, value)
+		}
+	}
+	return deduped
+}
+
+// expandBraces expands the first brace expression in a pattern, and recursively the rest. A brace expression is
+// either a comma separated list, as in "shard-{a,b}", or a numeric range, as in "shard-{001..256}", where a
+// leading zero pads all numbers to the same width. Braces that are neither are kept as is.
+func expandBraces(pattern string) ([]string, error) {
+	start := strings.Index(pattern, "{")
+	if start < 0 {
+		return []string{pattern}, nil
+	}
+	end := strings.Index(pattern[start:], "}")
+	if end < 0 {
+		return []string{pattern}, nil
+	}
+	end += start
+	prefix, body, suffix := pattern[:start], pattern[start+1:end], pattern[end+1:]
+
+	var alternatives []string
+	if from, to, ok := strings.Cut(body, ".."); ok {
+		fromNum, fromErr := strconv.Atoi(from)
+		toNum, toErr := strconv.Atoi(to)
+		if fromErr != nil || toErr != nil {
+			return nil, fmt.Errorf("invalid range {%s} in %s", body, pattern)
+		}
+		if toNum < fromNum {
+			return nil, fmt.Errorf("invalid range {%s} in %s: %d is less than %d", body, pattern, toNum, fromNum)
+		}
+		if toNum-fromNum >= maxExpandedTargets {
+			return nil, fmt.Errorf("range {%s} in %s expands to more than %d targets", body, pattern, maxExpandedTargets)
+		}
+		width := 0
+		if (len(from) > 1 && from[0] == '0') || (len(to) > 1 && to[0] == '0') {
+			width = max(len(from), len(to))
+		}
+		for n := fromNum; n <= toNum; n++ {
+			alternatives = append(alternatives, fmt.Sprintf("%0*d", width, n))
+		}
+	} else if strings.Contains(body, ",") {
+		alternatives = strings.Split(body, ",")
+	} else {
+		rest, err := expandBraces(suffix)
+		if err != nil {
+			return nil, err
+		}
+		var expanded []string
+		for _, r := range rest {
+			expanded = append(expanded, pattern[:end+1]+r)
+		}
+		return expanded, nil
+	}
+
+	rest, err := expandBraces(suffix)
+	if err != nil {
+		return nil, err
+	}
+	if len(alternatives)*len(rest) > maxExpandedTargets {
+		return nil, fmt.Errorf("%s expands to more than %d targets", pattern, maxExpandedTargets)
+	}
+	var expanded []string
+	for _, alternative := range alternatives {
+		for _, r := range rest {
+			expanded = append(expanded, prefix+alternative+r)
+		}
+	}
+	return expanded, nil
+}
+
+// ExpandTargets expands target patterns into a list of targets. Each pattern first undergoes brace expansion,
+// e.g. "user@tcp(shard-{001..256}:3306)/db" or "schemas/{a,b}.sql". A resulting value that is not a valid input
+// as is, and contains any of "*?[", is then matched as a file glob, e.g. "shards/*.sql". Duplicates are removed.
+func ExpandTargets(patterns []string) ([]string, error) {
+	var targets []string
+	seen := map[string]bool{}
+	add := func(target string) {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	for _, pattern := range patterns {
+		values, err := expandBraces(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if _, _, err := base.DetectSchemaSource(value); err == nil || !strings.ContainsAny(value, "*?[") {
+				add(value)
+				continue
+			}
+			matches, err := filepath.Glob(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid target pattern %s: %w", value, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no targets match %s", value)
+			}
+			sort.Strings(matches)
+			for _, match := range matches {
+				add(match)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// ReadTargetsFile reads target patterns from the given file, one per line. Empty lines and lines
+// starting with '#' are ignored.
+func ReadTargetsFile(fileName string) ([]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
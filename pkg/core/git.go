@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+const gitDevNull = "/dev/null"
+
+// singleEntity parses given statement as a CREATE TABLE or CREATE VIEW entity. It returns nil if the statement is neither.
+func singleEntity(env *schemadiff.Environment, sql string) (schemadiff.Entity, error) {
+	stmt, err := env.Parser().ParseStrictDDL(sql)
+	if err != nil {
+		return nil, err
+	}
+	switch stmt := stmt.(type) {
+	case *sqlparser.CreateTable:
+		return schemadiff.NewCreateTableEntity(env, stmt)
+	case *sqlparser.CreateView:
+		return schemadiff.NewCreateViewEntity(env, stmt)
+	}
+	return nil, nil
+}
+
+// diffSingleEntities diffs two lists of at most one CREATE statement each, as entities. It returns false
+// if either statement is not a valid CREATE TABLE|VIEW, or if the two cannot be diffed as entities.
+func diffSingleEntities(env *schemadiff.Environment, oldSQLs []string, newSQLs []string, hints *schemadiff.DiffHints) (schemadiff.EntityDiff, bool) {
+	var oldEntity, newEntity schemadiff.Entity
+	var err error
+	if len(oldSQLs) == 1 {
+		if oldEntity, err = singleEntity(env, oldSQLs[0]); err != nil || oldEntity == nil {
+			return nil, false
+		}
+	}
+	if len(newSQLs) == 1 {
+		if newEntity, err = singleEntity(env, newSQLs[0]); err != nil || newEntity == nil {
+			return nil, false
+		}
+	}
+	switch {
+	case oldEntity == nil && newEntity == nil:
+		return nil, true
+	case oldEntity == nil:
+		return newEntity.Create(), true
+	case newEntity == nil:
+		return oldEntity.Drop(), true
+	}
+	diff, err := oldEntity.Diff(newEntity, hints)
+	if err != nil {
+		return nil, false
+	}
+	return diff, true
+}
+
+// DiffGitBlobs returns the diffs between two versions of a file, as given to a git diff driver. git passes
+// /dev/null for a version of an added or deleted file, which reads as an empty schema.
+// When each version holds at most one CREATE statement, the versions are diffed as single entities, in the
+// manner of DiffTables and DiffViews. This is typical of one-table-per-file repositories, where a table may
+// reference tables found in other files, and so cannot be validated as a standalone schema.
+// Otherwise, the versions are diffed as schemas.
+func DiffGitBlobs(ctx context.Context, env *schemadiff.Environment, oldFile string, newFile string, hints *schemadiff.DiffHints, readOpts *base.ReadOptions) ([]schemadiff.EntityDiff, error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
+	oldSQLs, err := base.ReadSQLsFromSource(ctx, env, oldFile, readOpts)
+	if err != nil {
+		return nil, err
+	}
+	newSQLs, err := base.ReadSQLsFromSource(ctx, env, newFile, readOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(oldSQLs) <= 1 && len(newSQLs) <= 1 {
+		if diff, ok := diffSingleEntities(env, oldSQLs, newSQLs, hints); ok {
+			if diff == nil || diff.IsEmpty() {
+				return nil, nil
+			}
+			return []schemadiff.EntityDiff{diff}, nil
+		}
+		// Fall back to diffing as schemas, which reports a meaningful error if there is any.
+	}
+	oldSchema, err := schemadiff.NewSchemaFromQueries(env, oldSQLs)
+	if err != nil {
+		return nil, err
+	}
+	newSchema, err := schemadiff.NewSchemaFromQueries(env, newSQLs)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := oldSchema.SchemaDiff(newSchema, hints)
+	if err != nil {
+		return nil, err
+	}
+	return diff.UnorderedDiffs(), nil
+}
+
+// gitDiffHeader returns the header of a git diff driver output for the given path and file versions.
+func gitDiffHeader(path string, oldFile string, newFile string) string {
+	oldName := "a/" + path
+	if oldFile == gitDevNull {
+		oldName = gitDevNull
+	}
+	newName := "b/" + path
+	if newFile == gitDevNull {
+		newName = gitDevNull
+	}
+	return fmt.Sprintf("diff --schemadiff a/%s b/%s\n--- %s\n+++ %s\n", path, path, oldName, newName)
+}
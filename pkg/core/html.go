@@ -0,0 +1,161 @@
+package core
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Kinds of lines in the side-by-side view of an HTML report, which are also their CSS classes
+const (
+	htmlLineContext  = "context"
+	htmlLineAdded    = "added"
+	htmlLineRemoved  = "removed"
+	htmlLineModified = "modified"
+	htmlLineEmpty    = "empty"
+)
+
+// htmlDiffRow is a row of the side-by-side view of an entity diff: a line of the entity before the diff, on the
+// left, and the matching line after the diff, on the right.
+type htmlDiffRow struct {
+	Left      string
+	LeftKind  string
+	Right     string
+	RightKind string
+}
+
+// htmlEntity is the section of an entity diff in an HTML report.
+type htmlEntity struct {
+	*DiffSummary
+	// ID is the anchor of the section, linked from the index
+	ID   string
+	Rows []*htmlDiffRow
+}
+
+// sideBySide aligns the lines of a unified annotated diff, as exported by schemadiff.TextualAnnotations, into
+// side-by-side rows. Unchanged lines show on both sides. A run of removed lines followed by a run of added lines
+// pairs up as modified lines; the remaining lines show on one side only.
+func sideBySide(unified string) []*htmlDiffRow {
+	var rows []*htmlDiffRow
+	var removed, added []string
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			row := &htmlDiffRow{LeftKind: htmlLineEmpty, RightKind: htmlLineEmpty}
+			switch {
+			case i < len(removed) && i < len(added):
+				row.Left, row.LeftKind = removed[i], htmlLineModified
+				row.Right, row.RightKind = added[i], htmlLineModified
+			case i < len(removed):
+				row.Left, row.LeftKind = removed[i], htmlLineRemoved
+			default:
+				row.Right, row.RightKind = added[i], htmlLineAdded
+			}
+			rows = append(rows, row)
+		}
+		removed, added = nil, nil
+	}
+	for _, line := range strings.Split(unified, "\n") {
+		if line == "" {
+			continue
+		}
+		text := line[1:]
+		switch line[0] {
+		case '-':
+			if len(added) > 0 {
+				flush()
+			}
+			removed = append(removed, text)
+		case '+':
+			added = append(added, text)
+		default:
+			flush()
+			rows = append(rows, &htmlDiffRow{Left: text, LeftKind: htmlLineContext, Right: text, RightKind: htmlLineContext})
+		}
+	}
+	flush()
+	return rows
+}
+
+// htmlReport is the template of HTML reports. It has no external assets, so that the report displays offline.
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Schema diff</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+code, pre, .diff td { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 13px; }
+table { border-collapse: collapse; }
+.index th, .index td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; }
+.index th { background: #f6f8fa; }
+.destructive { color: #cf222e; font-weight: bold; }
+section { margin: 2em 0; }
+.diff { width: 100%; table-layout: fixed; border: 1px solid #d0d7de; }
+.diff td { white-space: pre-wrap; word-break: break-all; vertical-align: top; padding: 0 8px; width: 50%; }
+.diff td + td { border-left: 1px solid #d0d7de; }
+.diff th { background: #f6f8fa; text-align: left; padding: 4px 8px; border-bottom: 1px solid #d0d7de; }
+.added { background: #dafbe1; }
+.removed { background: #ffebe9; }
+.modified { background: #fff8c5; }
+.empty { background: #f6f8fa; }
+pre.migration { background: #f6f8fa; border: 1px solid #d0d7de; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Schema diff</h1>
+{{- if .NoChanges}}
+<p>No schema changes.</p>
+{{- else}}
+<h2>Entities</h2>
+<table class="index">
+<tr><th>Entity</th><th>Change</th><th>Algorithm</th><th>Destructive</th></tr>
+{{- range .Preamble}}
+<tr><td><code>{{.Entity}}</code></td><td>{{.Change}}</td><td>{{or .Algorithm "-"}}</td><td>{{if .Destructive}}<span class="destructive">yes</span>{{else}}no{{end}}</td></tr>
+{{- end}}
+{{- range .Entities}}
+<tr><td><a href="#{{.ID}}"><code>{{.Entity}}</code></a></td><td>{{.Change}}</td><td>{{or .Algorithm "-"}}</td><td>{{if .Destructive}}<span class="destructive">yes</span>{{else}}no{{end}}</td></tr>
+{{- end}}
+</table>
+{{- range .Entities}}
+<section id="{{.ID}}">
+<h3><code>{{.Entity}}</code>: {{.Change}}</h3>
+<table class="diff">
+<tr><th>Before</th><th>After</th></tr>
+{{- range .Rows}}
+<tr><td class="{{.LeftKind}}">{{.Left}}</td><td class="{{.RightKind}}">{{.Right}}</td></tr>
+{{- end}}
+</table>
+</section>
+{{- end}}
+<h2>Migration</h2>
+<pre class="migration">{{.Migration}}</pre>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the diffs of the result as a self-contained HTML report: an index of the changed entities, a
+// side-by-side, color-coded view of each entity before and after its diff, and the statements of the migration.
+func (r *Result) WriteHTML(w io.Writer) error {
+	data := struct {
+		NoChanges bool
+		// Preamble summarizes the preamble statements, which have no annotated diff
+		Preamble  []*DiffSummary
+		Entities  []*htmlEntity
+		Migration string
+	}{
+		NoChanges: len(r.Preamble) == 0 && len(r.Diffs) == 0,
+		Preamble:  summarizePreamble(r.Preamble),
+		Migration: r.migrationScript(),
+	}
+	for i, d := range r.Diffs {
+		_, _, unified := d.Annotated()
+		data.Entities = append(data.Entities, &htmlEntity{
+			DiffSummary: SummarizeDiff(d),
+			ID:          fmt.Sprintf("entity-%d", i+1),
+			Rows:        sideBySide(unified.Export()),
+		})
+	}
+	return htmlReport.Execute(w, data)
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSideBySide(t *testing.T) {
+	tcases := []struct {
+		name    string
+		unified string
+		expect  []*htmlDiffRow
+	}{
+		{
+			name:    "empty",
+			unified: "",
+		},
+		{
+			name:    "modified",
+			unified: " CREATE TABLE `t1` (\n-\t`id` int,\n+\t`id` bigint,\n \tPRIMARY KEY (`id`)\n )",
+			expect: []*htmlDiffRow{
+				{Left: "CREATE TABLE `t1` (", LeftKind: htmlLineContext, Right: "CREATE TABLE `t1` (", RightKind: htmlLineContext},
+				{Left: "\t`id` int,", LeftKind: htmlLineModified, Right: "\t`id` bigint,", RightKind: htmlLineModified},
+				{Left: "\tPRIMARY KEY (`id`)", LeftKind: htmlLineContext, Right: "\tPRIMARY KEY (`id`)", RightKind: htmlLineContext},
+				{Left: ")", LeftKind: htmlLineContext, Right: ")", RightKind: htmlLineContext},
+			},
+		},
+		{
+			name:    "added and removed",
+			unified: " (\n-a\n-b\n+c\n+d\n+e\n-f\n )",
+			expect: []*htmlDiffRow{
+				{Left: "(", LeftKind: htmlLineContext, Right: "(", RightKind: htmlLineContext},
+				{Left: "a", LeftKind: htmlLineModified, Right: "c", RightKind: htmlLineModified},
+				{Left: "b", LeftKind: htmlLineModified, Right: "d", RightKind: htmlLineModified},
+				{LeftKind: htmlLineEmpty, Right: "e", RightKind: htmlLineAdded},
+				{Left: "f", LeftKind: htmlLineRemoved, RightKind: htmlLineEmpty},
+				{Left: ")", LeftKind: htmlLineContext, Right: ")", RightKind: htmlLineContext},
+			},
+		},
+		{
+			name:    "created",
+			unified: "+CREATE TABLE `t2` (\n+\t`id` int\n+)",
+			expect: []*htmlDiffRow{
+				{LeftKind: htmlLineEmpty, Right: "CREATE TABLE `t2` (", RightKind: htmlLineAdded},
+				{LeftKind: htmlLineEmpty, Right: "\t`id` int", RightKind: htmlLineAdded},
+				{LeftKind: htmlLineEmpty, Right: ")", RightKind: htmlLineAdded},
+			},
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			assert.Equal(t, tcase.expect, sideBySide(tcase.unified))
+		})
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("report", func(t *testing.T) {
+		fileFrom := writeSchemaFile(t, []string{
+			"create table t1 (id int primary key, name varchar(12))",
+			"create table t2 (id int primary key)",
+		})
+		defer os.RemoveAll(fileFrom)
+		fileTo := writeSchemaFile(t, []string{
+			"create table t1 (id int primary key, name varchar(12), key name_idx (name))",
+			"create table `t<3>` (id int primary key)",
+		})
+		defer os.RemoveAll(fileTo)
+
+		output, err := Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{Output: "html"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(output, "<!DOCTYPE html>\n"), output)
+		assert.NotContains(t, output, "<link")
+		assert.NotContains(t, output, "<script")
+		assert.Contains(t, output, `<code>t2</code></a></td><td>drop table</td><td>-</td><td><span class="destructive">yes</span></td></tr>`)
+		assert.Contains(t, output, `<code>t1</code></a></td><td>alter table</td><td>INPLACE/COPY</td><td>no</td></tr>`)
+		assert.Contains(t, output, "<code>t&lt;3&gt;</code>")
+		assert.NotContains(t, output, "t<3>")
+		assert.Contains(t, output, `<td class="empty"></td><td class="added">	KEY `+"`name_idx`"+` (`+"`name`"+`)</td>`)
+		assert.Contains(t, output, `<td class="removed">CREATE TABLE `+"`t2`"+` (</td><td class="empty"></td>`)
+		assert.Contains(t, output, "<pre class=\"migration\">")
+		assert.Contains(t, output, "DROP TABLE `t2`;\n")
+	})
+	t.Run("no changes", func(t *testing.T) {
+		var b strings.Builder
+		require.NoError(t, (&Result{}).WriteHTML(&b))
+		assert.Contains(t, b.String(), "<p>No schema changes.</p>")
+		assert.NotContains(t, b.String(), "Migration")
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Exec(ctx, "fingerprint", "", "", &ExecOptions{Output: "html"})
+		assert.ErrorContains(t, err, "--output html applies to diff commands, not to fingerprint")
+		_, err = Exec(ctx, "diff", "", "", &ExecOptions{Output: "pdf"})
+		assert.ErrorContains(t, err, "expected text, json, markdown or html")
+	})
+}
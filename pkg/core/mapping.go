@@ -0,0 +1,451 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+// EntityMapping maps entity (table/view) names in the source schema onto their corresponding names in the
+// target schema. When diffing, mapped entities are renamed rather than dropped and recreated.
+type EntityMapping map[string]string
+
+// ColumnMapping maps source table names onto column renames in those tables. Each column rename maps
+// a column name in the source table onto its corresponding name in the target table.
+type ColumnMapping map[string]map[string]string
+
+// Mapping describes the correspondence between names in the source schema and names in the target schema.
+type Mapping struct {
+	Entities EntityMapping
+	Columns  ColumnMapping
+}
+
+// IsEmpty returns true when the mapping has no entries.
+func (m *Mapping) IsEmpty() bool {
+	return m == nil || (len(m.Entities) == 0 && len(m.Columns) == 0)
+}
+
+// entityName returns the target name of the given source entity
+func (m *Mapping) entityName(name string) string {
+	if newName, ok := m.Entities[name]; ok {
+		return newName
+	}
+	return name
+}
+
+// ParseMapping parses a list of mapping values. A value is either "old=new", mapping a source entity onto a
+// target entity, or "table.old=new", mapping a column in a source table onto a target column.
+// It returns an error on a malformed value, or when a name is mapped more than once.
+func ParseMapping(values []string) (*Mapping, error) {
+	mapping := &Mapping{
+		Entities: EntityMapping{},
+		Columns:  ColumnMapping{},
+	}
+	targets := map[string]bool{}
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, "=")
+		from = strings.TrimSpace(from)
+		to = strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected old=new or table.old=new", value)
+		}
+		if table, column, ok := strings.Cut(from, "."); ok {
+			if table == "" || column == "" || strings.Contains(to, ".") {
+				return nil, fmt.Errorf("invalid column mapping %q, expected table.old=new", value)
+			}
+			if mapping.Columns[table] == nil {
+				mapping.Columns[table] = map[string]string{}
+			}
+			if _, ok := mapping.Columns[table][column]; ok {
+				return nil, fmt.Errorf("column %s.%s is mapped more than once", table, column)
+			}
+			if targets[table+"."+to] {
+				return nil, fmt.Errorf("column %s.%s is mapped onto more than once", table, to)
+			}
+			mapping.Columns[table][column] = to
+			targets[table+"."+to] = true
+			continue
+		}
+		if _, ok := mapping.Entities[from]; ok {
+			return nil, fmt.Errorf("entity %s is mapped more than once", from)
+		}
+		if targets[to] {
+			return nil, fmt.Errorf("entity %s is mapped onto more than once", to)
+		}
+		mapping.Entities[from] = to
+		targets[to] = true
+	}
+	return mapping, nil
+}
+
+// ReadMappingFile reads mapping values from the given file, one per line. Empty lines and lines
+// starting with '#' are ignored.
+func ReadMappingFile(fileName string) ([]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var values []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values = append(values, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// renameTableStatement returns a single, atomic RENAME TABLE statement for all mapped entities, or an empty string
+// if there are none.
+func (m *Mapping) renameTableStatement() string {
+	if len(m.Entities) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(m.Entities))
+	for name := range m.Entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	renameTable := &sqlparser.RenameTable{}
+	for _, name := range names {
+		renameTable.TablePairs = append(renameTable.TablePairs, &sqlparser.RenameTablePair{
+			FromTable: sqlparser.NewTableName(name),
+			ToTable:   sqlparser.NewTableName(m.Entities[name]),
+		})
+	}
+	return sqlparser.CanonicalString(renameTable)
+}
+
+// renameColumnStatements returns an ALTER TABLE ... RENAME COLUMN statement per table with mapped columns.
+// The statements use the tables' target names, and are expected to run after renameTableStatement().
+func (m *Mapping) renameColumnStatements() (statements []string) {
+	tables := make([]string, 0, len(m.Columns))
+	for table := range m.Columns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		columns := make([]string, 0, len(m.Columns[table]))
+		for column := range m.Columns[table] {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		alterTable := &sqlparser.AlterTable{Table: sqlparser.NewTableName(m.entityName(table))}
+		for _, column := range columns {
+			alterTable.AlterOptions = append(alterTable.AlterOptions, &sqlparser.RenameColumn{
+				OldName: &sqlparser.ColName{Name: sqlparser.NewIdentifierCI(column)},
+				NewName: &sqlparser.ColName{Name: sqlparser.NewIdentifierCI(m.Columns[table][column])},
+			})
+		}
+		statements = append(statements, sqlparser.CanonicalString(alterTable))
+	}
+	return statements
+}
+
+// selectTableScope returns the qualifiers by which the given SELECT refers to the given table, i.e. the table name,
+// or its aliases, and whether unqualified columns refer to the table, which is when it is the only table the SELECT reads.
+func selectTableScope(sel *sqlparser.Select, table string) (qualifiers map[string]bool, unqualified bool) {
+	qualifiers = map[string]bool{}
+	tables := 0
+	var visit func(expr sqlparser.TableExpr)
+	visit = func(expr sqlparser.TableExpr) {
+		switch expr := expr.(type) {
+		case *sqlparser.AliasedTableExpr:
+			tables++
+			tableName, ok := expr.Expr.(sqlparser.TableName)
+			if !ok || !tableName.Qualifier.IsEmpty() || tableName.Name.String() != table {
+				return
+			}
+			if expr.As.IsEmpty() {
+				qualifiers[table] = true
+			} else {
+				qualifiers[expr.As.String()] = true
+			}
+		case *sqlparser.JoinTableExpr:
+			visit(expr.LeftExpr)
+			visit(expr.RightExpr)
+		case *sqlparser.ParenTableExpr:
+			for _, expr := range expr.Exprs {
+				visit(expr)
+			}
+		default:
+			tables++
+		}
+	}
+	for _, expr := range sel.From {
+		visit(expr)
+	}
+	return qualifiers, tables == 1 && len(qualifiers) == 1
+}
+
+// renameSelectColumns renames the mapped columns of the given table which the given SELECT references. Subqueries
+// have scopes of their own, and are left to be renamed separately.
+func renameSelectColumns(sel *sqlparser.Select, table string, columns map[string]string) {
+	qualifiers, unqualified := selectTableScope(sel, table)
+	if len(qualifiers) == 0 {
+		return
+	}
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+		switch node := node.(type) {
+		case *sqlparser.Select:
+			return node == sel, nil
+		case *sqlparser.ColName:
+			if node.Qualifier.IsEmpty() {
+				if !unqualified {
+					return true, nil
+				}
+			} else if !node.Qualifier.Qualifier.IsEmpty() || !qualifiers[node.Qualifier.Name.String()] {
+				return true, nil
+			}
+			if newName, ok := columns[node.Name.String()]; ok {
+				node.Name = sqlparser.NewIdentifierCI(newName)
+			}
+		}
+		return true, nil
+	}, sel)
+}
+
+// applyColumns renames mapped columns in the given CREATE statement. In a CREATE TABLE for a mapped table, it
+// renames the column definitions, key and foreign key columns, and column references in expressions. In a
+// CREATE TABLE for a child table, it renames referenced foreign key columns. In a CREATE VIEW, it renames
+// column references which qualify the mapped table by its name or alias, and unqualified column references in
+// queries which read no table other than the mapped table.
+func (m *Mapping) applyColumns(stmt sqlparser.Statement) {
+	for table, columns := range m.Columns {
+		switch stmt := stmt.(type) {
+		case *sqlparser.CreateTable:
+			if stmt.Table.Name.String() == table {
+				for _, col := range stmt.TableSpec.Columns {
+					if newName, ok := columns[col.Name.String()]; ok {
+						col.Name = sqlparser.NewIdentifierCI(newName)
+					}
+				}
+				for _, key := range stmt.TableSpec.Indexes {
+					for _, keyColumn := range key.Columns {
+						if newName, ok := columns[keyColumn.Column.String()]; ok {
+							keyColumn.Column = sqlparser.NewIdentifierCI(newName)
+						}
+					}
+				}
+			}
+			for _, constraint := range stmt.TableSpec.Constraints {
+				fk, ok := constraint.Details.(*sqlparser.ForeignKeyDefinition)
+				if !ok {
+					continue
+				}
+				if stmt.Table.Name.String() == table {
+					for i, col := range fk.Source {
+						if newName, ok := columns[col.String()]; ok {
+							fk.Source[i] = sqlparser.NewIdentifierCI(newName)
+						}
+					}
+				}
+				if fk.ReferenceDefinition.ReferencedTable.Name.String() == table {
+					for i, col := range fk.ReferenceDefinition.ReferencedColumns {
+						if newName, ok := columns[col.String()]; ok {
+							fk.ReferenceDefinition.ReferencedColumns[i] = sqlparser.NewIdentifierCI(newName)
+						}
+					}
+				}
+			}
+			if stmt.Table.Name.String() != table {
+				continue
+			}
+		case *sqlparser.CreateView:
+			_ = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+				if sel, ok := node.(*sqlparser.Select); ok {
+					renameSelectColumns(sel, table, columns)
+				}
+				return true, nil
+			}, stmt.Select)
+			continue
+		default:
+			continue
+		}
+		_ = sqlparser.Rewrite(stmt, func(cursor *sqlparser.Cursor) bool {
+			colName, ok := cursor.Node().(*sqlparser.ColName)
+			if !ok {
+				return true
+			}
+			if !colName.Qualifier.IsEmpty() && colName.Qualifier.Name.String() != table {
+				return true
+			}
+			if newName, ok := columns[colName.Name.String()]; ok {
+				colName.Name = sqlparser.NewIdentifierCI(newName)
+			}
+			return true
+		}, nil)
+	}
+}
+
+// applyEntities renames all references to mapped entities in the given CREATE statement. This covers
+// the entity's own name, foreign key parent tables, and tables referenced by views.
+func (m *Mapping) applyEntities(stmt sqlparser.Statement) sqlparser.Statement {
+	return sqlparser.Rewrite(stmt, func(cursor *sqlparser.Cursor) bool {
+		tableName, ok := cursor.Node().(sqlparser.TableName)
+		if !ok || !tableName.Qualifier.IsEmpty() {
+			return true
+		}
+		if newName, ok := m.Entities[tableName.Name.String()]; ok {
+			cursor.Replace(sqlparser.NewTableName(newName))
+		}
+		return true
+	}, nil).(sqlparser.Statement)
+}
+
+// apply returns a copy of the given CREATE statement, with mapped columns and entities renamed.
+func (m *Mapping) apply(stmt sqlparser.Statement) sqlparser.Statement {
+	stmt = sqlparser.CloneStatement(stmt)
+	m.applyColumns(stmt)
+	return m.applyEntities(stmt)
+}
+
+// validate checks that the mapping matches the given source and target schemas: mapped names must exist in
+// the source, their new names must exist in the target and must not already exist in the source.
+func (m *Mapping) validate(sourceSchema *schemadiff.Schema, targetSchema *schemadiff.Schema) error {
+	sourceNames := map[string]bool{}
+	for _, name := range sourceSchema.EntityNames() {
+		sourceNames[name] = true
+	}
+	targetNames := map[string]bool{}
+	for _, name := range targetSchema.EntityNames() {
+		targetNames[name] = true
+	}
+	for from, to := range m.Entities {
+		if !sourceNames[from] {
+			return fmt.Errorf("entity mapping %s=%s: source entity %s not found", from, to, from)
+		}
+		if !targetNames[to] {
+			return fmt.Errorf("entity mapping %s=%s: target entity %s not found", from, to, to)
+		}
+		if sourceNames[to] {
+			return fmt.Errorf("entity mapping %s=%s: entity %s already exists in source", from, to, to)
+		}
+	}
+	columnNames := func(table *schemadiff.CreateTableEntity) map[string]bool {
+		names := map[string]bool{}
+		for _, col := range table.TableSpec.Columns {
+			names[col.Name.String()] = true
+		}
+		return names
+	}
+	for table, columns := range m.Columns {
+		sourceTable := sourceSchema.Table(table)
+		if sourceTable == nil {
+			return fmt.Errorf("column mapping: source table %s not found", table)
+		}
+		targetTable := targetSchema.Table(m.entityName(table))
+		if targetTable == nil {
+			return fmt.Errorf("column mapping: target table %s not found", m.entityName(table))
+		}
+		sourceColumns := columnNames(sourceTable)
+		targetColumns := columnNames(targetTable)
+		for from, to := range columns {
+			if !sourceColumns[from] {
+				return fmt.Errorf("column mapping %s.%s=%s: source column %s not found", table, from, to, from)
+			}
+			if !targetColumns[to] {
+				return fmt.Errorf("column mapping %s.%s=%s: target column %s not found", table, from, to, to)
+			}
+			if sourceColumns[to] {
+				return fmt.Errorf("column mapping %s.%s=%s: column %s already exists in source", table, from, to, to)
+			}
+		}
+	}
+	return nil
+}
+
+// DiffSchemasWithMapping returns a rich diff between two given schemas, where entities and columns in the source
+// schema are first renamed according to given mapping. Along with the diff, the function returns the statements
+// which need to run before the diff: a RENAME TABLE statement, ALTER TABLE ... RENAME COLUMN statements, and
+// ALTER VIEW statements for views whose definitions reference renamed entities or columns, and which are otherwise unchanged.
+// Inputs can be stdin, file, directory, or MySQL URI.
+func DiffSchemasWithMapping(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string, mapping *Mapping, hints *schemadiff.DiffHints, readOpts *base.ReadOptions) (preamble []string, diff *schemadiff.SchemaDiff, err error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
+	sourceSchema, err := base.ReadSchemaFromSource(ctx, env, inputSourceValue, readOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetSchema, err := base.ReadSchemaFromSource(ctx, env, targetInputSourceValue, readOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return diffLoadedSchemasWithMapping(env, sourceSchema, targetSchema, mapping, hints)
+}
+
+// diffLoadedSchemasWithMapping is DiffSchemasWithMapping, over schemas already loaded.
+func diffLoadedSchemasWithMapping(env *schemadiff.Environment, sourceSchema *schemadiff.Schema, targetSchema *schemadiff.Schema, mapping *Mapping, hints *schemadiff.DiffHints) (preamble []string, diff *schemadiff.SchemaDiff, err error) {
+	if mapping.IsEmpty() {
+		diff, err := sourceSchema.SchemaDiff(targetSchema, hints)
+		return nil, diff, err
+	}
+	if err := mapping.validate(sourceSchema, targetSchema); err != nil {
+		return nil, nil, err
+	}
+
+	var statements []sqlparser.Statement
+	var rewrittenViews []*sqlparser.CreateView
+	for _, entity := range sourceSchema.Entities() {
+		stmt := entity.Create().Statement()
+		mappedStmt := mapping.apply(stmt)
+		statements = append(statements, mappedStmt)
+		if createView, ok := mappedStmt.(*sqlparser.CreateView); ok {
+			if sqlparser.CanonicalString(createView) != sqlparser.CanonicalString(stmt) {
+				rewrittenViews = append(rewrittenViews, createView)
+			}
+		}
+	}
+	mappedSchema, err := schemadiff.NewSchemaFromStatements(env, statements)
+	if err != nil {
+		return nil, nil, err
+	}
+	diff, err = mappedSchema.SchemaDiff(targetSchema, hints)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if stmt := mapping.renameTableStatement(); stmt != "" {
+		preamble = append(preamble, stmt)
+	}
+	preamble = append(preamble, mapping.renameColumnStatements()...)
+	// A renamed table or column is not renamed within the views that reference it; MySQL requires those views to be redefined.
+	// Views which have changes of their own are redefined by the diff.
+	diffedEntities := map[string]bool{}
+	for _, d := range diff.UnorderedDiffs() {
+		diffedEntities[d.EntityName()] = true
+	}
+	for _, createView := range rewrittenViews {
+		name := createView.ViewName.Name.String()
+		if diffedEntities[name] {
+			continue
+		}
+		alterView := &sqlparser.AlterView{
+			ViewName:    createView.ViewName,
+			Algorithm:   createView.Algorithm,
+			Definer:     createView.Definer,
+			Security:    createView.Security,
+			Columns:     createView.Columns,
+			Select:      createView.Select,
+			CheckOption: createView.CheckOption,
+		}
+		preamble = append(preamble, sqlparser.CanonicalString(alterView))
+	}
+	return preamble, diff, nil
+}
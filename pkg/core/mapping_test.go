@@ -0,0 +1,96 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMapping(t *testing.T) {
+	tcases := []struct {
+		name           string
+		values         []string
+		expectEntities EntityMapping
+		expectColumns  ColumnMapping
+		expectError    string
+	}{
+		{
+			name:           "empty",
+			expectEntities: EntityMapping{},
+			expectColumns:  ColumnMapping{},
+		},
+		{
+			name:           "single",
+			values:         []string{"users=users_v2"},
+			expectEntities: EntityMapping{"users": "users_v2"},
+			expectColumns:  ColumnMapping{},
+		},
+		{
+			name:           "spaces",
+			values:         []string{" users = users_v2 ", "orders=orders_v2"},
+			expectEntities: EntityMapping{"users": "users_v2", "orders": "orders_v2"},
+			expectColumns:  ColumnMapping{},
+		},
+		{
+			name:           "columns",
+			values:         []string{"users=users_v2", "users.name=full_name", "users.ts=created_at", "orders.ts=created_at"},
+			expectEntities: EntityMapping{"users": "users_v2"},
+			expectColumns: ColumnMapping{
+				"users":  {"name": "full_name", "ts": "created_at"},
+				"orders": {"ts": "created_at"},
+			},
+		},
+		{
+			name:        "no equal sign",
+			values:      []string{"users"},
+			expectError: "invalid mapping",
+		},
+		{
+			name:        "empty target",
+			values:      []string{"users="},
+			expectError: "invalid mapping",
+		},
+		{
+			name:        "qualified column target",
+			values:      []string{"users.name=users.full_name"},
+			expectError: "invalid column mapping",
+		},
+		{
+			name:        "empty column",
+			values:      []string{"users.=full_name"},
+			expectError: "invalid column mapping",
+		},
+		{
+			name:        "duplicate source",
+			values:      []string{"users=users_v2", "users=users_v3"},
+			expectError: "mapped more than once",
+		},
+		{
+			name:        "duplicate target",
+			values:      []string{"users=users_v2", "accounts=users_v2"},
+			expectError: "mapped onto more than once",
+		},
+		{
+			name:        "duplicate column source",
+			values:      []string{"users.name=full_name", "users.name=first_name"},
+			expectError: "column users.name is mapped more than once",
+		},
+		{
+			name:        "duplicate column target",
+			values:      []string{"users.name=full_name", "users.nm=full_name"},
+			expectError: "column users.full_name is mapped onto more than once",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			mapping, err := ParseMapping(tcase.values)
+			if tcase.expectError != "" {
+				assert.ErrorContains(t, err, tcase.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tcase.expectEntities, mapping.Entities)
+			assert.Equal(t, tcase.expectColumns, mapping.Columns)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+const (
+	// MarkdownMaxSize is the maximum size of markdown output, which is GitHub's limit on the size of a comment
+	MarkdownMaxSize = 65536
+	// markdownMaxSectionSize is the maximum size of the annotated diff of a single entity in markdown output
+	markdownMaxSectionSize = 8192
+)
+
+// DiffSummary describes a single diff, as listed in report output formats.
+type DiffSummary struct {
+	Entity string
+	// Change is the kind of statement, e.g. "alter table"
+	Change string
+	// Algorithm is "INSTANT" for ALTER TABLE statements which MySQL can run as INSTANT DDL, "INPLACE/COPY" for
+	// other ALTER TABLE statements, and empty otherwise
+	Algorithm string
+	// Destructive is true for diffs which may lose data or constraints: dropping a table, a view, a column, an
+	// index, a foreign key, a check constraint or a partition, or narrowing the type of a column
+	Destructive bool
+}
+
+// integerTypeRanks, floatTypeRanks, stringTypeRanks and bytesTypeRanks rank the column types of a family by the
+// values they hold, where a higher rank holds more.
+var (
+	integerTypeRanks = map[string]int{"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "integer": 4, "bigint": 5}
+	floatTypeRanks   = map[string]int{"float": 1, "double": 2, "real": 2}
+	stringTypeRanks  = map[string]int{"char": 1, "varchar": 1, "tinytext": 2, "text": 3, "mediumtext": 4, "longtext": 5}
+	bytesTypeRanks   = map[string]int{"binary": 1, "varbinary": 1, "tinyblob": 2, "blob": 3, "mediumblob": 4, "longblob": 5}
+)
+
+// narrowsType returns whether changing a column from one type to another may lose data: a lower ranked type of the
+// same family, a shorter length, a lower precision or scale, fewer digits before the decimal point, a change of
+// signedness, removed enum or set values, or a type of another family.
+func narrowsType(from *sqlparser.ColumnType, to *sqlparser.ColumnType) bool {
+	fromType, toType := strings.ToLower(from.Type), strings.ToLower(to.Type)
+	fromRank, toRank := 0, 0
+	if fromType != toType {
+		sameFamily := false
+		for _, ranks := range []map[string]int{integerTypeRanks, floatTypeRanks, stringTypeRanks, bytesTypeRanks} {
+			fromTypeRank, fromOK := ranks[fromType]
+			toTypeRank, toOK := ranks[toType]
+			if fromOK && toOK {
+				fromRank, toRank, sameFamily = fromTypeRank, toTypeRank, true
+			}
+		}
+		if !sameFamily {
+			return true
+		}
+	}
+	shorter := func(from *int, to *int) bool {
+		return from != nil && to != nil && *to < *from
+	}
+	// integerDigits returns the number of digits before the decimal point: the precision less the scale
+	integerDigits := func(t *sqlparser.ColumnType) *int {
+		if t.Length == nil || t.Scale == nil {
+			return t.Length
+		}
+		digits := *t.Length - *t.Scale
+		return &digits
+	}
+	_, isInteger := integerTypeRanks[fromType]
+	switch {
+	case toRank != fromRank:
+		return toRank < fromRank
+	case from.Unsigned != to.Unsigned:
+		return true
+	case !isInteger && (shorter(from.Length, to.Length) || shorter(from.Scale, to.Scale) || shorter(integerDigits(from), integerDigits(to))):
+		// An integer's length is its display width, which does not limit its values
+		return true
+	}
+	for _, value := range from.EnumValues {
+		if !slices.Contains(to.EnumValues, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// destructiveAlter returns whether the ALTER TABLE statement of the given diff may lose data or constraints: it
+// drops a column, an index, a foreign key or a check constraint, narrows the type of a column, or drops or
+// truncates partitions.
+func destructiveAlter(d schemadiff.EntityDiff, stmt *sqlparser.AlterTable) bool {
+	if stmt.PartitionSpec != nil {
+		switch stmt.PartitionSpec.Action {
+		case sqlparser.DropAction, sqlparser.TruncateAction:
+			return true
+		}
+	}
+	// fromColumn returns the definition of the named column before the diff
+	fromColumn := func(name string) *sqlparser.ColumnDefinition {
+		from, _ := d.Entities()
+		table, ok := from.(*schemadiff.CreateTableEntity)
+		if !ok || table == nil {
+			return nil
+		}
+		for _, col := range table.TableSpec.Columns {
+			if strings.EqualFold(col.Name.String(), name) {
+				return col
+			}
+		}
+		return nil
+	}
+	for _, opt := range stmt.AlterOptions {
+		switch opt := opt.(type) {
+		case *sqlparser.DropColumn, *sqlparser.DropKey:
+			return true
+		case *sqlparser.ModifyColumn:
+			if col := fromColumn(opt.NewColDefinition.Name.String()); col != nil && narrowsType(col.Type, opt.NewColDefinition.Type) {
+				return true
+			}
+		case *sqlparser.ChangeColumn:
+			if col := fromColumn(opt.OldColumn.Name.String()); col != nil && narrowsType(col.Type, opt.NewColDefinition.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SummarizeDiff returns the summary of the given diff. The diff is destructive if it, or any of its subsequent
+// diffs, is.
+func SummarizeDiff(d schemadiff.EntityDiff) *DiffSummary {
+	summary := &DiffSummary{Entity: d.EntityName()}
+	switch stmt := d.Statement().(type) {
+	case *sqlparser.CreateTable:
+		summary.Change = "create table"
+	case *sqlparser.AlterTable:
+		summary.Change = "alter table"
+		summary.Algorithm = "INPLACE/COPY"
+		if d.InstantDDLCapability() == schemadiff.InstantDDLCapabilityPossible {
+			summary.Algorithm = "INSTANT"
+		}
+		summary.Destructive = destructiveAlter(d, stmt)
+	case *sqlparser.DropTable:
+		summary.Change = "drop table"
+		summary.Destructive = true
+	case *sqlparser.RenameTable:
+		summary.Change = "rename table"
+	case *sqlparser.CreateView:
+		summary.Change = "create view"
+	case *sqlparser.AlterView:
+		summary.Change = "alter view"
+	case *sqlparser.DropView:
+		summary.Change = "drop view"
+		summary.Destructive = true
+	default:
+		summary.Change = "change"
+	}
+	for subsequent := d.SubsequentDiff(); subsequent != nil; subsequent = subsequent.SubsequentDiff() {
+		if stmt, ok := subsequent.Statement().(*sqlparser.AlterTable); ok && destructiveAlter(subsequent, stmt) {
+			summary.Destructive = true
+		}
+	}
+	return summary
+}
+
+// summarizePreamble returns the summaries of the preamble statements of a result, which rename tables and columns,
+// and redefine views: a summary per renamed table, and per table or view altered.
+func summarizePreamble(preamble []string) []*DiffSummary {
+	if len(preamble) == 0 {
+		return nil
+	}
+	parser, err := sqlparser.New(sqlparser.Options{MySQLServerVersion: DefaultMySQLVersion})
+	if err != nil {
+		return nil
+	}
+	var summaries []*DiffSummary
+	for _, statement := range preamble {
+		stmt, err := parser.ParseStrictDDL(statement)
+		if err != nil {
+			summaries = append(summaries, &DiffSummary{Change: "change"})
+			continue
+		}
+		switch stmt := stmt.(type) {
+		case *sqlparser.RenameTable:
+			for _, pair := range stmt.TablePairs {
+				summaries = append(summaries, &DiffSummary{Entity: pair.FromTable.Name.String(), Change: "rename table"})
+			}
+		case *sqlparser.AlterTable:
+			summaries = append(summaries, &DiffSummary{Entity: stmt.Table.Name.String(), Change: "rename column"})
+		case *sqlparser.AlterView:
+			summaries = append(summaries, &DiffSummary{Entity: stmt.ViewName.Name.String(), Change: "alter view"})
+		default:
+			summaries = append(summaries, &DiffSummary{Change: "change"})
+		}
+	}
+	return summaries
+}
+
+// truncateLines cuts the given text at a line boundary, so that it is no longer than maxSize bytes. It returns
+// the number of lines cut.
+func truncateLines(text string, maxSize int) (string, int) {
+	if len(text) <= maxSize {
+		return text, 0
+	}
+	cut := strings.LastIndex(text[:maxSize], "\n") + 1
+	return text[:cut], strings.Count(strings.TrimSuffix(text[cut:], "\n"), "\n") + 1
+}
+
+// markdownFence returns a code fence for the given text: three backticks, or more if the text contains three.
+func markdownFence(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// markdownCodeBlock returns the given text as a fenced code block, cut to maxSize bytes with a note.
+func markdownCodeBlock(language string, text string, maxSize int) string {
+	text, cutLines := truncateLines(text, maxSize)
+	fence := markdownFence(text)
+	block := fmt.Sprintf("%s%s\n%s%s\n", fence, language, text, fence)
+	if cutLines > 0 {
+		block += fmt.Sprintf("\n_%d more lines truncated._\n", cutLines)
+	}
+	return block
+}
+
+// markdownCell escapes a value for a markdown table cell.
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// migrationScript returns the preamble statements and the diffs of the result, one statement per line.
+func (r *Result) migrationScript() string {
+	var b strings.Builder
+	for _, stmt := range r.Preamble {
+		fmt.Fprintf(&b, "%s;\n", stmt)
+	}
+	for _, d := range r.Diffs {
+		fmt.Fprintf(&b, "%s;\n", d.CanonicalStatementString())
+	}
+	return b.String()
+}
+
+// WriteMarkdown writes the diffs of the result as a markdown report, fit for a pull request comment: a summary
+// table of the diffs, a collapsible section per entity with its annotated diff, and the statements in a SQL code
+// block. Large sections are truncated, so that the report does not exceed MarkdownMaxSize. The annotated diffs are
+// truncated first, and the statements are shown in full if they fit.
+func (r *Result) WriteMarkdown(w io.Writer) error {
+	if len(r.Preamble) == 0 && len(r.Diffs) == 0 {
+		_, err := io.WriteString(w, "No schema changes.\n")
+		return err
+	}
+	summaries := summarizePreamble(r.Preamble)
+	for _, d := range r.Diffs {
+		summaries = append(summaries, SummarizeDiff(d))
+	}
+	var table strings.Builder
+	table.WriteString("| Entity | Change | Algorithm | Destructive |\n|---|---|---|---|\n")
+	for i, summary := range summaries {
+		algorithm, destructive := summary.Algorithm, "no"
+		if algorithm == "" {
+			algorithm = "-"
+		}
+		if summary.Destructive {
+			destructive = "**yes**"
+		}
+		row := fmt.Sprintf("| `%s` | %s | %s | %s |\n", markdownCell(summary.Entity), summary.Change, algorithm, destructive)
+		if table.Len()+len(row) > MarkdownMaxSize/4 {
+			fmt.Fprintf(&table, "\n_%d more entities not listed._\n", len(summaries)-i)
+			break
+		}
+		table.WriteString(row)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Schema changes\n\n%s\n", table.String())
+	budget := MarkdownMaxSize - b.Len() - 256
+	// The statements are cut only if they do not fit along with the summary table, leaving room for the code fence
+	// and the truncation note
+	migration := "#### Migration\n\n" + markdownCodeBlock("sql", r.migrationScript(), budget-128)
+	budget -= len(migration)
+	for i, d := range r.Diffs {
+		summary := SummarizeDiff(d)
+		_, _, unified := d.Annotated()
+		section := fmt.Sprintf("<details>\n<summary><code>%s</code>: %s</summary>\n\n%s\n</details>\n\n",
+			html.EscapeString(summary.Entity), summary.Change,
+			markdownCodeBlock("diff", unified.Export()+"\n", markdownMaxSectionSize))
+		if len(section) > budget {
+			fmt.Fprintf(&b, "_The annotated diff of %d more entities is not shown, to keep within the comment size limit._\n\n", len(r.Diffs)-i)
+			break
+		}
+		b.WriteString(section)
+		budget -= len(section)
+	}
+	b.WriteString(migration)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
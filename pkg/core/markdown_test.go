@@ -0,0 +1,250 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+func TestTruncateLines(t *testing.T) {
+	tcases := []struct {
+		text      string
+		maxSize   int
+		expect    string
+		expectCut int
+	}{
+		{text: "a\nb\n", maxSize: 10, expect: "a\nb\n"},
+		{text: "a\nb\nc\n", maxSize: 4, expect: "a\nb\n", expectCut: 1},
+		{text: "a\nb\nc\nd", maxSize: 5, expect: "a\nb\n", expectCut: 2},
+		{text: "abc\n", maxSize: 2, expect: "", expectCut: 1},
+	}
+	for _, tcase := range tcases {
+		t.Run(fmt.Sprintf("%q/%d", tcase.text, tcase.maxSize), func(t *testing.T) {
+			text, cut := truncateLines(tcase.text, tcase.maxSize)
+			assert.Equal(t, tcase.expect, text)
+			assert.Equal(t, tcase.expectCut, cut)
+		})
+	}
+}
+
+func TestMarkdownCodeBlock(t *testing.T) {
+	assert.Equal(t, "```sql\nSELECT 1;\n```\n", markdownCodeBlock("sql", "SELECT 1;\n", 100))
+	assert.Equal(t, "````sql\nCOMMENT '```';\n````\n", markdownCodeBlock("sql", "COMMENT '```';\n", 100))
+	assert.Equal(t, "```diff\n+a\n```\n\n_2 more lines truncated._\n", markdownCodeBlock("diff", "+a\n+b\n+c\n", 4))
+}
+
+func TestSummarizeDiff(t *testing.T) {
+	ctx := context.Background()
+	env := schemadiff.NewTestEnv()
+	from, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table t1 (id int primary key, name varchar(12))",
+		"create table t2 (id int primary key, c int)",
+		"create table t4 (id int primary key)",
+		"create view v1 as select id from t4",
+		"create view v3 as select id from t1",
+	})
+	require.NoError(t, err)
+	to, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table t1 (id int primary key, name varchar(12), key name_idx (name))",
+		"create table t2 (id int primary key)",
+		"create table t3 (id int primary key)",
+		"create view v2 as select id from t3",
+		"create view v3 as select id, name from t1",
+	})
+	require.NoError(t, err)
+	diff, err := from.SchemaDiff(to, DefaultDiffHints())
+	require.NoError(t, err)
+	diffs, err := diff.OrderedDiffs(ctx)
+	require.NoError(t, err)
+
+	expect := map[string]DiffSummary{
+		"t1": {Entity: "t1", Change: "alter table", Algorithm: "INPLACE/COPY"},
+		"t2": {Entity: "t2", Change: "alter table", Algorithm: "INSTANT", Destructive: true},
+		"t3": {Entity: "t3", Change: "create table"},
+		"t4": {Entity: "t4", Change: "drop table", Destructive: true},
+		"v1": {Entity: "v1", Change: "drop view", Destructive: true},
+		"v2": {Entity: "v2", Change: "create view"},
+		"v3": {Entity: "v3", Change: "alter view"},
+	}
+	require.Len(t, diffs, len(expect))
+	for _, d := range diffs {
+		assert.Equal(t, expect[d.EntityName()], *SummarizeDiff(d))
+	}
+}
+
+func TestNarrowsType(t *testing.T) {
+	length := func(n int) *int { return &n }
+	tcases := []struct {
+		name   string
+		from   *sqlparser.ColumnType
+		to     *sqlparser.ColumnType
+		expect bool
+	}{
+		{name: "same", from: &sqlparser.ColumnType{Type: "int"}, to: &sqlparser.ColumnType{Type: "int"}},
+		{name: "wider integer", from: &sqlparser.ColumnType{Type: "int"}, to: &sqlparser.ColumnType{Type: "bigint"}},
+		{name: "narrower integer", from: &sqlparser.ColumnType{Type: "bigint"}, to: &sqlparser.ColumnType{Type: "int"}, expect: true},
+		{name: "display width", from: &sqlparser.ColumnType{Type: "int", Length: length(11)}, to: &sqlparser.ColumnType{Type: "int", Length: length(10)}},
+		{name: "signedness", from: &sqlparser.ColumnType{Type: "int", Unsigned: true}, to: &sqlparser.ColumnType{Type: "int"}, expect: true},
+		{name: "longer varchar", from: &sqlparser.ColumnType{Type: "varchar", Length: length(20)}, to: &sqlparser.ColumnType{Type: "varchar", Length: length(40)}},
+		{name: "shorter varchar", from: &sqlparser.ColumnType{Type: "varchar", Length: length(40)}, to: &sqlparser.ColumnType{Type: "varchar", Length: length(20)}, expect: true},
+		{name: "char to varchar", from: &sqlparser.ColumnType{Type: "char", Length: length(10)}, to: &sqlparser.ColumnType{Type: "varchar", Length: length(10)}},
+		{name: "varchar to text", from: &sqlparser.ColumnType{Type: "varchar", Length: length(200)}, to: &sqlparser.ColumnType{Type: "text"}},
+		{name: "text to varchar", from: &sqlparser.ColumnType{Type: "mediumtext"}, to: &sqlparser.ColumnType{Type: "varchar", Length: length(200)}, expect: true},
+		{name: "lower scale", from: &sqlparser.ColumnType{Type: "decimal", Length: length(10), Scale: length(4)}, to: &sqlparser.ColumnType{Type: "decimal", Length: length(10), Scale: length(2)}, expect: true},
+		{name: "higher scale", from: &sqlparser.ColumnType{Type: "decimal", Length: length(10), Scale: length(2)}, to: &sqlparser.ColumnType{Type: "decimal", Length: length(10), Scale: length(4)}, expect: true},
+		{name: "higher precision and scale", from: &sqlparser.ColumnType{Type: "decimal", Length: length(10), Scale: length(2)}, to: &sqlparser.ColumnType{Type: "decimal", Length: length(12), Scale: length(4)}},
+		{name: "double to float", from: &sqlparser.ColumnType{Type: "double"}, to: &sqlparser.ColumnType{Type: "float"}, expect: true},
+		{name: "added enum value", from: &sqlparser.ColumnType{Type: "enum", EnumValues: []string{"'a'"}}, to: &sqlparser.ColumnType{Type: "enum", EnumValues: []string{"'a'", "'b'"}}},
+		{name: "removed enum value", from: &sqlparser.ColumnType{Type: "enum", EnumValues: []string{"'a'", "'b'"}}, to: &sqlparser.ColumnType{Type: "enum", EnumValues: []string{"'a'"}}, expect: true},
+		{name: "other family", from: &sqlparser.ColumnType{Type: "varchar", Length: length(20)}, to: &sqlparser.ColumnType{Type: "int"}, expect: true},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			assert.Equal(t, tcase.expect, narrowsType(tcase.from, tcase.to))
+		})
+	}
+}
+
+func TestSummarizeDiffDestructive(t *testing.T) {
+	env := schemadiff.NewTestEnv()
+	tcases := []struct {
+		name   string
+		from   string
+		to     string
+		expect bool
+	}{
+		{
+			name: "add index",
+			from: "create table t (id int primary key, a int)",
+			to:   "create table t (id int primary key, a int, key a_idx (a))",
+		},
+		{
+			name:   "drop index",
+			from:   "create table t (id int primary key, a int, key a_idx (a))",
+			to:     "create table t (id int primary key, a int)",
+			expect: true,
+		},
+		{
+			name:   "drop foreign key",
+			from:   "create table t (id int primary key, p_id int, key p_idx (p_id), constraint t_p_fk foreign key (p_id) references p (id))",
+			to:     "create table t (id int primary key, p_id int, key p_idx (p_id))",
+			expect: true,
+		},
+		{
+			name:   "drop check constraint",
+			from:   "create table t (id int primary key, a int, constraint a_chk check (a > 0))",
+			to:     "create table t (id int primary key, a int)",
+			expect: true,
+		},
+		{
+			name: "widen column",
+			from: "create table t (id int primary key, a varchar(20))",
+			to:   "create table t (id int primary key, a varchar(40))",
+		},
+		{
+			name:   "narrow column",
+			from:   "create table t (id int primary key, a bigint)",
+			to:     "create table t (id int primary key, a int)",
+			expect: true,
+		},
+		{
+			name:   "drop partition",
+			from:   "create table t (id int primary key) partition by range (id) (partition p1 values less than (10), partition p2 values less than (20))",
+			to:     "create table t (id int primary key) partition by range (id) (partition p2 values less than (20))",
+			expect: true,
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			d, err := schemadiff.DiffCreateTablesQueries(env, tcase.from, tcase.to, DefaultDiffHints())
+			require.NoError(t, err)
+			require.False(t, d.IsEmpty())
+			assert.Equal(t, tcase.expect, SummarizeDiff(d).Destructive, d.CanonicalStatementString())
+		})
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	ctx := context.Background()
+	runner, err := NewRunner(nil)
+	require.NoError(t, err)
+
+	t.Run("report", func(t *testing.T) {
+		fileFrom := writeSchemaFile(t, []string{
+			"create table t1 (id int primary key, name varchar(12))",
+			"create table t2 (id int primary key)",
+		})
+		defer os.RemoveAll(fileFrom)
+		fileTo := writeSchemaFile(t, []string{
+			"create table t1 (id int primary key, name varchar(12), key name_idx (name))",
+		})
+		defer os.RemoveAll(fileTo)
+
+		output, err := Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{Output: "markdown"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(output, "### Schema changes\n\n| Entity | Change | Algorithm | Destructive |\n|---|---|---|---|\n"), output)
+		assert.Contains(t, output, "| `t1` | alter table | INPLACE/COPY | no |\n")
+		assert.Contains(t, output, "| `t2` | drop table | - | **yes** |\n")
+		assert.Contains(t, output, "<details>\n<summary><code>t1</code>: alter table</summary>\n\n```diff\n")
+		assert.Contains(t, output, "+\tKEY `name_idx` (`name`)")
+		assert.True(t, strings.HasSuffix(output, "#### Migration\n\n```sql\nDROP TABLE `t2`;\nALTER TABLE `t1` ADD KEY `name_idx` (`name`);\n```\n"), output)
+	})
+	t.Run("renames", func(t *testing.T) {
+		fileFrom := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(12))"})
+		defer os.RemoveAll(fileFrom)
+		fileTo := writeSchemaFile(t, []string{"create table users (id int primary key, full_name varchar(12))"})
+		defer os.RemoveAll(fileTo)
+
+		output, err := Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{Output: "markdown", Mapping: []string{"t1=users", "t1.name=full_name"}})
+		require.NoError(t, err)
+		assert.Contains(t, output, "| `t1` | rename table | - | no |\n| `users` | rename column | - | no |\n")
+	})
+	t.Run("no changes", func(t *testing.T) {
+		var b strings.Builder
+		require.NoError(t, (&Result{}).WriteMarkdown(&b))
+		assert.Equal(t, "No schema changes.\n", b.String())
+	})
+	t.Run("size limit", func(t *testing.T) {
+		var fromQueries []string
+		for i := 0; i < 200; i++ {
+			var columns []string
+			for j := 0; j < 150; j++ {
+				columns = append(columns, fmt.Sprintf("column_with_a_long_name_%d varchar(255) not null default 'some default value'", j))
+			}
+			fromQueries = append(fromQueries, fmt.Sprintf("create table t%d (id int primary key, %s)", i, strings.Join(columns, ", ")))
+		}
+		fileFrom := writeSchemaFile(t, fromQueries)
+		defer os.RemoveAll(fileFrom)
+		fileTo := writeSchemaFile(t, []string{"create table t (id int primary key)"})
+		defer os.RemoveAll(fileTo)
+
+		result, err := runner.Run(ctx, CommandOrderedDiff, &Request{Source: fileFrom, Target: fileTo})
+		require.NoError(t, err)
+		var b strings.Builder
+		require.NoError(t, result.WriteMarkdown(&b))
+		output := b.String()
+		assert.LessOrEqual(t, len(output), MarkdownMaxSize)
+		assert.Contains(t, output, "more lines truncated._")
+		assert.Contains(t, output, "more entities is not shown, to keep within the comment size limit._")
+		require.Contains(t, output, "#### Migration\n\n```sql\n")
+		// The statements are shown in full, as they fit
+		migration := output[strings.Index(output, "#### Migration\n\n```sql\n"):]
+		for i := 0; i < 200; i++ {
+			assert.Contains(t, migration, fmt.Sprintf("DROP TABLE `t%d`;\n", i))
+		}
+		assert.NotContains(t, migration, "truncated")
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Exec(ctx, "load", "", "", &ExecOptions{Output: "markdown"})
+		assert.ErrorContains(t, err, "--output markdown applies to diff commands, not to load")
+		_, err = Exec(ctx, "diff", "", "", &ExecOptions{Output: "markdown", Textual: true})
+		assert.ErrorContains(t, err, "--output markdown is not supported with --textual")
+	})
+}
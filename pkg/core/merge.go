@@ -0,0 +1,215 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+// MergeConflict describes an entity which both sides of a three-way merge change in incompatible ways.
+type MergeConflict struct {
+	Entity string
+	Reason string
+	Ours   string
+	Theirs string
+}
+
+// ErrMergeConflicts is returned by MergeSchemas when the two sides of the merge conflict.
+type ErrMergeConflicts struct {
+	Conflicts []*MergeConflict
+}
+
+func (e *ErrMergeConflicts) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "merge conflicts in %d entities:", len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		fmt.Fprintf(&b, "\n- %s: %s\n  ours:   %s\n  theirs: %s", conflict.Entity, conflict.Reason, conflict.Ours, conflict.Theirs)
+	}
+	return b.String()
+}
+
+// alterTableTouches returns the set of columns, keys, constraints and table options an ALTER TABLE statement changes.
+// A column positioned AFTER another column touches that column too, since dropping or renaming it breaks the position.
+func alterTableTouches(alterTable *sqlparser.AlterTable) map[string]bool {
+	touches := map[string]bool{}
+	column := func(name sqlparser.IdentifierCI) {
+		touches[fmt.Sprintf("column %s", name.Lowered())] = true
+	}
+	key := func(name string) {
+		touches[fmt.Sprintf("key %s", strings.ToLower(name))] = true
+	}
+	constraint := func(name string) {
+		touches[fmt.Sprintf("constraint %s", strings.ToLower(name))] = true
+	}
+	position := func(first bool, after *sqlparser.ColName) {
+		switch {
+		case first:
+			touches["first column"] = true
+		case after != nil:
+			column(after.Name)
+		}
+	}
+	for _, opt := range alterTable.AlterOptions {
+		switch opt := opt.(type) {
+		case *sqlparser.AddColumns:
+			for _, col := range opt.Columns {
+				column(col.Name)
+			}
+			position(opt.First, opt.After)
+		case *sqlparser.DropColumn:
+			column(opt.Name.Name)
+		case *sqlparser.ModifyColumn:
+			column(opt.NewColDefinition.Name)
+			position(opt.First, opt.After)
+		case *sqlparser.ChangeColumn:
+			column(opt.OldColumn.Name)
+			column(opt.NewColDefinition.Name)
+			position(opt.First, opt.After)
+		case *sqlparser.RenameColumn:
+			column(opt.OldName.Name)
+			column(opt.NewName.Name)
+		case *sqlparser.AlterColumn:
+			column(opt.Column.Name)
+		case *sqlparser.AddIndexDefinition:
+			key(opt.IndexDefinition.Info.Name.String())
+		case *sqlparser.RenameIndex:
+			key(opt.OldName.String())
+			key(opt.NewName.String())
+		case *sqlparser.AlterIndex:
+			key(opt.Name.String())
+		case *sqlparser.DropKey:
+			switch opt.Type {
+			case sqlparser.PrimaryKeyType:
+				key("PRIMARY")
+			case sqlparser.NormalKeyType:
+				key(opt.Name.String())
+			default:
+				constraint(opt.Name.String())
+			}
+		case *sqlparser.AddConstraintDefinition:
+			constraint(opt.ConstraintDefinition.Name.String())
+		case *sqlparser.AlterCheck:
+			constraint(opt.Name.String())
+		case sqlparser.TableOptions:
+			for _, tableOption := range opt {
+				touches[fmt.Sprintf("option %s", strings.ToLower(tableOption.Name))] = true
+			}
+		default:
+			touches[fmt.Sprintf("option %s", strings.ToLower(sqlparser.CanonicalString(opt)))] = true
+		}
+	}
+	if alterTable.PartitionSpec != nil || alterTable.PartitionOption != nil {
+		touches["partitioning"] = true
+	}
+	return touches
+}
+
+// mergeConflict checks whether two diffs of the same entity, one per side of the merge, can both apply.
+// It returns nil when they can. Otherwise it returns a description of the conflict.
+func mergeConflict(ours schemadiff.EntityDiff, theirs schemadiff.EntityDiff) *MergeConflict {
+	conflict := &MergeConflict{
+		Entity: ours.EntityName(),
+		Ours:   ours.CanonicalStatementString(),
+		Theirs: theirs.CanonicalStatementString(),
+	}
+	oursAlter, ok := ours.Statement().(*sqlparser.AlterTable)
+	if !ok {
+		conflict.Reason = "both sides change the entity"
+		return conflict
+	}
+	theirsAlter, ok := theirs.Statement().(*sqlparser.AlterTable)
+	if !ok {
+		conflict.Reason = "both sides change the entity"
+		return conflict
+	}
+	if ours.SubsequentDiff() != nil || theirs.SubsequentDiff() != nil {
+		conflict.Reason = "both sides change the table in multiple steps"
+		return conflict
+	}
+	theirsTouches := alterTableTouches(theirsAlter)
+	var common []string
+	for touch := range alterTableTouches(oursAlter) {
+		if theirsTouches[touch] {
+			common = append(common, touch)
+		}
+	}
+	if len(common) == 0 {
+		return nil
+	}
+	sort.Strings(common)
+	conflict.Reason = fmt.Sprintf("both sides change %s", strings.Join(common, ", "))
+	return conflict
+}
+
+// MergeSchemas runs a three-way merge: it computes the diff from the base schema to each of ours and theirs,
+// and applies both diffs onto the base schema. An entity changed by both sides is merged only if the changes
+// are identical, or if both are ALTER TABLE changes that do not touch the same columns, keys, constraints or
+// table options. The function returns *ErrMergeConflicts listing all conflicting entities otherwise.
+// Inputs can be stdin, file, directory, or MySQL URI.
+func MergeSchemas(ctx context.Context, env *schemadiff.Environment, baseInputSourceValue string, oursInputSourceValue string, theirsInputSourceValue string, hints *schemadiff.DiffHints, readOpts *base.ReadOptions) (*schemadiff.Schema, error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
+	baseSchema, err := base.ReadSchemaFromSource(ctx, env, baseInputSourceValue, readOpts)
+	if err != nil {
+		return nil, err
+	}
+	oursSchema, err := base.ReadSchemaFromSource(ctx, env, oursInputSourceValue, readOpts)
+	if err != nil {
+		return nil, err
+	}
+	theirsSchema, err := base.ReadSchemaFromSource(ctx, env, theirsInputSourceValue, readOpts)
+	if err != nil {
+		return nil, err
+	}
+	oursDiff, err := baseSchema.SchemaDiff(oursSchema, hints)
+	if err != nil {
+		return nil, err
+	}
+	theirsDiff, err := baseSchema.SchemaDiff(theirsSchema, hints)
+	if err != nil {
+		return nil, err
+	}
+	oursDiffs := oursDiff.UnorderedDiffs()
+	theirsDiffs := theirsDiff.UnorderedDiffs()
+
+	oursByEntity := map[string]schemadiff.EntityDiff{}
+	for _, d := range oursDiffs {
+		oursByEntity[d.EntityName()] = d
+	}
+	diffs := append([]schemadiff.EntityDiff{}, oursDiffs...)
+	conflicts := &ErrMergeConflicts{}
+	for _, theirs := range theirsDiffs {
+		ours, ok := oursByEntity[theirs.EntityName()]
+		if !ok {
+			diffs = append(diffs, theirs)
+			continue
+		}
+		if ours.CanonicalStatementString() == theirs.CanonicalStatementString() {
+			// Both sides made the same change
+			continue
+		}
+		if conflict := mergeConflict(ours, theirs); conflict != nil {
+			conflicts.Conflicts = append(conflicts.Conflicts, conflict)
+			continue
+		}
+		diffs = append(diffs, theirs)
+	}
+	if len(conflicts.Conflicts) > 0 {
+		sort.Slice(conflicts.Conflicts, func(i, j int) bool {
+			return conflicts.Conflicts[i].Entity < conflicts.Conflicts[j].Entity
+		})
+		return nil, conflicts
+	}
+	merged, err := baseSchema.Apply(diffs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot apply both sides of the merge: %w", err)
+	}
+	return merged, nil
+}
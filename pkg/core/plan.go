@@ -0,0 +1,414 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// PlanPolicy is an expand/contract policy of the plan command, which defers some of the changes of a diff to
+// later phases, so that each phase can deploy on its own.
+type PlanPolicy string
+
+const (
+	// PlanNullableFirst adds NOT NULL columns without a default, and makes nullable columns NOT NULL, as nullable
+	// columns in the expand phase. A backfill phase follows, and the contract phase makes the columns NOT NULL.
+	PlanNullableFirst PlanPolicy = "nullable-first"
+	// PlanIndexBeforeDrop keeps the indexes a table drops until the contract phase, so that the table's new
+	// indexes are created before its old indexes are dropped.
+	PlanIndexBeforeDrop PlanPolicy = "index-before-drop"
+	// PlanDropFKsFirst drops the foreign keys of dropped tables, and those referencing them, in the expand phase,
+	// and the tables in a final drop phase.
+	PlanDropFKsFirst PlanPolicy = "drop-fks-first"
+	// PlanDropColumnsLast keeps the columns a table drops, and the types of the columns it narrows, until the
+	// contract phase, so that code deployed after the expand phase can stop using them first. Dropped columns
+	// are not kept with heuristic column renames, as a renamed column would not be told from a dropped one.
+	PlanDropColumnsLast PlanPolicy = "drop-columns-last"
+	// planPoliciesNone is the value of ParsePlanPolicies which disables all policies
+	planPoliciesNone = "none"
+)
+
+// Plan phases, in the order they apply
+const (
+	// PlanPhaseExpand applies all changes which the policies do not defer
+	PlanPhaseExpand = "expand"
+	// PlanPhaseBackfill has no statements. It lists the columns to backfill before the contract phase.
+	PlanPhaseBackfill = "backfill"
+	// PlanPhaseContract makes backfilled columns NOT NULL, drops the indexes kept by PlanIndexBeforeDrop, and drops
+	// or narrows the columns kept by PlanDropColumnsLast
+	PlanPhaseContract = "contract"
+	// PlanPhaseDrop drops the tables kept by PlanDropFKsFirst
+	PlanPhaseDrop = "drop"
+)
+
+// DefaultPlanPolicies returns all plan policies.
+func DefaultPlanPolicies() []PlanPolicy {
+	return []PlanPolicy{PlanNullableFirst, PlanIndexBeforeDrop, PlanDropFKsFirst, PlanDropColumnsLast}
+}
+
+// ParsePlanPolicies parses policy names, as given to --plan-policies. The single value "none" disables all
+// policies.
+func ParsePlanPolicies(values []string) ([]PlanPolicy, error) {
+	policies := []PlanPolicy{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		switch PlanPolicy(value) {
+		case PlanNullableFirst, PlanIndexBeforeDrop, PlanDropFKsFirst, PlanDropColumnsLast:
+			policies = append(policies, PlanPolicy(value))
+		case planPoliciesNone:
+			if len(values) > 1 {
+				return nil, fmt.Errorf("plan policy %s cannot be combined with other policies", planPoliciesNone)
+			}
+		default:
+			return nil, fmt.Errorf("unknown plan policy %q, expected %s, %s, %s, %s or %s", value, PlanNullableFirst, PlanIndexBeforeDrop, PlanDropFKsFirst, PlanDropColumnsLast, planPoliciesNone)
+		}
+	}
+	return policies, nil
+}
+
+// PlanPhase is a step of a plan, deployed on its own.
+type PlanPhase struct {
+	// Name is one of PlanPhaseExpand, PlanPhaseBackfill, PlanPhaseContract and PlanPhaseDrop
+	Name string
+	// Notes describe what the phase defers to later phases, or, in the backfill phase, the columns to backfill
+	Notes []string
+	// Diffs are the diffs of the phase, in an order valid to apply
+	Diffs []schemadiff.EntityDiff
+}
+
+// Plan splits the diff between two schemas into phases, deployed one after the other.
+type Plan struct {
+	Phases []*PlanPhase
+	// Remaining are the diffs between the target schema and the result of applying all phases in sequence onto
+	// the source schema. It is empty when the plan is valid.
+	Remaining []string
+}
+
+// Valid returns true if applying all phases in sequence onto the source schema yields the target schema.
+func (p *Plan) Valid() bool {
+	return len(p.Remaining) == 0
+}
+
+// phaseSQL returns the migration of the phase at index i: a comment header with the phase's notes, followed by
+// its statements.
+func (p *Plan) phaseSQL(i int) string {
+	var b strings.Builder
+	phase := p.Phases[i]
+	fmt.Fprintf(&b, "-- phase %d of %d: %s\n", i+1, len(p.Phases), phase.Name)
+	for _, note := range phase.Notes {
+		fmt.Fprintf(&b, "-- %s\n", note)
+	}
+	for _, d := range phase.Diffs {
+		fmt.Fprintf(&b, "%s;\n", d.CanonicalStatementString())
+	}
+	return b.String()
+}
+
+// phaseFileName returns the migration file name of the phase at index i, e.g. "002_backfill.sql"
+func (p *Plan) phaseFileName(i int) string {
+	return fmt.Sprintf("%03d_%s.sql", i+1, p.Phases[i].Name)
+}
+
+func (p *Plan) String() string {
+	var b strings.Builder
+	for i := range p.Phases {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(p.phaseSQL(i))
+	}
+	if !p.Valid() {
+		if len(p.Phases) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("-- plan does not reach the target schema, which differs by:\n")
+		for _, d := range p.Remaining {
+			fmt.Fprintf(&b, "%s;\n", d)
+		}
+	}
+	return b.String()
+}
+
+// WriteFiles writes each phase as a migration file into the given directory, which is created if needed, and
+// returns the names of the written files.
+func (p *Plan) WriteFiles(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var fileNames []string
+	for i := range p.Phases {
+		fileName := filepath.Join(dir, p.phaseFileName(i))
+		if err := os.WriteFile(fileName, []byte(p.phaseSQL(i)), 0644); err != nil {
+			return fileNames, err
+		}
+		fileNames = append(fileNames, fileName)
+	}
+	return fileNames, nil
+}
+
+// isNotNull returns whether the column is declared NOT NULL
+func isNotNull(col *sqlparser.ColumnDefinition) bool {
+	return col.Type.Options != nil && col.Type.Options.Null != nil && !*col.Type.Options.Null
+}
+
+// needsBackfill returns whether PlanNullableFirst defers the NOT NULL of a target column, given the column of
+// the same name in the source table, if any: the column is NOT NULL in the target, but absent from the source
+// without a default to fill it with, or nullable in the source. Primary key, auto increment and generated
+// columns are never deferred.
+func needsBackfill(col *sqlparser.ColumnDefinition, sourceCol *sqlparser.ColumnDefinition, primaryKey map[string]bool) bool {
+	if !isNotNull(col) || primaryKey[col.Name.Lowered()] {
+		return false
+	}
+	if col.Type.Options.Autoincrement || col.Type.Options.As != nil {
+		return false
+	}
+	if sourceCol == nil {
+		return col.Type.Options.Default == nil
+	}
+	return !isNotNull(sourceCol)
+}
+
+// foreignKeys returns the names of the foreign keys of a table
+func foreignKeys(stmt *sqlparser.CreateTable) (names []string) {
+	for _, constraint := range stmt.TableSpec.Constraints {
+		if _, ok := constraint.Details.(*sqlparser.ForeignKeyDefinition); ok {
+			names = append(names, constraint.Name.String())
+		}
+	}
+	return names
+}
+
+// withoutForeignKeys returns a copy of the table, without its foreign keys
+func withoutForeignKeys(stmt *sqlparser.CreateTable) *sqlparser.CreateTable {
+	stmt = sqlparser.CloneRefOfCreateTable(stmt)
+	var constraints []*sqlparser.ConstraintDefinition
+	for _, constraint := range stmt.TableSpec.Constraints {
+		if _, ok := constraint.Details.(*sqlparser.ForeignKeyDefinition); !ok {
+			constraints = append(constraints, constraint)
+		}
+	}
+	stmt.TableSpec.Constraints = constraints
+	return stmt
+}
+
+// planner builds the intermediate schemas of a plan.
+type planner struct {
+	policies map[PlanPolicy]bool
+	// columnRenames is true when the diff renames columns heuristically
+	columnRenames bool
+	// backfill lists the columns to backfill, as "table.column"
+	backfill []string
+	// notes describe the changes which the expand phase defers
+	notes []string
+}
+
+// expandTable returns the table in the expand phase: the target table, with the changes deferred by the
+// policies undone.
+func (p *planner) expandTable(source *sqlparser.CreateTable, target *sqlparser.CreateTable) *sqlparser.CreateTable {
+	stmt := sqlparser.CloneRefOfCreateTable(target)
+	tableName := stmt.Table.Name.String()
+	sourceColumns := map[string]*sqlparser.ColumnDefinition{}
+	for _, col := range source.TableSpec.Columns {
+		sourceColumns[col.Name.Lowered()] = col
+	}
+	columns := map[string]bool{}
+	primaryKey := map[string]bool{}
+	indexes := map[string]bool{}
+	for _, col := range stmt.TableSpec.Columns {
+		columns[col.Name.Lowered()] = true
+	}
+	for _, idx := range stmt.TableSpec.Indexes {
+		indexes[idx.Info.Name.Lowered()] = true
+		if idx.Info.Type != sqlparser.IndexTypePrimary {
+			continue
+		}
+		for _, col := range idx.Columns {
+			primaryKey[col.Column.Lowered()] = true
+		}
+	}
+
+	if p.policies[PlanDropColumnsLast] {
+		for _, col := range stmt.TableSpec.Columns {
+			sourceCol := sourceColumns[col.Name.Lowered()]
+			if sourceCol == nil || !narrowsType(sourceCol.Type, col.Type) {
+				continue
+			}
+			colType := sqlparser.CloneRefOfColumnType(sourceCol.Type)
+			colType.Options = col.Type.Options
+			col.Type = colType
+			p.notes = append(p.notes, fmt.Sprintf("keeps the type of %s.%s; the %s phase narrows it", tableName, col.Name.String(), PlanPhaseContract))
+		}
+	}
+	if p.policies[PlanDropColumnsLast] && !p.columnRenames {
+		// A dropped column is kept in its source position, following the source column it follows
+		position := 0
+		for _, sourceCol := range source.TableSpec.Columns {
+			if i := slices.IndexFunc(stmt.TableSpec.Columns, func(col *sqlparser.ColumnDefinition) bool {
+				return col.Name.Lowered() == sourceCol.Name.Lowered()
+			}); i >= 0 {
+				position = i + 1
+				continue
+			}
+			col := sqlparser.CloneRefOfColumnDefinition(sourceCol)
+			stmt.TableSpec.Columns = slices.Insert(stmt.TableSpec.Columns, position, col)
+			position++
+			columns[col.Name.Lowered()] = true
+			column := fmt.Sprintf("%s.%s", tableName, col.Name.String())
+			if isNotNull(col) && col.Type.Options.Default == nil && !col.Type.Options.Autoincrement && col.Type.Options.As == nil {
+				// Rows inserted once the column is no longer written to must not fail
+				col.Type.Options.Null = nil
+				p.notes = append(p.notes, fmt.Sprintf("keeps column %s, as nullable; the %s phase drops it", column, PlanPhaseContract))
+			} else {
+				p.notes = append(p.notes, fmt.Sprintf("keeps column %s; the %s phase drops it", column, PlanPhaseContract))
+			}
+		}
+	}
+	if p.policies[PlanNullableFirst] {
+		for _, col := range stmt.TableSpec.Columns {
+			sourceCol := sourceColumns[col.Name.Lowered()]
+			if !needsBackfill(col, sourceCol, primaryKey) {
+				continue
+			}
+			col.Type.Options.Null = nil
+			column := fmt.Sprintf("%s.%s", tableName, col.Name.String())
+			p.backfill = append(p.backfill, column)
+			if sourceCol == nil {
+				p.notes = append(p.notes, fmt.Sprintf("adds %s as nullable; the %s phase makes it NOT NULL", column, PlanPhaseContract))
+			} else {
+				p.notes = append(p.notes, fmt.Sprintf("keeps %s nullable; the %s phase makes it NOT NULL", column, PlanPhaseContract))
+			}
+		}
+	}
+	if p.policies[PlanIndexBeforeDrop] {
+	nextIndex:
+		for _, idx := range source.TableSpec.Indexes {
+			if idx.Info.Type == sqlparser.IndexTypePrimary || indexes[idx.Info.Name.Lowered()] {
+				continue
+			}
+			// An index over a dropped column, or over an expression, is dropped along with the column
+			for _, col := range idx.Columns {
+				if col.Expression != nil || !columns[col.Column.Lowered()] {
+					continue nextIndex
+				}
+			}
+			stmt.TableSpec.Indexes = append(stmt.TableSpec.Indexes, sqlparser.CloneRefOfIndexDefinition(idx))
+			p.notes = append(p.notes, fmt.Sprintf("keeps index %s on %s; the %s phase drops it", idx.Info.Name.String(), tableName, PlanPhaseContract))
+		}
+	}
+	return stmt
+}
+
+// phaseDiffs returns the ordered diffs from one intermediate schema to the next
+func phaseDiffs(ctx context.Context, from *schemadiff.Schema, to *schemadiff.Schema, hints *schemadiff.DiffHints) ([]schemadiff.EntityDiff, error) {
+	diff, err := from.SchemaDiff(to, hints)
+	if err != nil {
+		return nil, err
+	}
+	return diff.OrderedDiffs(ctx)
+}
+
+// PlanMigration splits the diff from the source schema to the target schema into phases, according to the
+// given expand/contract policies: the expand phase, an optional backfill phase, the contract phase and the drop
+// phase. Each phase is the ordered diff between two intermediate schemas, and phases without changes are
+// omitted. The plan is validated by applying all phases in sequence onto the source schema; Plan.Remaining
+// lists any diffs left between the result and the target schema.
+func PlanMigration(ctx context.Context, env *schemadiff.Environment, source *schemadiff.Schema, target *schemadiff.Schema, hints *schemadiff.DiffHints, policies []PlanPolicy) (*Plan, error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
+	p := &planner{
+		policies:      map[PlanPolicy]bool{},
+		columnRenames: hints.ColumnRenameStrategy == schemadiff.ColumnRenameHeuristicStatement,
+	}
+	for _, policy := range policies {
+		p.policies[policy] = true
+	}
+
+	var expand, contract []sqlparser.Statement
+	for _, entity := range target.Entities() {
+		stmt := entity.Create().Statement()
+		contract = append(contract, stmt)
+		targetTable, ok := stmt.(*sqlparser.CreateTable)
+		sourceTable := source.Table(entity.Name())
+		if !ok || sourceTable == nil {
+			expand = append(expand, stmt)
+			continue
+		}
+		expand = append(expand, p.expandTable(sourceTable.CreateTable, targetTable))
+	}
+	if p.policies[PlanDropFKsFirst] {
+		for _, table := range source.Tables() {
+			if target.Entity(table.Name()) != nil {
+				continue
+			}
+			for _, name := range foreignKeys(table.CreateTable) {
+				p.notes = append(p.notes, fmt.Sprintf("drops foreign key %s of %s; the %s phase drops the table", name, table.Name(), PlanPhaseDrop))
+			}
+			stmt := withoutForeignKeys(table.CreateTable)
+			expand = append(expand, stmt)
+			contract = append(contract, stmt)
+		}
+	}
+	expandSchema, err := schemadiff.NewSchemaFromStatements(env, expand)
+	if err != nil {
+		return nil, fmt.Errorf("building the %s phase schema: %w", PlanPhaseExpand, err)
+	}
+	contractSchema, err := schemadiff.NewSchemaFromStatements(env, contract)
+	if err != nil {
+		return nil, fmt.Errorf("building the %s phase schema: %w", PlanPhaseContract, err)
+	}
+
+	plan := &Plan{}
+	steps := []struct {
+		name string
+		from *schemadiff.Schema
+		to   *schemadiff.Schema
+	}{
+		{PlanPhaseExpand, source, expandSchema},
+		{PlanPhaseContract, expandSchema, contractSchema},
+		{PlanPhaseDrop, contractSchema, target},
+	}
+	for _, step := range steps {
+		diffs, err := phaseDiffs(ctx, step.from, step.to, hints)
+		if err != nil {
+			return nil, fmt.Errorf("diffing the %s phase: %w", step.name, err)
+		}
+		phase := &PlanPhase{Name: step.name, Diffs: diffs}
+		switch step.name {
+		case PlanPhaseExpand:
+			phase.Notes = p.notes
+		case PlanPhaseContract:
+			if len(p.backfill) > 0 {
+				backfill := &PlanPhase{Name: PlanPhaseBackfill}
+				for _, column := range p.backfill {
+					backfill.Notes = append(backfill.Notes, fmt.Sprintf("backfill NULL values of %s", column))
+				}
+				plan.Phases = append(plan.Phases, backfill)
+			}
+		}
+		if len(diffs) > 0 {
+			plan.Phases = append(plan.Phases, phase)
+		}
+	}
+
+	applied := source
+	for _, phase := range plan.Phases {
+		if applied, err = applied.Apply(phase.Diffs); err != nil {
+			return nil, fmt.Errorf("applying the %s phase: %w", phase.Name, err)
+		}
+	}
+	remaining, err := applied.SchemaDiff(target, hints)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range remaining.UnorderedDiffs() {
+		plan.Remaining = append(plan.Remaining, d.CanonicalStatementString())
+	}
+	return plan, nil
+}
@@ -0,0 +1,265 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+func TestParsePlanPolicies(t *testing.T) {
+	tcases := []struct {
+		values      []string
+		expect      []PlanPolicy
+		expectError string
+	}{
+		{
+			values: nil,
+			expect: []PlanPolicy{},
+		},
+		{
+			values: []string{"nullable-first", " drop-fks-first"},
+			expect: []PlanPolicy{PlanNullableFirst, PlanDropFKsFirst},
+		},
+		{
+			values: []string{"none"},
+			expect: []PlanPolicy{},
+		},
+		{
+			values:      []string{"none", "index-before-drop"},
+			expectError: "cannot be combined",
+		},
+		{
+			values:      []string{"expand-all"},
+			expectError: `unknown plan policy "expand-all"`,
+		},
+	}
+	for _, tcase := range tcases {
+		policies, err := ParsePlanPolicies(tcase.values)
+		if tcase.expectError != "" {
+			assert.ErrorContains(t, err, tcase.expectError)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tcase.expect, policies)
+	}
+}
+
+// testPlan is a plan adding a NOT NULL column to t1, backfilling it, and making it NOT NULL
+func testPlan() *Plan {
+	return &Plan{
+		Phases: []*PlanPhase{
+			{
+				Name:  PlanPhaseExpand,
+				Notes: []string{"adds t1.c as nullable; the contract phase makes it NOT NULL"},
+				Diffs: []schemadiff.EntityDiff{&fakeEntityDiff{name: "t1", canonical: "ALTER TABLE `t1` ADD COLUMN `c` int"}},
+			},
+			{
+				Name:  PlanPhaseBackfill,
+				Notes: []string{"backfill NULL values of t1.c"},
+			},
+			{
+				Name:  PlanPhaseContract,
+				Diffs: []schemadiff.EntityDiff{&fakeEntityDiff{name: "t1", canonical: "ALTER TABLE `t1` MODIFY COLUMN `c` int NOT NULL"}},
+			},
+		},
+	}
+}
+
+func TestPlanString(t *testing.T) {
+	plan := testPlan()
+	expect := "-- phase 1 of 3: expand\n-- adds t1.c as nullable; the contract phase makes it NOT NULL\nALTER TABLE `t1` ADD COLUMN `c` int;\n" +
+		"\n-- phase 2 of 3: backfill\n-- backfill NULL values of t1.c\n" +
+		"\n-- phase 3 of 3: contract\nALTER TABLE `t1` MODIFY COLUMN `c` int NOT NULL;\n"
+	assert.True(t, plan.Valid())
+	assert.Equal(t, expect, plan.String())
+
+	plan.Remaining = []string{"ALTER TABLE `t1` ADD KEY `c_idx` (`c`)"}
+	assert.False(t, plan.Valid())
+	assert.Equal(t, expect+"\n-- plan does not reach the target schema, which differs by:\nALTER TABLE `t1` ADD KEY `c_idx` (`c`);\n", plan.String())
+
+	assert.Equal(t, "", (&Plan{}).String())
+}
+
+func TestPlanWriteFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	fileNames, err := testPlan().WriteFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "001_expand.sql"),
+		filepath.Join(dir, "002_backfill.sql"),
+		filepath.Join(dir, "003_contract.sql"),
+	}, fileNames)
+	content, err := os.ReadFile(fileNames[1])
+	require.NoError(t, err)
+	assert.Equal(t, "-- phase 2 of 3: backfill\n-- backfill NULL values of t1.c\n", string(content))
+}
+
+func TestPlanMigration(t *testing.T) {
+	tcases := []struct {
+		name     string
+		from     []string
+		to       []string
+		policies []PlanPolicy
+		// expect lists the statements of each phase, by phase name
+		expect      map[string][]string
+		expectNotes map[string][]string
+	}{
+		{
+			name: "nullable first",
+			from: []string{"create table t1 (id int primary key, a int)"},
+			to:   []string{"create table t1 (id int primary key, a int not null, b int not null, c int not null default 0)"},
+			expect: map[string][]string{
+				PlanPhaseExpand:   {"ALTER TABLE `t1` ADD COLUMN `b` int, ADD COLUMN `c` int NOT NULL DEFAULT 0"},
+				PlanPhaseBackfill: nil,
+				PlanPhaseContract: {"ALTER TABLE `t1` MODIFY COLUMN `a` int NOT NULL, MODIFY COLUMN `b` int NOT NULL"},
+			},
+			expectNotes: map[string][]string{
+				PlanPhaseExpand: {
+					"keeps t1.a nullable; the contract phase makes it NOT NULL",
+					"adds t1.b as nullable; the contract phase makes it NOT NULL",
+				},
+				PlanPhaseBackfill: {"backfill NULL values of t1.a", "backfill NULL values of t1.b"},
+			},
+		},
+		{
+			name: "index before drop",
+			from: []string{"create table t1 (id int primary key, a int, b int, key a_idx (a))"},
+			to:   []string{"create table t1 (id int primary key, a int, b int, key a_b_idx (a, b))"},
+			expect: map[string][]string{
+				PlanPhaseExpand:   {"ALTER TABLE `t1` ADD KEY `a_b_idx` (`a`, `b`)"},
+				PlanPhaseContract: {"ALTER TABLE `t1` DROP KEY `a_idx`"},
+			},
+			expectNotes: map[string][]string{
+				PlanPhaseExpand: {"keeps index a_idx on t1; the contract phase drops it"},
+			},
+		},
+		{
+			name:     "index over dropped column",
+			from:     []string{"create table t1 (id int primary key, a int, key a_idx (a))"},
+			to:       []string{"create table t1 (id int primary key)"},
+			policies: []PlanPolicy{PlanNullableFirst, PlanIndexBeforeDrop, PlanDropFKsFirst},
+			expect: map[string][]string{
+				PlanPhaseExpand: {"ALTER TABLE `t1` DROP KEY `a_idx`, DROP COLUMN `a`"},
+			},
+		},
+		{
+			name: "drop columns last",
+			from: []string{"create table t1 (id int primary key, a int not null, b bigint, key a_idx (a))"},
+			to:   []string{"create table t1 (id int primary key, b int)"},
+			expect: map[string][]string{
+				PlanPhaseExpand:   {"ALTER TABLE `t1` MODIFY COLUMN `a` int"},
+				PlanPhaseContract: {"ALTER TABLE `t1` DROP KEY `a_idx`, DROP COLUMN `a`, MODIFY COLUMN `b` int"},
+			},
+			expectNotes: map[string][]string{
+				PlanPhaseExpand: {
+					"keeps the type of t1.b; the contract phase narrows it",
+					"keeps column t1.a, as nullable; the contract phase drops it",
+					"keeps index a_idx on t1; the contract phase drops it",
+				},
+			},
+		},
+		{
+			name: "drop fks first",
+			from: []string{
+				"create table parent (id int primary key)",
+				"create table child (id int primary key, parent_id int, constraint child_parent_fk foreign key (parent_id) references parent (id))",
+				"create table t1 (id int primary key)",
+			},
+			to: []string{"create table t1 (id int primary key)"},
+			expect: map[string][]string{
+				PlanPhaseExpand: {"ALTER TABLE `child` DROP FOREIGN KEY `child_parent_fk`"},
+				PlanPhaseDrop:   {"DROP TABLE `child`", "DROP TABLE `parent`"},
+			},
+			expectNotes: map[string][]string{
+				PlanPhaseExpand: {"drops foreign key child_parent_fk of child; the drop phase drops the table"},
+			},
+		},
+		{
+			name: "no policies",
+			from: []string{
+				"create table parent (id int primary key)",
+				"create table child (id int primary key, parent_id int, constraint child_parent_fk foreign key (parent_id) references parent (id))",
+			},
+			to:       []string{"create table parent (id int primary key, name varchar(64) not null)"},
+			policies: []PlanPolicy{},
+			expect: map[string][]string{
+				PlanPhaseExpand: {"DROP TABLE `child`", "ALTER TABLE `parent` ADD COLUMN `name` varchar(64) NOT NULL"},
+			},
+		},
+	}
+	ctx := context.Background()
+	env := schemadiff.NewTestEnv()
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			from, err := schemadiff.NewSchemaFromQueries(env, tcase.from)
+			require.NoError(t, err)
+			to, err := schemadiff.NewSchemaFromQueries(env, tcase.to)
+			require.NoError(t, err)
+			policies := tcase.policies
+			if policies == nil {
+				policies = DefaultPlanPolicies()
+			}
+			plan, err := PlanMigration(ctx, env, from, to, DefaultDiffHints(), policies)
+			require.NoError(t, err)
+			require.True(t, plan.Valid(), plan.String())
+
+			phases := map[string][]string{}
+			notes := map[string][]string{}
+			for _, phase := range plan.Phases {
+				phases[phase.Name] = nil
+				for _, d := range phase.Diffs {
+					phases[phase.Name] = append(phases[phase.Name], d.CanonicalStatementString())
+				}
+				if len(phase.Notes) > 0 {
+					notes[phase.Name] = phase.Notes
+				}
+			}
+			assert.Equal(t, tcase.expect, phases)
+			if tcase.expectNotes == nil {
+				tcase.expectNotes = map[string][]string{}
+			}
+			assert.Equal(t, tcase.expectNotes, notes)
+		})
+	}
+}
+
+func TestExecPlan(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, []string{"create table t1 (id int primary key)"})
+	defer os.RemoveAll(fileFrom)
+	fileTo := writeSchemaFile(t, []string{"create table t1 (id int primary key, name varchar(12) not null)"})
+	defer os.RemoveAll(fileTo)
+
+	output, err := Exec(ctx, "plan", fileFrom, fileTo, nil)
+	require.NoError(t, err)
+	expect := "-- phase 1 of 3: expand\n-- adds t1.name as nullable; the contract phase makes it NOT NULL\nALTER TABLE `t1` ADD COLUMN `name` varchar(12);\n" +
+		"\n-- phase 2 of 3: backfill\n-- backfill NULL values of t1.name\n" +
+		"\n-- phase 3 of 3: contract\nALTER TABLE `t1` MODIFY COLUMN `name` varchar(12) NOT NULL;\n"
+	assert.Equal(t, expect, output)
+
+	output, err = Exec(ctx, "plan", fileFrom, fileTo, &ExecOptions{PlanPolicies: []string{"none"}})
+	require.NoError(t, err)
+	assert.Equal(t, "-- phase 1 of 1: expand\nALTER TABLE `t1` ADD COLUMN `name` varchar(12) NOT NULL;\n", output)
+
+	dir := t.TempDir()
+	output, err = Exec(ctx, "plan", fileFrom, fileTo, &ExecOptions{PlanDir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "001_expand.sql")+"\n"+filepath.Join(dir, "002_backfill.sql")+"\n"+filepath.Join(dir, "003_contract.sql")+"\n", output)
+	content, err := os.ReadFile(filepath.Join(dir, "003_contract.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, "-- phase 3 of 3: contract\nALTER TABLE `t1` MODIFY COLUMN `name` varchar(12) NOT NULL;\n", string(content))
+
+	_, err = Exec(ctx, "plan", fileFrom, fileTo, &ExecOptions{PlanPolicies: []string{"expand-all"}})
+	assert.ErrorContains(t, err, `unknown plan policy "expand-all"`)
+	_, err = Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{PlanDir: dir})
+	assert.ErrorContains(t, err, "--plan-dir applies to the plan command")
+	_, err = Exec(ctx, "diff", fileFrom, fileTo, &ExecOptions{PlanPolicies: []string{"none"}})
+	assert.ErrorContains(t, err, "--plan-policies applies to the plan command")
+	_, err = Exec(ctx, "plan", fileFrom, fileTo, &ExecOptions{Mapping: []string{"t1=t9"}})
+	assert.ErrorContains(t, err, "not supported by plan")
+}
@@ -0,0 +1,329 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+const (
+	// minRenameSuggestionScore is the minimal score for a candidate rename to be suggested
+	minRenameSuggestionScore = 0.5
+)
+
+// RenameSuggestion is a candidate rename of a table, or of a column within a table, found by comparing a dropped
+// entity with a created entity. The score is between 0 and 1, where higher means more likely.
+type RenameSuggestion struct {
+	// Table is the name of the table in the source schema
+	Table string
+	// Column is the name of the column in the source table, or empty for a table rename
+	Column string
+	// NewName is the name of the table or of the column in the target schema
+	NewName string
+	Score   float64
+	Reasons []string
+}
+
+// MappingValue returns the suggestion as a mapping value, which can be approved by passing it to --map or
+// by listing it in a --map-file.
+func (s *RenameSuggestion) MappingValue() string {
+	if s.Column == "" {
+		return fmt.Sprintf("%s=%s", s.Table, s.NewName)
+	}
+	return fmt.Sprintf("%s.%s=%s", s.Table, s.Column, s.NewName)
+}
+
+// String returns the suggestion as a commented mapping line.
+func (s *RenameSuggestion) String() string {
+	var description string
+	if s.Column == "" {
+		description = fmt.Sprintf("table %s renamed to %s", s.Table, s.NewName)
+	} else {
+		description = fmt.Sprintf("column %s.%s renamed to %s", s.Table, s.Column, s.NewName)
+	}
+	return fmt.Sprintf("# %s? score %.2f: %s\n%s", description, s.Score, strings.Join(s.Reasons, ", "), s.MappingValue())
+}
+
+// columnKeySignatures returns, per column, the set of keys the column participates in. A key is
+// described by its columns list, where the column itself is replaced by '?'. This allows comparing
+// key membership of two columns of different names.
+func columnKeySignatures(table *schemadiff.CreateTableEntity) map[string]map[string]bool {
+	signatures := map[string]map[string]bool{}
+	for _, key := range table.TableSpec.Indexes {
+		for _, keyColumn := range key.Columns {
+			name := keyColumn.Column.String()
+			if name == "" {
+				continue
+			}
+			var parts []string
+			for _, c := range key.Columns {
+				if c.Column.String() == name {
+					parts = append(parts, "?")
+				} else {
+					parts = append(parts, c.Column.String())
+				}
+			}
+			if signatures[name] == nil {
+				signatures[name] = map[string]bool{}
+			}
+			signatures[name][strings.Join(parts, ",")] = true
+		}
+	}
+	return signatures
+}
+
+// jaccard returns the similarity of two sets, where two empty sets are identical.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	intersection := 0
+	for k := range a {
+		if b[k] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// scoreColumnRename scores the likelihood that column sourceColumn in sourceTable was renamed to targetColumn in targetTable,
+// by comparing column types, positions and key membership. Columns of unrelated types score 0: position and key
+// membership alone, such as two unindexed columns in the same position, do not make a rename.
+func scoreColumnRename(
+	sourceTable *schemadiff.CreateTableEntity, sourcePos int, sourceKeys map[string]bool,
+	targetTable *schemadiff.CreateTableEntity, targetPos int, targetKeys map[string]bool,
+) (score float64, reasons []string) {
+	sourceColumn := sourceTable.TableSpec.Columns[sourcePos]
+	targetColumn := targetTable.TableSpec.Columns[targetPos]
+	switch {
+	case sqlparser.CanonicalString(sourceColumn.Type) == sqlparser.CanonicalString(targetColumn.Type):
+		score += 0.5
+		reasons = append(reasons, "same type")
+	case strings.EqualFold(sourceColumn.Type.Type, targetColumn.Type.Type):
+		score += 0.25
+		reasons = append(reasons, "similar type")
+	default:
+		return 0, nil
+	}
+	if sourcePos == targetPos {
+		score += 0.25
+		reasons = append(reasons, "same position")
+	}
+	if similarity := jaccard(sourceKeys, targetKeys); similarity > 0 {
+		score += 0.25 * similarity
+		if len(sourceKeys) == 0 {
+			reasons = append(reasons, "not indexed")
+		} else {
+			reasons = append(reasons, fmt.Sprintf("%.0f%% same keys", similarity*100))
+		}
+	}
+	return score, reasons
+}
+
+// scoreTableRename scores the likelihood that sourceTable was renamed to targetTable, by comparing column definitions,
+// column order and keys.
+func scoreTableRename(sourceTable *schemadiff.CreateTableEntity, targetTable *schemadiff.CreateTableEntity) (score float64, reasons []string) {
+	definitions := func(table *schemadiff.CreateTableEntity) map[string]bool {
+		m := map[string]bool{}
+		for _, col := range table.TableSpec.Columns {
+			m[col.Name.String()+" "+sqlparser.CanonicalString(col.Type)] = true
+		}
+		return m
+	}
+	keys := func(table *schemadiff.CreateTableEntity) map[string]bool {
+		m := map[string]bool{}
+		for _, key := range table.TableSpec.Indexes {
+			var columns []string
+			for _, c := range key.Columns {
+				columns = append(columns, c.Column.String())
+			}
+			m[strings.Join(columns, ",")] = true
+		}
+		return m
+	}
+	sourceColumns := sourceTable.TableSpec.Columns
+	targetColumns := targetTable.TableSpec.Columns
+
+	similarity := jaccard(definitions(sourceTable), definitions(targetTable))
+	score += 0.5 * similarity
+	reasons = append(reasons, fmt.Sprintf("%.0f%% same columns", similarity*100))
+
+	samePositions := 0
+	for i := 0; i < len(sourceColumns) && i < len(targetColumns); i++ {
+		if sourceColumns[i].Name.String() == targetColumns[i].Name.String() {
+			samePositions++
+		}
+	}
+	if maxColumns := max(len(sourceColumns), len(targetColumns)); maxColumns > 0 {
+		score += 0.2 * float64(samePositions) / float64(maxColumns)
+	}
+
+	similarity = jaccard(keys(sourceTable), keys(targetTable))
+	score += 0.3 * similarity
+	reasons = append(reasons, fmt.Sprintf("%.0f%% same keys", similarity*100))
+	return score, reasons
+}
+
+// pickSuggestions greedily picks the highest scored candidates, such that each old name and each new name
+// appears at most once.
+func pickSuggestions(candidates []*RenameSuggestion) (picked []*RenameSuggestion) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	usedOld := map[string]bool{}
+	usedNew := map[string]bool{}
+	for _, candidate := range candidates {
+		oldKey := candidate.Table + "." + candidate.Column
+		if usedOld[oldKey] || usedNew[candidate.NewName] {
+			continue
+		}
+		usedOld[oldKey] = true
+		usedNew[candidate.NewName] = true
+		picked = append(picked, candidate)
+	}
+	return picked
+}
+
+// suggestColumnRenames suggests column renames between a source table and its corresponding target table.
+func suggestColumnRenames(sourceTable *schemadiff.CreateTableEntity, targetTable *schemadiff.CreateTableEntity, mapped map[string]string) []*RenameSuggestion {
+	sourceNames := map[string]bool{}
+	for _, col := range sourceTable.TableSpec.Columns {
+		sourceNames[col.Name.String()] = true
+	}
+	targetNames := map[string]bool{}
+	for _, col := range targetTable.TableSpec.Columns {
+		targetNames[col.Name.String()] = true
+	}
+	for from, to := range mapped {
+		delete(sourceNames, from)
+		delete(targetNames, to)
+	}
+	sourceKeys := columnKeySignatures(sourceTable)
+	targetKeys := columnKeySignatures(targetTable)
+
+	var candidates []*RenameSuggestion
+	for sourcePos, sourceColumn := range sourceTable.TableSpec.Columns {
+		sourceName := sourceColumn.Name.String()
+		if !sourceNames[sourceName] || targetNames[sourceName] {
+			// Either already mapped, or exists in both tables, hence not dropped
+			continue
+		}
+		for targetPos, targetColumn := range targetTable.TableSpec.Columns {
+			targetName := targetColumn.Name.String()
+			if !targetNames[targetName] || sourceNames[targetName] {
+				continue
+			}
+			score, reasons := scoreColumnRename(sourceTable, sourcePos, sourceKeys[sourceName], targetTable, targetPos, targetKeys[targetName])
+			if score < minRenameSuggestionScore {
+				continue
+			}
+			candidates = append(candidates, &RenameSuggestion{
+				Table:   sourceTable.Name(),
+				Column:  sourceName,
+				NewName: targetName,
+				Score:   score,
+				Reasons: reasons,
+			})
+		}
+	}
+	return pickSuggestions(candidates)
+}
+
+// suggestRenames suggests table renames and column renames between the two schemas, skipping anything
+// already covered by the given mapping.
+func suggestRenames(sourceSchema *schemadiff.Schema, targetSchema *schemadiff.Schema, mapping *Mapping) (suggestions []*RenameSuggestion) {
+	if mapping == nil {
+		mapping = &Mapping{}
+	}
+	mappedTargets := map[string]bool{}
+	for _, to := range mapping.Entities {
+		mappedTargets[to] = true
+	}
+	// Tables found in source and not in target are considered dropped, and vice versa for created tables.
+	var dropped, created []*schemadiff.CreateTableEntity
+	for _, table := range sourceSchema.Tables() {
+		if _, ok := mapping.Entities[table.Name()]; ok {
+			continue
+		}
+		if targetSchema.Table(table.Name()) == nil {
+			dropped = append(dropped, table)
+		}
+	}
+	for _, table := range targetSchema.Tables() {
+		if mappedTargets[table.Name()] {
+			continue
+		}
+		if sourceSchema.Table(table.Name()) == nil {
+			created = append(created, table)
+		}
+	}
+	var candidates []*RenameSuggestion
+	for _, sourceTable := range dropped {
+		for _, targetTable := range created {
+			score, reasons := scoreTableRename(sourceTable, targetTable)
+			if score < minRenameSuggestionScore {
+				continue
+			}
+			candidates = append(candidates, &RenameSuggestion{
+				Table:   sourceTable.Name(),
+				NewName: targetTable.Name(),
+				Score:   score,
+				Reasons: reasons,
+			})
+		}
+	}
+	tableSuggestions := pickSuggestions(candidates)
+	suggestions = append(suggestions, tableSuggestions...)
+
+	// Column renames are looked for in tables found in both schemas, whether by name, by mapping, or by suggested rename.
+	correspondingTables := map[string]string{}
+	for _, table := range sourceSchema.Tables() {
+		if targetSchema.Table(table.Name()) != nil {
+			correspondingTables[table.Name()] = table.Name()
+		}
+	}
+	for from, to := range mapping.Entities {
+		correspondingTables[from] = to
+	}
+	for _, suggestion := range tableSuggestions {
+		correspondingTables[suggestion.Table] = suggestion.NewName
+	}
+	for _, table := range sourceSchema.Tables() {
+		to, ok := correspondingTables[table.Name()]
+		if !ok {
+			continue
+		}
+		targetTable := targetSchema.Table(to)
+		if targetTable == nil {
+			continue
+		}
+		suggestions = append(suggestions, suggestColumnRenames(table, targetTable, mapping.Columns[table.Name()])...)
+	}
+	return suggestions
+}
+
+// SuggestRenames compares two schemas and returns candidate table and column renames, which would otherwise
+// be diffed as DROP and CREATE (or ADD). Renames already covered by the given mapping are not suggested.
+// Inputs can be stdin, file, directory, or MySQL URI.
+func SuggestRenames(ctx context.Context, env *schemadiff.Environment, inputSourceValue string, targetInputSourceValue string, mapping *Mapping, readOpts *base.ReadOptions) ([]*RenameSuggestion, error) {
+	sourceSchema, err := base.ReadSchemaFromSource(ctx, env, inputSourceValue, readOpts)
+	if err != nil {
+		return nil, err
+	}
+	targetSchema, err := base.ReadSchemaFromSource(ctx, env, targetInputSourceValue, readOpts)
+	if err != nil {
+		return nil, err
+	}
+	if !mapping.IsEmpty() {
+		if err := mapping.validate(sourceSchema, targetSchema); err != nil {
+			return nil, err
+		}
+	}
+	return suggestRenames(sourceSchema, targetSchema, mapping), nil
+}
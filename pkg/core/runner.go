@@ -0,0 +1,453 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+// Runner, Options, Request, Result and Command are the API for using schemadiff as a Go library. They follow
+// semantic versioning: within a major version, fields may be added to the structs and new commands may be
+// added, but existing fields, commands and their meaning do not change. The zero value of any new field
+// keeps the previous behavior.
+
+// Command is a schemadiff command, run by Runner.Run.
+type Command string
+
+const (
+	// CommandLoad loads, validates and normalizes the source schema. Result.Schema and Result.Entities are set.
+	CommandLoad Command = "load"
+	// CommandDiff diffs the source and target schemas. Result.Preamble and Result.Diffs are set.
+	CommandDiff Command = "diff"
+	// CommandOrderedDiff diffs the source and target schemas, in an order valid to apply. Result.Preamble and Result.Diffs are set.
+	CommandOrderedDiff Command = "ordered-diff"
+	// CommandDiffTable diffs two single CREATE TABLE statements. Result.Diffs is set.
+	CommandDiffTable Command = "diff-table"
+	// CommandDiffView diffs two single CREATE VIEW statements. Result.Diffs is set.
+	CommandDiffView Command = "diff-view"
+	// CommandSuggestRenames compares the source and target schemas for likely renames. Result.Suggestions is set.
+	CommandSuggestRenames Command = "suggest-renames"
+	// CommandMerge merges the ours and theirs schemas onto their base. Result.Schema and Result.Entities are set.
+	CommandMerge Command = "merge"
+	// CommandFingerprint loads the source schema, and hashes it and its entities. Result.Schema and Result.Fingerprint are set.
+	CommandFingerprint Command = "fingerprint"
+	// CommandVerify applies the diff between the source and target schemas in memory, in both directions, and
+	// checks the results match the target and source schemas. Result.Verification is set. Options.Mapping
+	// does not apply.
+	CommandVerify Command = "verify"
+	// CommandERD loads the source schema into an entity-relationship diagram, or, if the target is given, diffs
+	// the source and target schemas into a diagram of the diff. Result.ERD is set. Options.Mapping does not apply.
+	CommandERD Command = "erd"
+	// CommandExplainOrder diffs the source and target schemas, and explains the order of the diffs.
+	// Result.OrderExplanation is set. Options.Mapping and Options.Filter do not apply.
+	CommandExplainOrder Command = "explain-order"
+	// CommandPlan diffs the source and target schemas, and splits the diff into phases according to
+	// Options.PlanPolicies. Result.Plan is set. Options.Mapping and Options.Filter do not apply.
+	CommandPlan Command = "plan"
+)
+
+// Options configure a Runner. A nil value is valid and implies defaults.
+type Options struct {
+	// MySQLVersion is the MySQL version to emulate. Defaults to DefaultMySQLVersion.
+	MySQLVersion string
+	// Hints are the diff hints. Defaults to DefaultDiffHints().
+	Hints *schemadiff.DiffHints
+	// IncludeInternalTables, when true, reads online schema change artifacts and Vitess internal tables from MySQL sources
+	IncludeInternalTables bool
+	// Warnings, if non nil, receives non-fatal notices, such as skipped entities.
+	Warnings io.Writer
+	// Mapping, if non nil, renames source entities and columns before diffing.
+	Mapping *Mapping
+	// Filter, if non nil, limits the results to entities for which it returns true. It applies to Result.Entities,
+	// Result.Diffs, Result.Suggestions and Result.ERD, but not to Result.Schema, nor to the statements in
+	// Result.Preamble.
+	Filter func(entityName string) bool
+	// StdinPair, when true, makes the diff commands, suggest-renames, verify, explain-order and plan read both the
+	// source and the target from a single standard input stream, separated by a StdinDelimiter line or sent as a
+	// JSON object. See base.ReadStdinPair. The source and target inputs must then be empty or "-".
+	StdinPair bool
+	// StdinDelimiter is the line separating the source from the target in StdinPair mode. Defaults to
+	// base.DefaultStdinDelimiter.
+	StdinDelimiter string
+	// Stdin is the stream read in StdinPair mode, unless Request.Stdin is given. Defaults to os.Stdin. The stream is
+	// read once, by the first Run that reads it; further such Runs return ErrStdinPairConsumed.
+	Stdin io.Reader
+	// PlanPolicies are the expand/contract policies of the plan command. Defaults to DefaultPlanPolicies(). An
+	// empty, non nil value disables all policies, and the plan has a single phase.
+	PlanPolicies []PlanPolicy
+}
+
+// Request holds the inputs to a command. Inputs can be stdin, file, directory, or MySQL URI.
+type Request struct {
+	// Source is the input of all commands but merge
+	Source string
+	// Target is the input of diff commands, suggest-renames, verify, explain-order and plan, and the optional input of erd
+	Target string
+	// Base, Ours and Theirs are the inputs of merge
+	Base   string
+	Ours   string
+	Theirs string
+	// Stdin, if non nil, is the stream read in Options.StdinPair mode, in place of Options.Stdin. Runners that run
+	// more than one StdinPair request give each its own Stdin.
+	Stdin io.Reader
+}
+
+// Result is the typed result of a command. Only the fields documented by the command are set.
+type Result struct {
+	Command Command
+	// Schema is the loaded or merged schema
+	Schema *schemadiff.Schema
+	// Entities are the entities of Schema, in Schema order
+	Entities []schemadiff.Entity
+	// Preamble lists statements to run before Diffs, such as renames implied by Options.Mapping
+	Preamble []string
+	Diffs    []schemadiff.EntityDiff
+	// Suggestions are candidate renames
+	Suggestions []*RenameSuggestion
+	// Fingerprint is the hash of the schema and of its entities
+	Fingerprint *Fingerprint
+	// Verification is the outcome of applying the diff in both directions
+	Verification *Verification
+	// ERD is the entity-relationship diagram of the schema, or of the diff
+	ERD *ERD
+	// OrderExplanation explains the order of the diffs
+	OrderExplanation *OrderExplanation
+	// Plan is the diff split into phases
+	Plan *Plan
+}
+
+// Write writes the result in schemadiff's CLI output format: one statement per entity or diff, or, if textual
+// is true, the annotated textual diff per diff. A fingerprint is written as a "schema <hash>" line, followed by
+// a "<kind> <name> <hash>" line per entity.
+func (r *Result) Write(w io.Writer, textual bool) error {
+	for _, stmt := range r.Preamble {
+		if _, err := fmt.Fprintf(w, "%s;\n", stmt); err != nil {
+			return err
+		}
+	}
+	writeDiff := func(d schemadiff.EntityDiff) error {
+		if textual {
+			_, _, unified := d.Annotated()
+			_, err := fmt.Fprintf(w, "%s;\n", unified.Export())
+			return err
+		}
+		_, err := fmt.Fprintf(w, "%s;\n", d.CanonicalStatementString())
+		return err
+	}
+	for _, e := range r.Entities {
+		if err := writeDiff(e.Create()); err != nil {
+			return err
+		}
+	}
+	for _, d := range r.Diffs {
+		if err := writeDiff(d); err != nil {
+			return err
+		}
+	}
+	for _, suggestion := range r.Suggestions {
+		if _, err := fmt.Fprintf(w, "%s\n", suggestion); err != nil {
+			return err
+		}
+	}
+	if r.Fingerprint != nil {
+		if _, err := io.WriteString(w, r.Fingerprint.String()); err != nil {
+			return err
+		}
+	}
+	if r.Verification != nil {
+		if _, err := io.WriteString(w, r.Verification.String()); err != nil {
+			return err
+		}
+	}
+	if r.ERD != nil {
+		if err := r.ERD.Write(w, nil); err != nil {
+			return err
+		}
+	}
+	if r.OrderExplanation != nil {
+		if _, err := io.WriteString(w, r.OrderExplanation.String()); err != nil {
+			return err
+		}
+	}
+	if r.Plan != nil {
+		if _, err := io.WriteString(w, r.Plan.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runner runs schemadiff commands. A Runner is safe for concurrent use.
+type Runner struct {
+	env      *schemadiff.Environment
+	opts     Options
+	readOpts *base.ReadOptions
+	// stdinRead is set once Options.Stdin is read in StdinPair mode
+	stdinRead atomic.Bool
+}
+
+// NewRunner returns a runner with the given options.
+func NewRunner(opts *Options) (*Runner, error) {
+	r := &Runner{}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.MySQLVersion == "" {
+		r.opts.MySQLVersion = DefaultMySQLVersion
+	}
+	if r.opts.Hints == nil {
+		r.opts.Hints = DefaultDiffHints()
+	}
+	if r.opts.PlanPolicies == nil {
+		r.opts.PlanPolicies = DefaultPlanPolicies()
+	}
+	env, err := NewEnv(r.opts.MySQLVersion)
+	if err != nil {
+		return nil, err
+	}
+	r.env = env
+	if r.opts.Stdin == nil {
+		r.opts.Stdin = os.Stdin
+	}
+	r.readOpts = &base.ReadOptions{
+		IncludeInternalTables: r.opts.IncludeInternalTables,
+		Warnings:              r.opts.Warnings,
+	}
+	return r, nil
+}
+
+// Env returns the schemadiff environment of the runner.
+func (r *Runner) Env() *schemadiff.Environment {
+	return r.env
+}
+
+func (r *Runner) included(entityName string) bool {
+	return r.opts.Filter == nil || r.opts.Filter(entityName)
+}
+
+func (r *Runner) filterDiffs(diffs []schemadiff.EntityDiff) (filtered []schemadiff.EntityDiff) {
+	for _, d := range diffs {
+		if r.included(d.EntityName()) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+func (r *Runner) schemaResult(command Command, schema *schemadiff.Schema) *Result {
+	result := &Result{Command: command, Schema: schema}
+	for _, e := range schema.Entities() {
+		if r.included(e.Name()) {
+			result.Entities = append(result.Entities, e)
+		}
+	}
+	return result
+}
+
+// readStdinPair reads the source and target from standard input together, returning a request and read
+// options that read them by the "stdin:source" and "stdin:target" inputs. The stream is req.Stdin if given, and
+// otherwise Options.Stdin, which is read once.
+func (r *Runner) readStdinPair(req *Request) (*Request, *base.ReadOptions, error) {
+	isStdin := func(value string) bool { return value == "" || value == "-" }
+	if !isStdin(req.Source) || !isStdin(req.Target) {
+		return nil, nil, ErrStdinPairInputs
+	}
+	stdin := req.Stdin
+	if stdin == nil {
+		if r.stdinRead.Swap(true) {
+			return nil, nil, ErrStdinPairConsumed
+		}
+		stdin = r.opts.Stdin
+	}
+	pair, err := base.ReadStdinPair(stdin, r.opts.StdinDelimiter)
+	if err != nil {
+		return nil, nil, err
+	}
+	pairReq := *req
+	pairReq.Source = "stdin:" + base.StdinPairSource
+	pairReq.Target = "stdin:" + base.StdinPairTarget
+	readOpts := *r.readOpts
+	readOpts.StdinPair = pair
+	return &pairReq, &readOpts, nil
+}
+
+// Run runs the given command. The context bounds reading the inputs, e.g. from a MySQL server, as well as
+// computing the result.
+func (r *Runner) Run(ctx context.Context, command Command, req *Request) (*Result, error) {
+	if req == nil {
+		req = &Request{}
+	}
+	spec, err := lookupCommand(command, false)
+	if err != nil {
+		return nil, err
+	}
+	readOpts := r.readOpts
+	if spec.sourceTarget {
+		if r.opts.StdinPair {
+			if req, readOpts, err = r.readStdinPair(req); err != nil {
+				return nil, err
+			}
+		}
+		if req.Source == req.Target {
+			return nil, ErrIdenticalSourceTarget
+		}
+	}
+	switch command {
+	case CommandLoad:
+		schema, err := LoadSchema(ctx, r.env, req.Source, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		return r.schemaResult(command, schema), nil
+	case CommandFingerprint:
+		schema, err := LoadSchema(ctx, r.env, req.Source, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		result := r.schemaResult(command, schema)
+		result.Fingerprint = FingerprintEntities(result.Entities)
+		result.Entities = nil
+		return result, nil
+	case CommandDiff, CommandOrderedDiff:
+		preamble, diff, err := DiffSchemasWithMapping(ctx, r.env, req.Source, req.Target, r.opts.Mapping, r.opts.Hints, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		diffs := diff.UnorderedDiffs()
+		if command == CommandOrderedDiff {
+			if diffs, err = diff.OrderedDiffs(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return &Result{Command: command, Preamble: preamble, Diffs: r.filterDiffs(diffs)}, nil
+	case CommandDiffTable, CommandDiffView:
+		diffEntities := DiffTables
+		if command == CommandDiffView {
+			diffEntities = DiffViews
+		}
+		diff, err := diffEntities(ctx, r.env, req.Source, req.Target, r.opts.Hints, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		result := &Result{Command: command}
+		if !diff.IsEmpty() {
+			result.Diffs = r.filterDiffs([]schemadiff.EntityDiff{diff})
+		}
+		return result, nil
+	case CommandSuggestRenames:
+		suggestions, err := SuggestRenames(ctx, r.env, req.Source, req.Target, r.opts.Mapping, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		result := &Result{Command: command}
+		for _, suggestion := range suggestions {
+			if r.included(suggestion.Table) {
+				result.Suggestions = append(result.Suggestions, suggestion)
+			}
+		}
+		return result, nil
+	case CommandVerify:
+		sourceSchema, err := LoadSchema(ctx, r.env, req.Source, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		targetSchema, err := LoadSchema(ctx, r.env, req.Target, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		verification, err := VerifyRoundTrip(ctx, sourceSchema, targetSchema, r.opts.Hints)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Command: command, Verification: verification}, nil
+	case CommandExplainOrder:
+		sourceSchema, err := LoadSchema(ctx, r.env, req.Source, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		targetSchema, err := LoadSchema(ctx, r.env, req.Target, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		diff, err := sourceSchema.SchemaDiff(targetSchema, r.opts.Hints)
+		if err != nil {
+			return nil, err
+		}
+		explanation, err := ExplainOrder(ctx, sourceSchema, diff)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Command: command, OrderExplanation: explanation}, nil
+	case CommandPlan:
+		sourceSchema, err := LoadSchema(ctx, r.env, req.Source, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		targetSchema, err := LoadSchema(ctx, r.env, req.Target, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		plan, err := PlanMigration(ctx, r.env, sourceSchema, targetSchema, r.opts.Hints, r.opts.PlanPolicies)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Command: command, Plan: plan}, nil
+	case CommandERD:
+		sourceSchema, err := LoadSchema(ctx, r.env, req.Source, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		erd := SchemaERD(sourceSchema)
+		if req.Target != "" {
+			targetSchema, err := LoadSchema(ctx, r.env, req.Target, readOpts)
+			if err != nil {
+				return nil, err
+			}
+			if erd, err = DiffERD(sourceSchema, targetSchema, r.opts.Hints); err != nil {
+				return nil, err
+			}
+		}
+		erd.filter(r.included)
+		return &Result{Command: command, ERD: erd}, nil
+	case CommandMerge:
+		if req.Base == "" || req.Ours == "" || req.Theirs == "" {
+			return nil, ErrMissingMergeInputs
+		}
+		schema, err := MergeSchemas(ctx, r.env, req.Base, req.Ours, req.Theirs, r.opts.Hints, readOpts)
+		if err != nil {
+			return nil, err
+		}
+		return r.schemaResult(command, schema), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, command)
+}
+
+// Snapshot returns a snapshot of the entities of a load or merge result, along with the description and server
+// version of the given source, and the runner's MySQL version and hints.
+func (r *Runner) Snapshot(ctx context.Context, result *Result, source string) (*base.Snapshot, error) {
+	serverVersion, err := base.ReadServerVersion(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := base.NewSnapshot(result.Entities)
+	snapshot.Source = base.DescribeInputSource(source)
+	snapshot.ServerVersion = serverVersion
+	snapshot.MySQLVersion = r.opts.MySQLVersion
+	snapshot.Hints = r.opts.Hints
+	return snapshot, nil
+}
+
+// DiffGitBlobs diffs two versions of a file, as given to a git diff driver. See the DiffGitBlobs function.
+func (r *Runner) DiffGitBlobs(ctx context.Context, oldFile string, newFile string) ([]schemadiff.EntityDiff, error) {
+	diffs, err := DiffGitBlobs(ctx, r.env, oldFile, newFile, r.opts.Hints, r.readOpts)
+	if err != nil {
+		return nil, err
+	}
+	return r.filterDiffs(diffs), nil
+}
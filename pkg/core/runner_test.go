@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+)
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, schemaFrom)
+	defer os.RemoveAll(fileFrom)
+	fileTo := writeSchemaFile(t, schemaTo)
+	defer os.RemoveAll(fileTo)
+
+	runner, err := NewRunner(nil)
+	require.NoError(t, err)
+
+	t.Run("load", func(t *testing.T) {
+		result, err := runner.Run(ctx, CommandLoad, &Request{Source: fileFrom})
+		require.NoError(t, err)
+		assert.Equal(t, CommandLoad, result.Command)
+		require.NotNil(t, result.Schema)
+		assert.Len(t, result.Entities, len(loadFrom))
+		for i, e := range result.Entities {
+			assert.Equal(t, loadFrom[i], e.Create().CanonicalStatementString())
+		}
+	})
+	t.Run("diff", func(t *testing.T) {
+		result, err := runner.Run(ctx, CommandDiff, &Request{Source: fileFrom, Target: fileTo})
+		require.NoError(t, err)
+		assert.Empty(t, result.Preamble)
+		var statements []string
+		for _, d := range result.Diffs {
+			statements = append(statements, d.CanonicalStatementString())
+		}
+		assert.ElementsMatch(t, diffsFromTo, statements)
+	})
+	t.Run("ordered-diff", func(t *testing.T) {
+		result, err := runner.Run(ctx, CommandOrderedDiff, &Request{Source: fileFrom, Target: fileTo})
+		require.NoError(t, err)
+		var statements []string
+		for _, d := range result.Diffs {
+			statements = append(statements, d.CanonicalStatementString())
+		}
+		assert.Equal(t, diffsFromTo, statements)
+
+		var b strings.Builder
+		require.NoError(t, result.Write(&b, false))
+		assert.Equal(t, sqlsToMultiStatementText(diffsFromTo), b.String())
+	})
+	t.Run("identical", func(t *testing.T) {
+		_, err := runner.Run(ctx, CommandDiff, &Request{Source: fileFrom, Target: fileFrom})
+		assert.ErrorIs(t, err, ErrIdenticalSourceTarget)
+	})
+	t.Run("missing merge inputs", func(t *testing.T) {
+		_, err := runner.Run(ctx, CommandMerge, &Request{Base: fileFrom})
+		assert.ErrorIs(t, err, ErrMissingMergeInputs)
+	})
+	t.Run("unknown", func(t *testing.T) {
+		_, err := runner.Run(ctx, Command("no-such-command"), nil)
+		assert.ErrorIs(t, err, ErrUnknownCommand)
+		assert.EqualError(t, err, "unknown command: no-such-command")
+	})
+}
+
+func TestRunnerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dirFrom, dirTo := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dirFrom, "t1.sql"), []byte("create table t1 (id int primary key)"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dirTo, "t1.sql"), []byte("create table t1 (id bigint primary key)"), 0644))
+
+	runner, err := NewRunner(nil)
+	require.NoError(t, err)
+	for _, command := range []Command{CommandLoad, CommandDiff, CommandOrderedDiff, CommandDiffTable, CommandDiffView, CommandSuggestRenames, CommandFingerprint, CommandVerify, CommandERD, CommandExplainOrder, CommandPlan, CommandMerge} {
+		t.Run(string(command), func(t *testing.T) {
+			_, err := runner.Run(ctx, command, &Request{Source: dirFrom, Target: dirTo, Base: dirFrom, Ours: dirTo, Theirs: dirTo})
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func TestRunnerOptions(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, schemaFrom)
+	defer os.RemoveAll(fileFrom)
+	fileTo := writeSchemaFile(t, schemaTo)
+	defer os.RemoveAll(fileTo)
+	fileOriginal := writeSchemaFile(t, []string{"create table t1 (id int primary key)"})
+	defer os.RemoveAll(fileOriginal)
+	fileRenamed := writeSchemaFile(t, []string{"create table t9 (id int primary key)"})
+	defer os.RemoveAll(fileRenamed)
+
+	t.Run("filter", func(t *testing.T) {
+		runner, err := NewRunner(&Options{
+			Filter: func(entityName string) bool { return strings.HasPrefix(entityName, "t") },
+		})
+		require.NoError(t, err)
+
+		result, err := runner.Run(ctx, CommandLoad, &Request{Source: fileFrom})
+		require.NoError(t, err)
+		assert.Len(t, result.Entities, 2)
+		assert.Len(t, result.Schema.Entities(), 3)
+
+		result, err = runner.Run(ctx, CommandOrderedDiff, &Request{Source: fileFrom, Target: fileTo})
+		require.NoError(t, err)
+		var entities []string
+		for _, d := range result.Diffs {
+			entities = append(entities, d.EntityName())
+		}
+		assert.Equal(t, []string{"t1", "t3"}, entities)
+	})
+	t.Run("hints", func(t *testing.T) {
+		hints := DefaultDiffHints()
+		hints.TableRenameStrategy = schemadiff.TableRenameHeuristicStatement
+		runner, err := NewRunner(&Options{Hints: hints})
+		require.NoError(t, err)
+
+		result, err := runner.Run(ctx, CommandDiff, &Request{Source: fileOriginal, Target: fileRenamed})
+		require.NoError(t, err)
+		require.Len(t, result.Diffs, 1)
+		assert.Equal(t, "RENAME TABLE `t1` TO `t9`", result.Diffs[0].CanonicalStatementString())
+	})
+	t.Run("mapping", func(t *testing.T) {
+		mapping, err := ParseMapping([]string{"t1=t9"})
+		require.NoError(t, err)
+		runner, err := NewRunner(&Options{Mapping: mapping})
+		require.NoError(t, err)
+
+		result, err := runner.Run(ctx, CommandDiff, &Request{Source: fileOriginal, Target: fileRenamed})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"RENAME TABLE `t1` TO `t9`"}, result.Preamble)
+		assert.Empty(t, result.Diffs)
+	})
+	t.Run("stdin pair", func(t *testing.T) {
+		stdin := sqlsToMultiStatementText(schemaFrom) + base.DefaultStdinDelimiter + "\n" + sqlsToMultiStatementText(schemaTo)
+		runner, err := NewRunner(&Options{StdinPair: true, Stdin: strings.NewReader(stdin)})
+		require.NoError(t, err)
+
+		result, err := runner.Run(ctx, CommandDiff, &Request{Source: "-", Target: ""})
+		require.NoError(t, err)
+		var statements []string
+		for _, d := range result.Diffs {
+			statements = append(statements, d.CanonicalStatementString())
+		}
+		assert.ElementsMatch(t, diffsFromTo, statements)
+
+		_, err = runner.Run(ctx, CommandDiff, nil)
+		assert.ErrorIs(t, err, ErrStdinPairConsumed)
+		result, err = runner.Run(ctx, CommandDiff, &Request{Stdin: strings.NewReader(stdin)})
+		require.NoError(t, err)
+		assert.Len(t, result.Diffs, len(diffsFromTo))
+
+		runner, err = NewRunner(&Options{StdinPair: true, Stdin: strings.NewReader(sqlsToMultiStatementText(schemaFrom))})
+		require.NoError(t, err)
+		_, err = runner.Run(ctx, CommandDiff, nil)
+		assert.ErrorContains(t, err, "line separates the source schema from the target schema")
+
+		_, err = runner.Run(ctx, CommandDiffTable, &Request{Source: fileFrom})
+		assert.ErrorIs(t, err, ErrStdinPairInputs)
+	})
+	t.Run("mysql version", func(t *testing.T) {
+		runner, err := NewRunner(&Options{MySQLVersion: "8.4.0"})
+		require.NoError(t, err)
+		assert.NotNil(t, runner.Env())
+	})
+}
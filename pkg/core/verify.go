@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vitess.io/vitess/go/vt/schemadiff"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// RoundTripMismatch is an entity which differs between a schema and the result of applying a diff that should
+// have produced it.
+type RoundTripMismatch struct {
+	Entity string
+	// Expected is the canonical CREATE statement of the entity in the expected schema, empty if it has no such entity
+	Expected string
+	// Actual is the canonical CREATE statement of the entity in the result of applying the diff, empty if it has
+	// no such entity
+	Actual string
+}
+
+func (m *RoundTripMismatch) String() string {
+	statement := func(s string) string {
+		if s == "" {
+			return "none"
+		}
+		return strings.ReplaceAll(s, "\n", "\n    ") + ";"
+	}
+	return fmt.Sprintf("%s:\n    expected: %s\n    actual:   %s", m.Entity, statement(m.Expected), statement(m.Actual))
+}
+
+// RoundTrip is the outcome of applying the diff from one schema to another onto the first schema, in memory.
+type RoundTrip struct {
+	// Diffs is the number of diffs applied
+	Diffs int
+	// Error is the error ordering or applying the diffs, if any
+	Error string
+	// Mismatches are the entities that differ between the other schema and the result of applying the diffs
+	Mismatches []*RoundTripMismatch
+}
+
+// Converges returns true if applying the diffs produced the other schema.
+func (rt *RoundTrip) Converges() bool {
+	return rt.Error == "" && len(rt.Mismatches) == 0
+}
+
+func (rt *RoundTrip) String() string {
+	var b strings.Builder
+	diffs := fmt.Sprintf("%d diffs", rt.Diffs)
+	if rt.Diffs == 1 {
+		diffs = "1 diff"
+	}
+	switch {
+	case rt.Error != "":
+		fmt.Fprintf(&b, "%s, cannot apply: %s\n", diffs, rt.Error)
+	case rt.Converges():
+		fmt.Fprintf(&b, "%s, converges\n", diffs)
+	default:
+		fmt.Fprintf(&b, "%s, does not converge\n", diffs)
+	}
+	for _, mismatch := range rt.Mismatches {
+		fmt.Fprintf(&b, "  %s\n", mismatch)
+	}
+	return b.String()
+}
+
+// Verification is the outcome of the verify command: the diff in each direction between the source and target
+// schemas, applied in memory.
+type Verification struct {
+	// Forward applies the diff from the source to the target onto the source
+	Forward *RoundTrip
+	// Reverse applies the diff from the target to the source onto the target
+	Reverse *RoundTrip
+}
+
+// Converges returns true if both directions converge.
+func (v *Verification) Converges() bool {
+	return v.Forward.Converges() && v.Reverse.Converges()
+}
+
+func (v *Verification) String() string {
+	return "source -> target: " + v.Forward.String() + "target -> source: " + v.Reverse.String()
+}
+
+// roundTrip applies the ordered diff from one schema to another onto the first schema, and compares the result
+// with the other schema. An error ordering or applying the diff is part of the round trip, rather than returned.
+// The comparison does not use the diff: it compares the canonical CREATE statements of each entity, so that it
+// sees what the diff misses, other than what the hints ignore.
+func roundTrip(ctx context.Context, from *schemadiff.Schema, to *schemadiff.Schema, hints *schemadiff.DiffHints) (*RoundTrip, error) {
+	diff, err := from.SchemaDiff(to, hints)
+	if err != nil {
+		return nil, err
+	}
+	rt := &RoundTrip{Diffs: len(diff.UnorderedDiffs())}
+	diffs, err := diff.OrderedDiffs(ctx)
+	if err != nil {
+		rt.Error = err.Error()
+		return rt, nil
+	}
+	applied, err := from.Apply(diffs)
+	if err != nil {
+		rt.Error = err.Error()
+		return rt, nil
+	}
+	rt.Mismatches = compareSchemas(to, applied, hints)
+	return rt, nil
+}
+
+// compareSchemas returns the entities whose canonical CREATE statements differ between the expected and actual
+// schemas, including entities which only one of them has. Table AUTO_INCREMENT values are not compared when the
+// hints ignore them, as the diff does not apply them. Mismatches are listed in the order of the expected schema,
+// followed by entities only the actual schema has.
+func compareSchemas(expected *schemadiff.Schema, actual *schemadiff.Schema, hints *schemadiff.DiffHints) (mismatches []*RoundTripMismatch) {
+	statement := func(schema *schemadiff.Schema, name string) string {
+		e := schema.Entity(name)
+		if e == nil {
+			return ""
+		}
+		if stmt, ok := e.Create().Statement().(*sqlparser.CreateTable); ok && hints.AutoIncrementStrategy == schemadiff.AutoIncrementIgnore {
+			return sqlparser.CanonicalString(withoutAutoIncrement(stmt))
+		}
+		return e.Create().CanonicalStatementString()
+	}
+	for _, name := range expected.EntityNames() {
+		expectedStatement, actualStatement := statement(expected, name), statement(actual, name)
+		if expectedStatement != actualStatement {
+			mismatches = append(mismatches, &RoundTripMismatch{Entity: name, Expected: expectedStatement, Actual: actualStatement})
+		}
+	}
+	for _, name := range actual.EntityNames() {
+		if expected.Entity(name) == nil {
+			mismatches = append(mismatches, &RoundTripMismatch{Entity: name, Actual: statement(actual, name)})
+		}
+	}
+	return mismatches
+}
+
+// VerifyRoundTrip checks that the diff between the two schemas is correct: applying the diff from the source
+// to the target onto the source, in memory, yields the target, and the same holds in the reverse direction.
+func VerifyRoundTrip(ctx context.Context, source *schemadiff.Schema, target *schemadiff.Schema, hints *schemadiff.DiffHints) (*Verification, error) {
+	if hints == nil {
+		hints = defaultDiffHints
+	}
+	forward, err := roundTrip(ctx, source, target, hints)
+	if err != nil {
+		return nil, err
+	}
+	reverse, err := roundTrip(ctx, target, source, hints)
+	if err != nil {
+		return nil, err
+	}
+	return &Verification{Forward: forward, Reverse: reverse}, nil
+}
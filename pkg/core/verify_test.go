@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/vt/schemadiff"
+)
+
+func TestVerificationString(t *testing.T) {
+	v := &Verification{
+		Forward: &RoundTrip{Diffs: 2},
+		Reverse: &RoundTrip{
+			Diffs: 1,
+			Mismatches: []*RoundTripMismatch{
+				{Entity: "t1", Expected: "CREATE TABLE `t1` (\n\t`c` int\n)", Actual: "CREATE TABLE `t1` (\n\t`c` bigint\n)"},
+				{Entity: "v1", Actual: "CREATE VIEW `v1` AS SELECT `c` FROM `t1`"},
+			},
+		},
+	}
+	assert.False(t, v.Converges())
+	expect := "source -> target: 2 diffs, converges\n" +
+		"target -> source: 1 diff, does not converge\n" +
+		"  t1:\n    expected: CREATE TABLE `t1` (\n    \t`c` int\n    );\n    actual:   CREATE TABLE `t1` (\n    \t`c` bigint\n    );\n" +
+		"  v1:\n    expected: none\n    actual:   CREATE VIEW `v1` AS SELECT `c` FROM `t1`;\n"
+	assert.Equal(t, expect, v.String())
+
+	v.Reverse = &RoundTrip{Diffs: 1, Error: "table t9 does not exist"}
+	assert.False(t, v.Converges())
+	assert.Equal(t, "source -> target: 2 diffs, converges\ntarget -> source: 1 diff, cannot apply: table t9 does not exist\n", v.String())
+
+	v.Reverse = &RoundTrip{}
+	assert.True(t, v.Converges())
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	tcases := []struct {
+		name   string
+		from   []string
+		to     []string
+		expect int
+	}{
+		{
+			name:   "identical",
+			from:   []string{"create table t1 (id int primary key)"},
+			to:     []string{"create table t1 (id int primary key)"},
+			expect: 0,
+		},
+		{
+			name:   "columns and keys",
+			from:   []string{"create table t1 (id int primary key, name varchar(12), key name_idx (name))"},
+			to:     []string{"create table t1 (id bigint primary key, name varchar(32) not null, age int, key age_idx (age))"},
+			expect: 1,
+		},
+		{
+			name: "tables and views",
+			from: []string{
+				"create table t1 (id int primary key, name varchar(12))",
+				"create table t2 (id int primary key)",
+				"create view v1 as select id from t1",
+			},
+			to: []string{
+				"create table t1 (id int primary key, name varchar(12))",
+				"create table t3 (id int primary key, t1_id int, foreign key (t1_id) references t1 (id))",
+				"create view v1 as select id, name from t1",
+				"create view v2 as select id from v1",
+			},
+			expect: 4,
+		},
+	}
+	env := schemadiff.NewTestEnv()
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			from, err := schemadiff.NewSchemaFromQueries(env, tcase.from)
+			require.NoError(t, err)
+			to, err := schemadiff.NewSchemaFromQueries(env, tcase.to)
+			require.NoError(t, err)
+			v, err := VerifyRoundTrip(ctx, from, to, nil)
+			require.NoError(t, err)
+			assert.True(t, v.Converges(), v.String())
+			assert.Equal(t, tcase.expect, v.Forward.Diffs)
+			assert.Equal(t, tcase.expect, v.Reverse.Diffs)
+		})
+	}
+}
+
+func TestVerifyRoundTripIgnoredByHints(t *testing.T) {
+	// The diff ignores AUTO_INCREMENT values, and so does the comparison, unless the hints apply them
+	env := schemadiff.NewTestEnv()
+	from, err := schemadiff.NewSchemaFromQueries(env, []string{"create table t1 (id int auto_increment primary key) auto_increment=10"})
+	require.NoError(t, err)
+	to, err := schemadiff.NewSchemaFromQueries(env, []string{"create table t1 (id int auto_increment primary key, name varchar(12)) auto_increment=20"})
+	require.NoError(t, err)
+	v, err := VerifyRoundTrip(context.Background(), from, to, nil)
+	require.NoError(t, err)
+	assert.True(t, v.Converges(), v.String())
+
+	applied, err := schemadiff.NewSchemaFromQueries(env, []string{"create table t1 (id int auto_increment primary key, name varchar(12)) auto_increment=10"})
+	require.NoError(t, err)
+	assert.Empty(t, compareSchemas(to, applied, defaultDiffHints))
+	mismatches := compareSchemas(to, applied, &schemadiff.DiffHints{AutoIncrementStrategy: schemadiff.AutoIncrementApplyAlways})
+	require.Len(t, mismatches, 1)
+	assert.Contains(t, mismatches[0].Expected, "AUTO_INCREMENT 20")
+	assert.Contains(t, mismatches[0].Actual, "AUTO_INCREMENT 10")
+}
+
+func TestCompareSchemas(t *testing.T) {
+	env := schemadiff.NewTestEnv()
+	expected, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table t1 (id int primary key)",
+		"create table t2 (id int primary key)",
+	})
+	require.NoError(t, err)
+	actual, err := schemadiff.NewSchemaFromQueries(env, []string{
+		"create table t1 (id int primary key)",
+		"create table t3 (id int primary key)",
+	})
+	require.NoError(t, err)
+	mismatches := compareSchemas(expected, actual, defaultDiffHints)
+	require.Len(t, mismatches, 2)
+	assert.Equal(t, "t2", mismatches[0].Entity)
+	assert.Empty(t, mismatches[0].Actual)
+	assert.Equal(t, "t3", mismatches[1].Entity)
+	assert.Empty(t, mismatches[1].Expected)
+	assert.Empty(t, compareSchemas(expected, expected, defaultDiffHints))
+}
+
+func TestExecVerify(t *testing.T) {
+	ctx := context.Background()
+	fileFrom := writeSchemaFile(t, schemaFrom)
+	defer os.RemoveAll(fileFrom)
+	fileTo := writeSchemaFile(t, schemaTo)
+	defer os.RemoveAll(fileTo)
+
+	output, err := Exec(ctx, "verify", fileFrom, fileTo, nil)
+	require.NoError(t, err)
+	assert.Regexp(t, `^source -> target: \d+ diffs?, converges\ntarget -> source: \d+ diffs?, converges\n$`, output)
+
+	_, err = Exec(ctx, "verify", fileFrom, fileFrom, nil)
+	assert.ErrorIs(t, err, ErrIdenticalSourceTarget)
+	_, err = Exec(ctx, "verify", fileFrom, fileTo, &ExecOptions{Mapping: []string{"t1=t9"}})
+	assert.ErrorContains(t, err, "not supported by verify")
+}
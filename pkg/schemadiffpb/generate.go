@@ -0,0 +1,4 @@
+// Package schemadiffpb holds the generated protobuf and gRPC code for the SchemaDiff service, defined in proto/schemadiff.proto.
+package schemadiffpb
+
+//go:generate protoc --proto_path=../../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative schemadiff.proto
@@ -0,0 +1,299 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.27.1
+// source: schemadiff.proto
+
+package schemadiffpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SchemaDiff_Load_FullMethodName        = "/schemadiff.SchemaDiff/Load"
+	SchemaDiff_Diff_FullMethodName        = "/schemadiff.SchemaDiff/Diff"
+	SchemaDiff_OrderedDiff_FullMethodName = "/schemadiff.SchemaDiff/OrderedDiff"
+	SchemaDiff_Apply_FullMethodName       = "/schemadiff.SchemaDiff/Apply"
+	SchemaDiff_Validate_FullMethodName    = "/schemadiff.SchemaDiff/Validate"
+)
+
+// SchemaDiffClient is the client API for SchemaDiff service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SchemaDiff loads, validates, diffs and applies MySQL schemas.
+type SchemaDiffClient interface {
+	// Load normalizes a schema, streaming one entity per message.
+	Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entity], error)
+	// Diff streams the diffs between two schemas, one entity diff per message, in no particular order.
+	Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EntityDiff], error)
+	// OrderedDiff streams the diffs between two schemas, one entity diff per message, in an order valid to apply.
+	// Each diff is sent as soon as the diffs it depends on are sent.
+	OrderedDiff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EntityDiff], error)
+	// Apply computes the ordered diff between two schemas, and applies it onto the source schema.
+	Apply(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*ApplyResponse, error)
+	// Validate reports whether a schema is valid. An invalid schema is not an RPC error.
+	Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+}
+
+type schemaDiffClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSchemaDiffClient(cc grpc.ClientConnInterface) SchemaDiffClient {
+	return &schemaDiffClient{cc}
+}
+
+func (c *schemaDiffClient) Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entity], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SchemaDiff_ServiceDesc.Streams[0], SchemaDiff_Load_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LoadRequest, Entity]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SchemaDiff_LoadClient = grpc.ServerStreamingClient[Entity]
+
+func (c *schemaDiffClient) Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EntityDiff], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SchemaDiff_ServiceDesc.Streams[1], SchemaDiff_Diff_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DiffRequest, EntityDiff]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SchemaDiff_DiffClient = grpc.ServerStreamingClient[EntityDiff]
+
+func (c *schemaDiffClient) OrderedDiff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EntityDiff], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SchemaDiff_ServiceDesc.Streams[2], SchemaDiff_OrderedDiff_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DiffRequest, EntityDiff]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SchemaDiff_OrderedDiffClient = grpc.ServerStreamingClient[EntityDiff]
+
+func (c *schemaDiffClient) Apply(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*ApplyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApplyResponse)
+	err := c.cc.Invoke(ctx, SchemaDiff_Apply_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schemaDiffClient) Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, SchemaDiff_Validate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SchemaDiffServer is the server API for SchemaDiff service.
+// All implementations must embed UnimplementedSchemaDiffServer
+// for forward compatibility.
+//
+// SchemaDiff loads, validates, diffs and applies MySQL schemas.
+type SchemaDiffServer interface {
+	// Load normalizes a schema, streaming one entity per message.
+	Load(*LoadRequest, grpc.ServerStreamingServer[Entity]) error
+	// Diff streams the diffs between two schemas, one entity diff per message, in no particular order.
+	Diff(*DiffRequest, grpc.ServerStreamingServer[EntityDiff]) error
+	// OrderedDiff streams the diffs between two schemas, one entity diff per message, in an order valid to apply.
+	// Each diff is sent as soon as the diffs it depends on are sent.
+	OrderedDiff(*DiffRequest, grpc.ServerStreamingServer[EntityDiff]) error
+	// Apply computes the ordered diff between two schemas, and applies it onto the source schema.
+	Apply(context.Context, *DiffRequest) (*ApplyResponse, error)
+	// Validate reports whether a schema is valid. An invalid schema is not an RPC error.
+	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
+	mustEmbedUnimplementedSchemaDiffServer()
+}
+
+// UnimplementedSchemaDiffServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSchemaDiffServer struct{}
+
+func (UnimplementedSchemaDiffServer) Load(*LoadRequest, grpc.ServerStreamingServer[Entity]) error {
+	return status.Errorf(codes.Unimplemented, "method Load not implemented")
+}
+func (UnimplementedSchemaDiffServer) Diff(*DiffRequest, grpc.ServerStreamingServer[EntityDiff]) error {
+	return status.Errorf(codes.Unimplemented, "method Diff not implemented")
+}
+func (UnimplementedSchemaDiffServer) OrderedDiff(*DiffRequest, grpc.ServerStreamingServer[EntityDiff]) error {
+	return status.Errorf(codes.Unimplemented, "method OrderedDiff not implemented")
+}
+func (UnimplementedSchemaDiffServer) Apply(context.Context, *DiffRequest) (*ApplyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Apply not implemented")
+}
+func (UnimplementedSchemaDiffServer) Validate(context.Context, *ValidateRequest) (*ValidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Validate not implemented")
+}
+func (UnimplementedSchemaDiffServer) mustEmbedUnimplementedSchemaDiffServer() {}
+func (UnimplementedSchemaDiffServer) testEmbeddedByValue()                    {}
+
+// UnsafeSchemaDiffServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SchemaDiffServer will
+// result in compilation errors.
+type UnsafeSchemaDiffServer interface {
+	mustEmbedUnimplementedSchemaDiffServer()
+}
+
+func RegisterSchemaDiffServer(s grpc.ServiceRegistrar, srv SchemaDiffServer) {
+	// If the following call pancis, it indicates UnimplementedSchemaDiffServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SchemaDiff_ServiceDesc, srv)
+}
+
+func _SchemaDiff_Load_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LoadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SchemaDiffServer).Load(m, &grpc.GenericServerStream[LoadRequest, Entity]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SchemaDiff_LoadServer = grpc.ServerStreamingServer[Entity]
+
+func _SchemaDiff_Diff_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DiffRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SchemaDiffServer).Diff(m, &grpc.GenericServerStream[DiffRequest, EntityDiff]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SchemaDiff_DiffServer = grpc.ServerStreamingServer[EntityDiff]
+
+func _SchemaDiff_OrderedDiff_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DiffRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SchemaDiffServer).OrderedDiff(m, &grpc.GenericServerStream[DiffRequest, EntityDiff]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SchemaDiff_OrderedDiffServer = grpc.ServerStreamingServer[EntityDiff]
+
+func _SchemaDiff_Apply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaDiffServer).Apply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaDiff_Apply_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaDiffServer).Apply(ctx, req.(*DiffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchemaDiff_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaDiffServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaDiff_Validate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaDiffServer).Validate(ctx, req.(*ValidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SchemaDiff_ServiceDesc is the grpc.ServiceDesc for SchemaDiff service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SchemaDiff_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "schemadiff.SchemaDiff",
+	HandlerType: (*SchemaDiffServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Apply",
+			Handler:    _SchemaDiff_Apply_Handler,
+		},
+		{
+			MethodName: "Validate",
+			Handler:    _SchemaDiff_Validate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Load",
+			Handler:       _SchemaDiff_Load_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Diff",
+			Handler:       _SchemaDiff_Diff_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "OrderedDiff",
+			Handler:       _SchemaDiff_OrderedDiff_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "schemadiff.proto",
+}
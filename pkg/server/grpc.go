@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"vitess.io/vitess/go/vt/schemadiff"
+
+	"github.com/planetscale/schemadiff/pkg/core"
+	"github.com/planetscale/schemadiff/pkg/schemadiffpb"
+)
+
+// grpcService implements the SchemaDiff gRPC service on top of the server's environments and DSN allowlist.
+type grpcService struct {
+	schemadiffpb.UnimplementedSchemaDiffServer
+	server *Server
+}
+
+// RegisterGRPC registers the SchemaDiff gRPC service with the given gRPC server.
+func (s *Server) RegisterGRPC(registrar grpc.ServiceRegistrar) {
+	schemadiffpb.RegisterSchemaDiffServer(registrar, &grpcService{server: s})
+}
+
+// NewGRPCServer returns a gRPC server serving the SchemaDiff service, limited by the configured maximum request size.
+func (s *Server) NewGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.MaxRecvMsgSize(int(s.config.MaxRequestSize)))
+	s.RegisterGRPC(grpcServer)
+	return grpcServer
+}
+
+// ListenAndServeGRPC serves gRPC on the configured GRPCListen address until the given context is canceled.
+func (s *Server) ListenAndServeGRPC(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.GRPCListen)
+	if err != nil {
+		return err
+	}
+	grpcServer := s.NewGRPCServer()
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+	return grpcServer.Serve(listener)
+}
+
+// grpcError converts an error into a gRPC status error.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		switch reqErr.status {
+		case http.StatusForbidden:
+			return status.Error(codes.PermissionDenied, err.Error())
+		case http.StatusRequestEntityTooLarge:
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// request returns the context of a single RPC, applying the configured request timeout. The caller must call cancel.
+func (g *grpcService) request(ctx context.Context, options *schemadiffpb.Options) (req *request, cancel context.CancelFunc, err error) {
+	env, err := g.server.env(options.GetMysqlVersion())
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, cancel = context.WithTimeout(ctx, g.server.config.RequestTimeout)
+	return &request{
+		ctx:     ctx,
+		env:     env,
+		hints:   core.DefaultDiffHints(),
+		textual: options.GetTextual(),
+	}, cancel, nil
+}
+
+// readSchema converts a schema input into a list of statements.
+func (g *grpcService) readSchema(req *request, schema *schemadiffpb.Schema) ([]string, error) {
+	switch input := schema.GetInput().(type) {
+	case *schemadiffpb.Schema_Sql:
+		return req.env.Parser().SplitStatementToPieces(strings.TrimSpace(input.Sql))
+	case *schemadiffpb.Schema_Statements:
+		return input.Statements.GetStatements(), nil
+	case *schemadiffpb.Schema_Dsn:
+		return g.server.readDSN(req, input.Dsn)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "schema expected: one of sql, statements or dsn")
+	}
+}
+
+func (g *grpcService) readSourceTarget(req *request, in *schemadiffpb.DiffRequest) (source []string, target []string, err error) {
+	if source, err = g.readSchema(req, in.GetSource()); err != nil {
+		return nil, nil, err
+	}
+	if target, err = g.readSchema(req, in.GetTarget()); err != nil {
+		return nil, nil, err
+	}
+	return source, target, nil
+}
+
+func newEntityDiff(req *request, d schemadiff.EntityDiff) *schemadiffpb.EntityDiff {
+	entityDiff := &schemadiffpb.EntityDiff{
+		Entity:    d.EntityName(),
+		Statement: d.CanonicalStatementString(),
+	}
+	if req.textual {
+		_, _, unified := d.Annotated()
+		entityDiff.Annotated = unified.Export()
+	}
+	return entityDiff
+}
+
+func newEntities(schema *schemadiff.Schema) []*schemadiffpb.Entity {
+	var entities []*schemadiffpb.Entity
+	for _, e := range schema.Entities() {
+		entities = append(entities, &schemadiffpb.Entity{
+			Name:      e.Name(),
+			Statement: e.Create().CanonicalStatementString(),
+		})
+	}
+	return entities
+}
+
+func (g *grpcService) Load(in *schemadiffpb.LoadRequest, stream grpc.ServerStreamingServer[schemadiffpb.Entity]) error {
+	req, cancel, err := g.request(stream.Context(), in.GetOptions())
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	sqls, err := g.readSchema(req, in.GetSchema())
+	if err != nil {
+		return grpcError(err)
+	}
+	schema, err := schemadiff.NewSchemaFromQueries(req.env, sqls)
+	if err != nil {
+		return grpcError(err)
+	}
+	for _, entity := range newEntities(schema) {
+		if err := stream.Send(entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamDiffs sends one entity diff per message.
+func streamDiffs(req *request, stream grpc.ServerStreamingServer[schemadiffpb.EntityDiff], diffs []schemadiff.EntityDiff) error {
+	for _, d := range diffs {
+		if err := req.ctx.Err(); err != nil {
+			return grpcError(err)
+		}
+		if err := stream.Send(newEntityDiff(req, d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *grpcService) Diff(in *schemadiffpb.DiffRequest, stream grpc.ServerStreamingServer[schemadiffpb.EntityDiff]) error {
+	req, cancel, err := g.request(stream.Context(), in.GetOptions())
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	sourceSQLs, targetSQLs, err := g.readSourceTarget(req, in)
+	if err != nil {
+		return grpcError(err)
+	}
+	_, diff, err := schemaDiff(req.env, req.hints, sourceSQLs, targetSQLs)
+	if err != nil {
+		return grpcError(err)
+	}
+	return streamDiffs(req, stream, diff.UnorderedDiffs())
+}
+
+// streamOrderedDiffs sends the diffs from source to target in an order valid to apply, as soon as the order
+// allows: each pass sends the diffs which apply onto the source with the diffs sent so far, and applies them. When
+// a pass sends no diff, the remaining diffs depend on each other, and are ordered together by schemadiff.
+func streamOrderedDiffs(req *request, stream grpc.ServerStreamingServer[schemadiffpb.EntityDiff], source *schemadiff.Schema, target *schemadiff.Schema, diff *schemadiff.SchemaDiff) error {
+	schema := source
+	remaining := diff.UnorderedDiffs()
+	for len(remaining) > 0 {
+		var pending []schemadiff.EntityDiff
+		for _, d := range remaining {
+			if err := req.ctx.Err(); err != nil {
+				return grpcError(err)
+			}
+			applied, err := schema.Apply([]schemadiff.EntityDiff{d})
+			if err != nil {
+				pending = append(pending, d)
+				continue
+			}
+			if err := stream.Send(newEntityDiff(req, d)); err != nil {
+				return err
+			}
+			schema = applied
+		}
+		if len(pending) == len(remaining) {
+			rest, err := schema.SchemaDiff(target, req.hints)
+			if err != nil {
+				return grpcError(err)
+			}
+			diffs, err := rest.OrderedDiffs(req.ctx)
+			if err != nil {
+				return grpcError(err)
+			}
+			return streamDiffs(req, stream, diffs)
+		}
+		remaining = pending
+	}
+	return nil
+}
+
+// OrderedDiff streams the diffs in an order valid to apply, sending each diff as soon as the diffs before it are
+// sent. See streamOrderedDiffs.
+func (g *grpcService) OrderedDiff(in *schemadiffpb.DiffRequest, stream grpc.ServerStreamingServer[schemadiffpb.EntityDiff]) error {
+	req, cancel, err := g.request(stream.Context(), in.GetOptions())
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	sourceSQLs, targetSQLs, err := g.readSourceTarget(req, in)
+	if err != nil {
+		return grpcError(err)
+	}
+	source, err := schemadiff.NewSchemaFromQueries(req.env, sourceSQLs)
+	if err != nil {
+		return grpcError(err)
+	}
+	target, err := schemadiff.NewSchemaFromQueries(req.env, targetSQLs)
+	if err != nil {
+		return grpcError(err)
+	}
+	diff, err := source.SchemaDiff(target, req.hints)
+	if err != nil {
+		return grpcError(err)
+	}
+	return streamOrderedDiffs(req, stream, source, target, diff)
+}
+
+func (g *grpcService) Apply(ctx context.Context, in *schemadiffpb.DiffRequest) (*schemadiffpb.ApplyResponse, error) {
+	req, cancel, err := g.request(ctx, in.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	sourceSQLs, targetSQLs, err := g.readSourceTarget(req, in)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	diffs, applied, err := applyDiff(req.ctx, req.env, req.hints, sourceSQLs, targetSQLs)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &schemadiffpb.ApplyResponse{
+		Entities: newEntities(applied),
+	}
+	for _, d := range diffs {
+		response.Diffs = append(response.Diffs, newEntityDiff(req, d))
+	}
+	return response, nil
+}
+
+func (g *grpcService) Validate(ctx context.Context, in *schemadiffpb.ValidateRequest) (*schemadiffpb.ValidateResponse, error) {
+	req, cancel, err := g.request(ctx, in.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	sqls, err := g.readSchema(req, in.GetSchema())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	validationErrors := validate(req.env, sqls)
+	return &schemadiffpb.ValidateResponse{
+		Valid:  len(validationErrors) == 0,
+		Errors: validationErrors,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/planetscale/schemadiff/pkg/schemadiffpb"
+)
+
+// newGRPCClient serves the SchemaDiff service over an in-memory connection, and returns a client to it.
+func newGRPCClient(t *testing.T, config Config) schemadiffpb.SchemaDiffClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := New(config).NewGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return schemadiffpb.NewSchemaDiffClient(conn)
+}
+
+// receiveAll reads a server stream to its end.
+func receiveAll[T any](t *testing.T, stream grpc.ServerStreamingClient[T]) ([]*T, error) {
+	var messages []*T
+	for {
+		message, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return messages, nil
+		}
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+}
+
+func sqlSchema(sql string) *schemadiffpb.Schema {
+	return &schemadiffpb.Schema{Input: &schemadiffpb.Schema_Sql{Sql: sql}}
+}
+
+func TestGRPCLoad(t *testing.T) {
+	ctx := context.Background()
+	client := newGRPCClient(t, Config{})
+
+	stream, err := client.Load(ctx, &schemadiffpb.LoadRequest{
+		Schema: sqlSchema("create table t2 (id int primary key); create table t1 (id int primary key)"),
+	})
+	require.NoError(t, err)
+	entities, err := receiveAll(t, stream)
+	require.NoError(t, err)
+	require.Len(t, entities, 2)
+	assert.Equal(t, "t1", entities[0].Name)
+	assert.Equal(t, "CREATE TABLE `t1` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)", entities[0].Statement)
+	assert.Equal(t, "t2", entities[1].Name)
+}
+
+func TestGRPCDiff(t *testing.T) {
+	ctx := context.Background()
+	client := newGRPCClient(t, Config{})
+	request := &schemadiffpb.DiffRequest{
+		Source: sqlSchema("create table t1 (id int primary key); create view v1 as select id from t1"),
+		Target: &schemadiffpb.Schema{Input: &schemadiffpb.Schema_Statements{Statements: &schemadiffpb.Statements{
+			Statements: []string{"create table t1 (id int unsigned primary key)", "create table t2 (id int primary key)"},
+		}}},
+	}
+	t.Run("diff", func(t *testing.T) {
+		stream, err := client.Diff(ctx, request)
+		require.NoError(t, err)
+		diffs, err := receiveAll(t, stream)
+		require.NoError(t, err)
+		require.Len(t, diffs, 3)
+		var statements []string
+		for _, d := range diffs {
+			statements = append(statements, d.Statement)
+			assert.Empty(t, d.Annotated)
+		}
+		assert.Contains(t, statements, "DROP VIEW `v1`")
+		assert.Contains(t, statements, "ALTER TABLE `t1` MODIFY COLUMN `id` int unsigned")
+	})
+	t.Run("ordered-diff", func(t *testing.T) {
+		stream, err := client.OrderedDiff(ctx, request)
+		require.NoError(t, err)
+		diffs, err := receiveAll(t, stream)
+		require.NoError(t, err)
+		require.Len(t, diffs, 3)
+
+		stream, err = client.OrderedDiff(ctx, &schemadiffpb.DiffRequest{
+			Source: request.Source,
+			Target: sqlSchema("create table t2 (id int primary key, t3_id int, foreign key (t3_id) references t3 (id)); create table t3 (id int primary key)"),
+		})
+		require.NoError(t, err)
+		diffs, err = receiveAll(t, stream)
+		require.NoError(t, err)
+		require.Len(t, diffs, 4)
+		order := map[string]int{}
+		for i, d := range diffs {
+			order[d.Entity] = i
+		}
+		assert.Less(t, order["v1"], order["t1"])
+		assert.Less(t, order["t3"], order["t2"])
+	})
+	t.Run("textual", func(t *testing.T) {
+		stream, err := client.Diff(ctx, &schemadiffpb.DiffRequest{
+			Source:  request.Source,
+			Target:  request.Target,
+			Options: &schemadiffpb.Options{Textual: true},
+		})
+		require.NoError(t, err)
+		diffs, err := receiveAll(t, stream)
+		require.NoError(t, err)
+		for _, d := range diffs {
+			assert.NotEmpty(t, d.Annotated)
+		}
+	})
+	t.Run("apply", func(t *testing.T) {
+		response, err := client.Apply(ctx, request)
+		require.NoError(t, err)
+		assert.Len(t, response.Diffs, 3)
+		require.Len(t, response.Entities, 2)
+		assert.Equal(t, "CREATE TABLE `t1` (\n\t`id` int unsigned,\n\tPRIMARY KEY (`id`)\n)", response.Entities[0].Statement)
+		assert.Equal(t, "t2", response.Entities[1].Name)
+	})
+}
+
+func TestGRPCValidate(t *testing.T) {
+	ctx := context.Background()
+	client := newGRPCClient(t, Config{})
+
+	response, err := client.Validate(ctx, &schemadiffpb.ValidateRequest{Schema: sqlSchema("create table t1 (id int primary key)")})
+	require.NoError(t, err)
+	assert.True(t, response.Valid)
+	assert.Empty(t, response.Errors)
+
+	response, err = client.Validate(ctx, &schemadiffpb.ValidateRequest{Schema: sqlSchema("create view v1 as select * from t_missing")})
+	require.NoError(t, err)
+	assert.False(t, response.Valid)
+	assert.NotEmpty(t, response.Errors)
+}
+
+func TestGRPCErrors(t *testing.T) {
+	ctx := context.Background()
+	client := newGRPCClient(t, Config{AllowedDSNAddresses: []string{"127.0.0.1:3306"}})
+	tcases := []struct {
+		name        string
+		request     *schemadiffpb.DiffRequest
+		expectCode  codes.Code
+		expectError string
+	}{
+		{
+			name:        "missing source",
+			request:     &schemadiffpb.DiffRequest{Target: sqlSchema("")},
+			expectCode:  codes.InvalidArgument,
+			expectError: "schema expected",
+		},
+		{
+			name: "dsn not allowed",
+			request: &schemadiffpb.DiffRequest{
+				Source: &schemadiffpb.Schema{Input: &schemadiffpb.Schema_Dsn{Dsn: "u:p@tcp(10.0.0.1:3306)/db"}},
+				Target: sqlSchema(""),
+			},
+			expectCode:  codes.PermissionDenied,
+			expectError: "not in the allowlist: 10.0.0.1:3306",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			stream, err := client.Diff(ctx, tcase.request)
+			require.NoError(t, err)
+			_, err = receiveAll(t, stream)
+			require.Error(t, err)
+			assert.Equal(t, tcase.expectCode, status.Code(err))
+			assert.Contains(t, status.Convert(err).Message(), tcase.expectError)
+		})
+	}
+}
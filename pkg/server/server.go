@@ -0,0 +1,455 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"vitess.io/vitess/go/vt/schemadiff"
+
+	"github.com/planetscale/schemadiff/pkg/base"
+	"github.com/planetscale/schemadiff/pkg/core"
+)
+
+const (
+	DefaultListen         = ":8080"
+	DefaultMaxRequestSize = 16 * 1024 * 1024
+	DefaultRequestTimeout = 30 * time.Second
+)
+
+// maxCachedEnvs bounds the number of MySQL version environments the server keeps. The version is given by the
+// request, so environments for further versions are created per request rather than cached.
+const maxCachedEnvs = 16
+
+var (
+	ErrDSNNotAllowed = errors.New("DSN address is not in the allowlist")
+)
+
+// Config configures the HTTP server.
+type Config struct {
+	// Listen is the address to listen on, e.g. ":8080"
+	Listen string
+	// GRPCListen is the address to serve gRPC on, e.g. ":9090". Used by ListenAndServeGRPC.
+	GRPCListen string
+	// AllowedDSNAddresses lists the MySQL addresses (host:port, or unix socket path) a request may read a schema from.
+	// When empty, requests may only provide schemas inline.
+	AllowedDSNAddresses []string
+	// MaxRequestSize is the maximum request body size, in bytes
+	MaxRequestSize int64
+	// RequestTimeout is the maximum duration of a request
+	RequestTimeout time.Duration
+}
+
+// Server serves schemadiff operations as a JSON API over HTTP.
+type Server struct {
+	config Config
+
+	mu   sync.Mutex
+	envs map[string]*schemadiff.Environment // MySQL version => environment
+}
+
+// New returns a new server. Zero valued config fields are set to their defaults.
+func New(config Config) *Server {
+	if config.Listen == "" {
+		config.Listen = DefaultListen
+	}
+	if config.MaxRequestSize <= 0 {
+		config.MaxRequestSize = DefaultMaxRequestSize
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = DefaultRequestTimeout
+	}
+	return &Server{
+		config: config,
+		envs:   map[string]*schemadiff.Environment{},
+	}
+}
+
+// env returns the environment for the given MySQL version, creating it on first use. Up to maxCachedEnvs
+// environments are cached.
+func (s *Server) env(mysqlVersion string) (*schemadiff.Environment, error) {
+	if mysqlVersion == "" {
+		mysqlVersion = core.DefaultMySQLVersion
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if env, ok := s.envs[mysqlVersion]; ok {
+		return env, nil
+	}
+	env, err := core.NewEnv(mysqlVersion)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.envs) < maxCachedEnvs {
+		s.envs[mysqlVersion] = env
+	}
+	return env, nil
+}
+
+// Handler returns the HTTP handler serving all endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/load", s.handle(s.load))
+	mux.HandleFunc("/lint", s.handle(s.lint))
+	mux.HandleFunc("/diff", s.handle(s.diff(false)))
+	mux.HandleFunc("/ordered-diff", s.handle(s.diff(true)))
+	mux.HandleFunc("/diff-table", s.handle(s.diffEntity(schemadiff.DiffCreateTablesQueries, "CREATE TABLE")))
+	mux.HandleFunc("/diff-view", s.handle(s.diffEntity(schemadiff.DiffCreateViewsQueries, "CREATE VIEW")))
+	mux.HandleFunc("/apply", s.handle(s.apply))
+	return http.TimeoutHandler(mux, s.config.RequestTimeout, `{"error":"request timed out"}`)
+}
+
+// ListenAndServe serves until the given context is canceled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:              s.config.Listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: s.config.RequestTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// requestError is an error caused by the request, as opposed to an error in the schema
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// request is the context of a single API request
+type request struct {
+	ctx     context.Context
+	env     *schemadiff.Environment
+	hints   *schemadiff.DiffHints
+	textual bool
+	body    []byte
+	json    bool
+}
+
+type handlerFunc func(req *request) (any, error)
+
+// handle wraps an endpoint implementation with method and size checks, environment lookup, and JSON encoding
+func (s *Server) handle(f handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := func() (any, error) {
+			if r.Method != http.MethodPost {
+				return nil, &requestError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("method %s not allowed, use POST", r.Method)}
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxRequestSize))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					return nil, &requestError{status: http.StatusRequestEntityTooLarge, err: fmt.Errorf("request body exceeds %d bytes", s.config.MaxRequestSize)}
+				}
+				return nil, &requestError{status: http.StatusBadRequest, err: err}
+			}
+			env, err := s.env(r.URL.Query().Get("mysql_version"))
+			if err != nil {
+				return nil, &requestError{status: http.StatusBadRequest, err: err}
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), s.config.RequestTimeout)
+			defer cancel()
+			return f(&request{
+				ctx:     ctx,
+				env:     env,
+				hints:   core.DefaultDiffHints(),
+				textual: r.URL.Query().Get("textual") == "true",
+				body:    body,
+				json:    strings.HasPrefix(r.Header.Get("Content-Type"), "application/json"),
+			})
+		}()
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			status := http.StatusUnprocessableEntity
+			var reqErr *requestError
+			if errors.As(err, &reqErr) {
+				status = reqErr.status
+			}
+			w.WriteHeader(status)
+			result = map[string]string{"error": err.Error()}
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// readSchemaValue converts a JSON schema value into a list of statements. The value is either a string of
+// SQL text, an array of statements, or an object of the form {"dsn": "..."}, read from a MySQL server.
+func (s *Server) readSchemaValue(req *request, value json.RawMessage) ([]string, error) {
+	var sql string
+	if err := json.Unmarshal(value, &sql); err == nil {
+		return req.env.Parser().SplitStatementToPieces(strings.TrimSpace(sql))
+	}
+	var sqls []string
+	if err := json.Unmarshal(value, &sqls); err == nil {
+		return sqls, nil
+	}
+	var dsnValue struct {
+		DSN string `json:"dsn"`
+	}
+	if err := json.Unmarshal(value, &dsnValue); err == nil && dsnValue.DSN != "" {
+		return s.readDSN(req, dsnValue.DSN)
+	}
+	return nil, &requestError{status: http.StatusBadRequest, err: errors.New(`schema must be SQL text, an array of statements, or {"dsn": "..."}`)}
+}
+
+// readDSN reads a schema from a MySQL server, provided its address is allowlisted. Reading is canceled along with the
+// request.
+func (s *Server) readDSN(req *request, dsn string) ([]string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, &requestError{status: http.StatusBadRequest, err: err}
+	}
+	allowed := false
+	for _, addr := range s.config.AllowedDSNAddresses {
+		if cfg.Addr == addr {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, &requestError{status: http.StatusForbidden, err: fmt.Errorf("%w: %s", ErrDSNNotAllowed, cfg.Addr)}
+	}
+	// The explicit scheme keeps the value from being interpreted as a local path.
+	return base.ReadSQLsFromSource(req.ctx, req.env, "mysql://"+dsn, nil)
+}
+
+// readSingleSchema reads the request body as a single schema: SQL text, or a JSON schema value.
+func (s *Server) readSingleSchema(req *request) ([]string, error) {
+	if !req.json {
+		return req.env.Parser().SplitStatementToPieces(strings.TrimSpace(string(req.body)))
+	}
+	return s.readSchemaValue(req, req.body)
+}
+
+// readSourceTarget reads the request body as a JSON object of the form {"source": <schema>, "target": <schema>}
+func (s *Server) readSourceTarget(req *request) (source []string, target []string, err error) {
+	var values struct {
+		Source json.RawMessage `json:"source"`
+		Target json.RawMessage `json:"target"`
+	}
+	if err := json.Unmarshal(req.body, &values); err != nil {
+		return nil, nil, &requestError{status: http.StatusBadRequest, err: fmt.Errorf(`expected {"source": ..., "target": ...}: %w`, err)}
+	}
+	if values.Source == nil || values.Target == nil {
+		return nil, nil, &requestError{status: http.StatusBadRequest, err: errors.New(`expected {"source": ..., "target": ...}`)}
+	}
+	if source, err = s.readSchemaValue(req, values.Source); err != nil {
+		return nil, nil, err
+	}
+	if target, err = s.readSchemaValue(req, values.Target); err != nil {
+		return nil, nil, err
+	}
+	return source, target, nil
+}
+
+type diffResult struct {
+	Entity    string `json:"entity"`
+	Statement string `json:"statement"`
+	Annotated string `json:"annotated,omitempty"`
+}
+
+type diffsResponse struct {
+	Diffs []*diffResult `json:"diffs"`
+}
+
+type schemaResponse struct {
+	Statements []string `json:"statements"`
+}
+
+type lintResponse struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+}
+
+type applyResponse struct {
+	Diffs      []*diffResult `json:"diffs"`
+	Statements []string      `json:"statements"`
+}
+
+func newDiffResults(req *request, diffs []schemadiff.EntityDiff) []*diffResult {
+	results := []*diffResult{}
+	for _, d := range diffs {
+		result := &diffResult{
+			Entity:    d.EntityName(),
+			Statement: d.CanonicalStatementString(),
+		}
+		if req.textual {
+			_, _, unified := d.Annotated()
+			result.Annotated = unified.Export()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func schemaStatements(schema *schemadiff.Schema) []string {
+	statements := []string{}
+	for _, e := range schema.Entities() {
+		statements = append(statements, e.Create().CanonicalStatementString())
+	}
+	return statements
+}
+
+func (s *Server) load(req *request) (any, error) {
+	sqls, err := s.readSingleSchema(req)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := schemadiff.NewSchemaFromQueries(req.env, sqls)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaResponse{Statements: schemaStatements(schema)}, nil
+}
+
+// lint validates a schema. Unlike load, an invalid schema is not an error: the response lists the validation errors.
+func (s *Server) lint(req *request) (any, error) {
+	sqls, err := s.readSingleSchema(req)
+	if err != nil {
+		return nil, err
+	}
+	validationErrors := validate(req.env, sqls)
+	return &lintResponse{Valid: len(validationErrors) == 0, Errors: validationErrors}, nil
+}
+
+// validate loads a schema and returns its validation errors, if any.
+func validate(env *schemadiff.Environment, sqls []string) []string {
+	validationErrors := []string{}
+	if _, err := schemadiff.NewSchemaFromQueries(env, sqls); err != nil {
+		var joined interface{ Unwrap() []error }
+		if errors.As(err, &joined) {
+			for _, err := range joined.Unwrap() {
+				validationErrors = append(validationErrors, err.Error())
+			}
+		} else {
+			validationErrors = append(validationErrors, err.Error())
+		}
+	}
+	return validationErrors
+}
+
+// schemaDiff loads the source and target schemas and diffs them.
+func schemaDiff(env *schemadiff.Environment, hints *schemadiff.DiffHints, sourceSQLs []string, targetSQLs []string) (*schemadiff.Schema, *schemadiff.SchemaDiff, error) {
+	sourceSchema, err := schemadiff.NewSchemaFromQueries(env, sourceSQLs)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetSchema, err := schemadiff.NewSchemaFromQueries(env, targetSQLs)
+	if err != nil {
+		return nil, nil, err
+	}
+	diff, err := sourceSchema.SchemaDiff(targetSchema, hints)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sourceSchema, diff, nil
+}
+
+// applyDiff computes the ordered diff between source and target, and applies it onto the source schema.
+func applyDiff(ctx context.Context, env *schemadiff.Environment, hints *schemadiff.DiffHints, sourceSQLs []string, targetSQLs []string) ([]schemadiff.EntityDiff, *schemadiff.Schema, error) {
+	sourceSchema, diff, err := schemaDiff(env, hints, sourceSQLs, targetSQLs)
+	if err != nil {
+		return nil, nil, err
+	}
+	diffs, err := diff.OrderedDiffs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := sourceSchema.Apply(diffs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return diffs, applied, nil
+}
+
+func (s *Server) diff(ordered bool) handlerFunc {
+	return func(req *request) (any, error) {
+		sourceSQLs, targetSQLs, err := s.readSourceTarget(req)
+		if err != nil {
+			return nil, err
+		}
+		_, diff, err := schemaDiff(req.env, req.hints, sourceSQLs, targetSQLs)
+		if err != nil {
+			return nil, err
+		}
+		diffs := diff.UnorderedDiffs()
+		if ordered {
+			if diffs, err = diff.OrderedDiffs(req.ctx); err != nil {
+				return nil, err
+			}
+		}
+		return &diffsResponse{Diffs: newDiffResults(req, diffs)}, nil
+	}
+}
+
+type diffEntityFunc func(env *schemadiff.Environment, query1 string, query2 string, hints *schemadiff.DiffHints) (schemadiff.EntityDiff, error)
+
+func (s *Server) diffEntity(f diffEntityFunc, expectedStatement string) handlerFunc {
+	return func(req *request) (any, error) {
+		sourceSQLs, targetSQLs, err := s.readSourceTarget(req)
+		if err != nil {
+			return nil, err
+		}
+		diffs, err := entityDiff(req.env, req.hints, f, expectedStatement, sourceSQLs, targetSQLs)
+		if err != nil {
+			return nil, err
+		}
+		return &diffsResponse{Diffs: newDiffResults(req, diffs)}, nil
+	}
+}
+
+// entityDiff diffs two single-statement schemas using the given entity diff function. It returns no diffs
+// when the entities are identical.
+func entityDiff(env *schemadiff.Environment, hints *schemadiff.DiffHints, f diffEntityFunc, expectedStatement string, sourceSQLs []string, targetSQLs []string) ([]schemadiff.EntityDiff, error) {
+	for _, sqls := range [][]string{sourceSQLs, targetSQLs} {
+		if len(sqls) != 1 {
+			return nil, fmt.Errorf("expected one %s statement, found %d entities", expectedStatement, len(sqls))
+		}
+	}
+	diff, err := f(env, sourceSQLs[0], targetSQLs[0], hints)
+	if err != nil {
+		return nil, err
+	}
+	if diff.IsEmpty() {
+		return nil, nil
+	}
+	return []schemadiff.EntityDiff{diff}, nil
+}
+
+// apply computes the ordered diff between source and target, applies it onto the source schema, and returns
+// both the diffs and the resulting schema.
+func (s *Server) apply(req *request) (any, error) {
+	sourceSQLs, targetSQLs, err := s.readSourceTarget(req)
+	if err != nil {
+		return nil, err
+	}
+	diffs, applied, err := applyDiff(req.ctx, req.env, req.hints, sourceSQLs, targetSQLs)
+	if err != nil {
+		return nil, err
+	}
+	return &applyResponse{
+		Diffs:      newDiffResults(req, diffs),
+		Statements: schemaStatements(applied),
+	}, nil
+}
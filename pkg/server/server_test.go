@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, handler http.Handler, path string, contentType string, body string) (int, map[string]any) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var result map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result), rec.Body.String())
+	return rec.Code, result
+}
+
+func TestServerLoad(t *testing.T) {
+	handler := New(Config{}).Handler()
+	t.Run("sql body", func(t *testing.T) {
+		status, result := post(t, handler, "/load", "text/plain", "create table t2 (id int primary key); create table t1 (id int primary key)")
+		require.Equal(t, http.StatusOK, status, result)
+		assert.Equal(t, []any{
+			"CREATE TABLE `t1` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)",
+			"CREATE TABLE `t2` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)",
+		}, result["statements"])
+	})
+	t.Run("json array", func(t *testing.T) {
+		status, result := post(t, handler, "/load", "application/json", `["create table t1 (id int primary key)"]`)
+		require.Equal(t, http.StatusOK, status, result)
+		assert.Equal(t, []any{"CREATE TABLE `t1` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)"}, result["statements"])
+	})
+	t.Run("invalid schema", func(t *testing.T) {
+		status, result := post(t, handler, "/load", "", "create view v1 as select * from t_missing")
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.NotEmpty(t, result["error"])
+	})
+}
+
+func TestServerLint(t *testing.T) {
+	handler := New(Config{}).Handler()
+	status, result := post(t, handler, "/lint", "", "create table t1 (id int primary key)")
+	require.Equal(t, http.StatusOK, status, result)
+	assert.Equal(t, true, result["valid"])
+	assert.Empty(t, result["errors"])
+
+	status, result = post(t, handler, "/lint", "", "create view v1 as select * from t_missing")
+	require.Equal(t, http.StatusOK, status, result)
+	assert.Equal(t, false, result["valid"])
+	assert.NotEmpty(t, result["errors"])
+}
+
+func TestServerDiff(t *testing.T) {
+	handler := New(Config{}).Handler()
+	body := `{
+		"source": "create table t1 (id int primary key); create view v1 as select id from t1",
+		"target": ["create table t1 (id int unsigned primary key)", "create table t2 (id int primary key)"]
+	}`
+	t.Run("diff", func(t *testing.T) {
+		status, result := post(t, handler, "/diff", "application/json", body)
+		require.Equal(t, http.StatusOK, status, result)
+		diffs, ok := result["diffs"].([]any)
+		require.True(t, ok)
+		require.Len(t, diffs, 3)
+		var statements []string
+		for _, d := range diffs {
+			statements = append(statements, d.(map[string]any)["statement"].(string))
+		}
+		assert.Contains(t, statements, "DROP VIEW `v1`")
+		assert.Contains(t, statements, "ALTER TABLE `t1` MODIFY COLUMN `id` int unsigned")
+	})
+	t.Run("ordered-diff", func(t *testing.T) {
+		status, result := post(t, handler, "/ordered-diff", "application/json", body)
+		require.Equal(t, http.StatusOK, status, result)
+		diffs := result["diffs"].([]any)
+		require.Len(t, diffs, 3)
+		assert.Equal(t, "v1", diffs[0].(map[string]any)["entity"])
+	})
+	t.Run("textual", func(t *testing.T) {
+		status, result := post(t, handler, "/diff?textual=true", "application/json", body)
+		require.Equal(t, http.StatusOK, status, result)
+		for _, d := range result["diffs"].([]any) {
+			assert.NotEmpty(t, d.(map[string]any)["annotated"])
+		}
+	})
+	t.Run("no diff", func(t *testing.T) {
+		status, result := post(t, handler, "/diff", "application/json", `{"source": "create table t1 (id int)", "target": "create table t1 (id int)"}`)
+		require.Equal(t, http.StatusOK, status, result)
+		assert.Equal(t, []any{}, result["diffs"])
+	})
+	t.Run("diff-table", func(t *testing.T) {
+		status, result := post(t, handler, "/diff-table", "application/json", `{"source": "create table t1 (id int)", "target": "create table t2 (id bigint)"}`)
+		require.Equal(t, http.StatusOK, status, result)
+		diffs := result["diffs"].([]any)
+		require.Len(t, diffs, 1)
+		assert.Equal(t, "ALTER TABLE `t1` MODIFY COLUMN `id` bigint", diffs[0].(map[string]any)["statement"])
+	})
+	t.Run("diff-table multiple", func(t *testing.T) {
+		status, _ := post(t, handler, "/diff-table", "application/json", body)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+	})
+	t.Run("apply", func(t *testing.T) {
+		status, result := post(t, handler, "/apply", "application/json", body)
+		require.Equal(t, http.StatusOK, status, result)
+		assert.Len(t, result["diffs"], 3)
+		assert.Equal(t, []any{
+			"CREATE TABLE `t1` (\n\t`id` int unsigned,\n\tPRIMARY KEY (`id`)\n)",
+			"CREATE TABLE `t2` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)",
+		}, result["statements"])
+	})
+}
+
+func TestServerRequestErrors(t *testing.T) {
+	handler := New(Config{MaxRequestSize: 64, AllowedDSNAddresses: []string{"127.0.0.1:3306"}}).Handler()
+	tcases := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		expectCode  int
+		expectError string
+	}{
+		{
+			name:        "method",
+			method:      http.MethodGet,
+			path:        "/diff",
+			expectCode:  http.StatusMethodNotAllowed,
+			expectError: "method GET not allowed",
+		},
+		{
+			name:        "size",
+			method:      http.MethodPost,
+			path:        "/load",
+			body:        strings.Repeat("-- comment\n", 10),
+			expectCode:  http.StatusRequestEntityTooLarge,
+			expectError: "request body exceeds 64 bytes",
+		},
+		{
+			name:        "not json",
+			method:      http.MethodPost,
+			path:        "/diff",
+			body:        "create table t1 (id int)",
+			expectCode:  http.StatusBadRequest,
+			expectError: "expected {",
+		},
+		{
+			name:        "missing target",
+			method:      http.MethodPost,
+			path:        "/diff",
+			body:        `{"source": ""}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "expected {",
+		},
+		{
+			name:        "invalid schema value",
+			method:      http.MethodPost,
+			path:        "/diff",
+			body:        `{"source": 1, "target": ""}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "schema must be",
+		},
+		{
+			name:        "dsn not allowed",
+			method:      http.MethodPost,
+			path:        "/diff",
+			body:        `{"source": {"dsn": "u:p@tcp(10.0.0.1:3306)/db"}, "target": ""}`,
+			expectCode:  http.StatusForbidden,
+			expectError: "not in the allowlist: 10.0.0.1:3306",
+		},
+		{
+			name:        "invalid dsn",
+			method:      http.MethodPost,
+			path:        "/diff",
+			body:        `{"source": {"dsn": "u:p@tcp(127.0.0.1:3306)"}, "target": ""}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "invalid DSN",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(tcase.method, tcase.path, strings.NewReader(tcase.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tcase.expectCode, rec.Code)
+
+			var result map[string]string
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+			assert.Contains(t, result["error"], tcase.expectError)
+		})
+	}
+}
+
+func TestServerEnvCache(t *testing.T) {
+	s := New(Config{})
+	env, err := s.env("")
+	require.NoError(t, err)
+	cached, err := s.env("")
+	require.NoError(t, err)
+	assert.Same(t, env, cached)
+
+	for i := 0; i < 2*maxCachedEnvs; i++ {
+		_, err := s.env(fmt.Sprintf("8.0.%d", i))
+		require.NoError(t, err)
+	}
+	assert.Len(t, s.envs, maxCachedEnvs)
+	cached, err = s.env("")
+	require.NoError(t, err)
+	assert.Same(t, env, cached)
+}
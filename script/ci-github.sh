@@ -27,6 +27,16 @@ cat $output_file
 grep -q 'DROP TABLE `t1`' $output_file
 grep -q 'CREATE TABLE `t2`' $output_file
 
+# gh-ost artifact tables are skipped by default
+my test -e "create table _t1_gho (id int primary key)"
+schemadiff load --source 'root:root@tcp(127.0.0.1:33306)/test' > $output_file
+cat $output_file
+grep -q 'CREATE TABLE `t1`' $output_file
+if grep -q '_t1_gho' $output_file ; then exit 1 ; fi
+schemadiff load --source 'root:root@tcp(127.0.0.1:33306)/test' --include-internal-tables > $output_file
+grep -q 'CREATE TABLE `_t1_gho`' $output_file
+my test -e "drop table _t1_gho"
+
 # diff the tables `t1` vs `t2`
 echo "${create_t2}" | schemadiff diff-table --source 'root:root@tcp(127.0.0.1:33306)/test?#t1' > $output_file
 cat $output_file